@@ -82,10 +82,10 @@ func (a *AuthenticationMiddleware) authenticateAccessToken(c echo.Context, next
 	}
 	ctx := c.Request().Context()
 
-	session, err := a.findSessionFromCache(token)
+	session, err := a.findSessionFromCache(ctx, token)
 	switch err {
 	default:
-		logrus.WithField("sessionCacheError", "find session from cache got error").Error(err)
+		logrus.WithContext(ctx).WithField("sessionCacheError", "find session from cache got error").Error(err)
 	case nil:
 		if session == nil {
 			break // fallback
@@ -111,19 +111,19 @@ func (a *AuthenticationMiddleware) authenticateAccessToken(c echo.Context, next
 		c.SetRequest(c.Request().WithContext(ctx))
 		return next(c)
 	case codes.NotFound:
-		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"message": "token is invalid"})
+		return echo.NewHTTPError(http.StatusUnauthorized, echo.Map{"message": "token is invalid"})
 	case codes.Unauthenticated:
 		return echo.NewHTTPError(http.StatusUnauthorized, echo.Map{"message": "token is expired"})
 	default:
-		logrus.Error(err)
+		logrus.WithContext(ctx).Error(err)
 		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{"message": "system error"})
 	}
 }
 
-func (a *AuthenticationMiddleware) findSessionFromCache(token string) (*model.Session, error) {
-	reply, err := a.cacheManager.Get(model.NewSessionTokenCacheKey(token))
+func (a *AuthenticationMiddleware) findSessionFromCache(ctx context.Context, token string) (*model.Session, error) {
+	reply, err := a.cacheManager.Get(ctx, model.NewSessionTokenCacheKey(token))
 	if err != nil {
-		logrus.Error(err)
+		logrus.WithContext(ctx).Error(err)
 		return nil, err
 	}
 
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeUserAuthenticator authenticate the "valid" token, the other tokens return the error of the token
+type fakeUserAuthenticator struct {
+	errs map[string]error
+}
+
+func (f *fakeUserAuthenticator) AuthenticateToken(_ context.Context, accessToken string) (*User, error) {
+	if err, ok := f.errs[accessToken]; ok {
+		return nil, err
+	}
+	return &User{ID: 1, SessionID: 1}, nil
+}
+
+// newTestAuthenticationMiddleware the "cached" token is a valid cached session and the "cached-expired" token is expired,
+// the "unknown", "expired" and "broken" tokens fail in the authenticator
+func newTestAuthenticationMiddleware(t *testing.T) *AuthenticationMiddleware {
+	t.Helper()
+
+	cacheManager := cacher.NewMemoryCacheManager()
+	for token, expiredAt := range map[string]time.Time{
+		"cached":         time.Now().Add(time.Hour),
+		"cached-expired": time.Now().Add(-time.Hour),
+	} {
+		session, err := json.Marshal(&model.Session{ID: 2, UserID: 2, AccessToken: token, AccessTokenExpiredAt: expiredAt})
+		require.NoError(t, err)
+		require.NoError(t, cacheManager.StoreWithoutBlocking(context.Background(), cacher.NewItem(model.NewSessionTokenCacheKey(token), session)))
+	}
+
+	return NewAuthenticationMiddleware(cacheManager, &fakeUserAuthenticator{errs: map[string]error{
+		"unknown": status.Error(codes.NotFound, "not found"),
+		"expired": status.Error(codes.Unauthenticated, "access token expired"),
+		"broken":  errors.New("db is down"),
+	}})
+}
+
+func TestAuthenticationMiddleware_MustAuthenticateAccessToken(t *testing.T) {
+	middleware := newTestAuthenticationMiddleware(t)
+	e := echo.New()
+	e.GET("/", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, GetUserFromCtx(c.Request().Context()).ID)
+	}, middleware.MustAuthenticateAccessToken())
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "valid", token: "valid", status: http.StatusOK},
+		{name: "cached", token: "cached", status: http.StatusOK},
+		{name: "no token", token: "", status: http.StatusUnauthorized},
+		{name: "unknown", token: "unknown", status: http.StatusUnauthorized},
+		{name: "expired", token: "expired", status: http.StatusUnauthorized},
+		{name: "cached expired", token: "cached-expired", status: http.StatusUnauthorized},
+		{name: "system error", token: "broken", status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// UnaryServerInterceptor authenticate access token from the `authorization` metadata and load a User to context.
+// Every method must be authenticated except the given public methods, e.g. "/userbalance.UserBalanceService/Login".
+func (a *AuthenticationMiddleware) UnaryServerInterceptor(publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, method := range publicMethods {
+		public[method] = true
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		token := getAccessTokenFromMetadata(ctx)
+		if token == "" {
+			return nil, status.Error(codes.Unauthenticated, "user is unauthenticated")
+		}
+
+		user, err := a.authenticateGRPCAccessToken(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(SetUserToCtx(ctx, *user), req)
+	}
+}
+
+func getAccessTokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+
+	parts := strings.SplitN(values[0], " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return ""
+	}
+
+	return strings.TrimSpace(parts[1])
+}
+
+// authenticateGRPCAccessToken like authenticateAccessToken, find the session from the cache first and fallback to the UserAuthenticator
+func (a *AuthenticationMiddleware) authenticateGRPCAccessToken(ctx context.Context, token string) (*User, error) {
+	session, err := a.findSessionFromCache(ctx, token)
+	switch err {
+	default:
+		logrus.WithContext(ctx).WithField("sessionCacheError", "find session from cache got error").Error(err)
+	case nil:
+		if session == nil {
+			break // fallback
+		}
+		if session.IsAccessTokenExpired() {
+			return nil, status.Error(codes.Unauthenticated, "token expired")
+		}
+
+		user := NewUserFromSession(*session)
+		return &user, nil
+	}
+
+	user, err := a.userAuthenticator.AuthenticateToken(ctx, token)
+	switch status.Code(err) {
+	case codes.OK:
+		if user == nil { // safety check
+			return nil, status.Error(codes.Unauthenticated, "user is unauthenticated")
+		}
+		return user, nil
+	case codes.NotFound:
+		return nil, status.Error(codes.Unauthenticated, "token is invalid")
+	case codes.Unauthenticated:
+		return nil, status.Error(codes.Unauthenticated, "token is expired")
+	default:
+		logrus.WithContext(ctx).Error(err)
+		return nil, status.Error(codes.Internal, "system error")
+	}
+}
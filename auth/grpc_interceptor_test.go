@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestAuthenticationMiddleware_UnaryServerInterceptor(t *testing.T) {
+	interceptor := newTestAuthenticationMiddleware(t).UnaryServerInterceptor("/test.Service/Public")
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return GetUserFromCtx(ctx), nil
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		code   codes.Code
+	}{
+		{name: "valid", method: "/test.Service/Private", token: "valid", code: codes.OK},
+		{name: "cached", method: "/test.Service/Private", token: "cached", code: codes.OK},
+		{name: "public", method: "/test.Service/Public", token: "", code: codes.OK},
+		{name: "no token", method: "/test.Service/Private", token: "", code: codes.Unauthenticated},
+		{name: "unknown", method: "/test.Service/Private", token: "unknown", code: codes.Unauthenticated},
+		{name: "expired", method: "/test.Service/Private", token: "expired", code: codes.Unauthenticated},
+		{name: "cached expired", method: "/test.Service/Private", token: "cached-expired", code: codes.Unauthenticated},
+		{name: "system error", method: "/test.Service/Private", token: "broken", code: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+tt.token))
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package cacher
 
 import (
-	"github.com/go-redsync/redsync/v4"
 	redigo "github.com/gomodule/redigo/redis"
 )
 
@@ -36,7 +35,7 @@ func get(client redigo.Conn, key string) (value any, err error) {
 }
 
 // SafeUnlock safely unlock mutex
-func SafeUnlock(mutex *redsync.Mutex) {
+func SafeUnlock(mutex Mutex) {
 	if mutex != nil {
 		_, _ = mutex.Unlock()
 	}
@@ -7,4 +7,8 @@ var (
 	ErrWaitTooLong = errors.New("wait too long")
 	// ErrKeyNotExist :nodoc:
 	ErrKeyNotExist = errors.New("key not exist")
+	// ErrLockNotAcquired the key is locked by another caller
+	ErrLockNotAcquired = errors.New("lock not acquired")
+	// ErrLockExpired the lock expired before it's unlocked
+	ErrLockExpired = errors.New("lock expired")
 )
@@ -0,0 +1,57 @@
+package cacher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+)
+
+// GetOrSet read through the cache: the cached value of the key is returned, on a miss the value is loaded
+// while holding the lock of the key, so only one caller load it, and stored before the lock is released.
+// The value is stored as JSON, T should be a pointer, a slice or a map so a nil value means not found.
+// A nil value is stored with the nil TTL, the next calls get nil from the cache instead of loading it again.
+// The opts are applied to the item after the value is loaded, so they may depend on it.
+func GetOrSet[T any](ctx context.Context, manager CacheManager, key string, load func(ctx context.Context) (T, error), opts ...func(Item)) (value T, err error) {
+	cachedItem, mutex, err := manager.GetOrLock(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	defer SafeUnlock(mutex)
+
+	if cachedItem != nil {
+		bt, ok := cachedItem.([]byte)
+		if !ok {
+			return value, fmt.Errorf("unexpected cached value %T of key %s", cachedItem, key)
+		}
+		err = json.Unmarshal(bt, &value)
+		return value, err
+	}
+
+	value, err = load(ctx)
+	if err != nil {
+		return value, err
+	}
+
+	bt, err := json.Marshal(value)
+	if err != nil {
+		return value, err
+	}
+
+	if bytes.Equal(bt, nilValue) {
+		err = manager.StoreNil(ctx, key)
+	} else {
+		item := NewItem(key, bt)
+		for _, opt := range opts {
+			opt(item)
+		}
+		err = manager.StoreWithoutBlocking(ctx, item)
+	}
+	if err != nil {
+		// the value is loaded, a failed store only cost another load on the next call
+		logrus.WithContext(ctx).WithField("key", key).Error(err)
+	}
+
+	return value, nil
+}
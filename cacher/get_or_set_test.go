@@ -0,0 +1,276 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type cachedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// countingLoader a loader of the user which count its calls
+type countingLoader struct {
+	calls int32
+	user  *cachedUser
+	err   error
+	delay time.Duration
+}
+
+func (l *countingLoader) load(context.Context) (*cachedUser, error) {
+	atomic.AddInt32(&l.calls, 1)
+	time.Sleep(l.delay)
+	return l.user, l.err
+}
+
+func (l *countingLoader) callCount() int {
+	return int(atomic.LoadInt32(&l.calls))
+}
+
+// newRedisCacheManager the redis CacheManager on a miniredis server which is closed at the end of the test
+func newRedisCacheManager(t *testing.T) (CacheManager, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	pool := &redigo.Pool{
+		MaxIdle: 10,
+		Dial: func() (redigo.Conn, error) {
+			return redigo.Dial("tcp", server.Addr())
+		},
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+
+	manager := NewCacheManager()
+	manager.SetConnectionPool(pool)
+	manager.SetLockConnectionPool(pool)
+	return manager, server
+}
+
+func TestGetOrSet_Miss(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	loader := &countingLoader{user: &cachedUser{ID: 1, Name: "johndoe"}}
+
+	user, err := GetOrSet(ctx, manager, "cache:user:id:1", loader.load, WithTTL(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, &cachedUser{ID: 1, Name: "johndoe"}, user)
+	assert.Equal(t, 1, loader.callCount())
+
+	// the value is stored as JSON with the given TTL
+	cached, err := manager.Get(ctx, "cache:user:id:1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"name":"johndoe"}`, string(cached.([]byte)))
+	ttl, err := manager.GetTTL(ctx, "cache:user:id:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), ttl)
+}
+
+func TestGetOrSet_Hit(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	require.NoError(t, manager.StoreWithoutBlocking(ctx, NewItem("cache:user:id:1", []byte(`{"id":1,"name":"cached"}`))))
+	loader := &countingLoader{user: &cachedUser{ID: 1, Name: "johndoe"}}
+
+	user, err := GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+	require.NoError(t, err)
+	assert.Equal(t, &cachedUser{ID: 1, Name: "cached"}, user)
+	assert.Equal(t, 0, loader.callCount())
+}
+
+func TestGetOrSet_StoreNil(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	manager.SetNilTTL(2 * time.Minute)
+	loader := &countingLoader{}
+
+	user, err := GetOrSet(ctx, manager, "cache:user:id:2", loader.load)
+	require.NoError(t, err)
+	assert.Nil(t, user)
+
+	// the miss is cached with the nil TTL, the next call doesn't load again
+	ttl, err := manager.GetTTL(ctx, "cache:user:id:2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), ttl)
+
+	user, err = GetOrSet(ctx, manager, "cache:user:id:2", loader.load)
+	require.NoError(t, err)
+	assert.Nil(t, user)
+	assert.Equal(t, 1, loader.callCount())
+}
+
+func TestGetOrSet_LockWait(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	loader := &countingLoader{user: &cachedUser{ID: 1, Name: "johndoe"}, delay: 100 * time.Millisecond}
+
+	// the callers wait for the one holding the lock, then read what it stored
+	const callers = 10
+	users := make([]*cachedUser, callers)
+	errs := make([]error, callers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			users[i], errs[i] = GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, loader.callCount())
+	for i := 0; i < callers; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, &cachedUser{ID: 1, Name: "johndoe"}, users[i])
+	}
+}
+
+func TestGetOrSet_LockWaitTooLong(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	manager.SetWaitTime(50 * time.Millisecond)
+
+	mutex, err := manager.AcquireLock(ctx, "cache:user:id:1")
+	require.NoError(t, err)
+	defer SafeUnlock(mutex)
+
+	loader := &countingLoader{user: &cachedUser{ID: 1}}
+	_, err = GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+	assert.Equal(t, ErrWaitTooLong, err)
+	assert.Equal(t, 0, loader.callCount())
+}
+
+func TestGetOrSet_LoaderError(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	errLoad := errors.New("connection refused")
+	loader := &countingLoader{err: errLoad}
+
+	_, err := GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+	assert.Equal(t, errLoad, err)
+
+	// nothing is stored and the lock is released, so the next call load again without waiting
+	cached, err := manager.Get(ctx, "cache:user:id:1")
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+
+	loader.err = nil
+	loader.user = &cachedUser{ID: 1, Name: "johndoe"}
+	begin := time.Now()
+	user, err := GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+	require.NoError(t, err)
+	assert.Equal(t, &cachedUser{ID: 1, Name: "johndoe"}, user)
+	assert.Equal(t, 2, loader.callCount())
+	assert.Less(t, time.Since(begin), time.Second)
+}
+
+func TestGetOrSet_DisableCaching(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	manager.SetDisableCaching(true)
+	loader := &countingLoader{user: &cachedUser{ID: 1, Name: "johndoe"}}
+
+	for i := 0; i < 2; i++ {
+		user, err := GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+		require.NoError(t, err)
+		assert.Equal(t, &cachedUser{ID: 1, Name: "johndoe"}, user)
+	}
+	assert.Equal(t, 2, loader.callCount())
+}
+
+func TestGetOrSet_Redis(t *testing.T) {
+	ctx := context.Background()
+	manager, server := newRedisCacheManager(t)
+	loader := &countingLoader{user: &cachedUser{ID: 1, Name: "johndoe"}}
+
+	user, err := GetOrSet(ctx, manager, "cache:user:id:1", loader.load, WithTTL(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, &cachedUser{ID: 1, Name: "johndoe"}, user)
+
+	// the value is stored as JSON with the given TTL, and the lock is released
+	cached, err := server.Get("cache:user:id:1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"name":"johndoe"}`, cached)
+	assert.Equal(t, time.Minute, server.TTL("cache:user:id:1"))
+	assert.False(t, server.Exists("lock:cache:user:id:1"))
+
+	// the next call is a hit
+	user, err = GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+	require.NoError(t, err)
+	assert.Equal(t, &cachedUser{ID: 1, Name: "johndoe"}, user)
+	assert.Equal(t, 1, loader.callCount())
+}
+
+func TestGetOrSet_RedisStoreNil(t *testing.T) {
+	ctx := context.Background()
+	manager, server := newRedisCacheManager(t)
+	manager.SetNilTTL(2 * time.Minute)
+	loader := &countingLoader{}
+
+	user, err := GetOrSet(ctx, manager, "cache:user:id:2", loader.load)
+	require.NoError(t, err)
+	assert.Nil(t, user)
+	assert.Equal(t, 2*time.Minute, server.TTL("cache:user:id:2"))
+
+	user, err = GetOrSet(ctx, manager, "cache:user:id:2", loader.load)
+	require.NoError(t, err)
+	assert.Nil(t, user)
+	assert.Equal(t, 1, loader.callCount())
+
+	// the nil expires with its TTL, then it's loaded again
+	server.FastForward(2 * time.Minute)
+	loader.user = &cachedUser{ID: 2, Name: "janedoe"}
+	user, err = GetOrSet(ctx, manager, "cache:user:id:2", loader.load)
+	require.NoError(t, err)
+	assert.Equal(t, &cachedUser{ID: 2, Name: "janedoe"}, user)
+	assert.Equal(t, 2, loader.callCount())
+}
+
+func TestGetOrSet_RedisLockWait(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := newRedisCacheManager(t)
+	loader := &countingLoader{user: &cachedUser{ID: 1, Name: "johndoe"}, delay: 100 * time.Millisecond}
+
+	// the callers wait for the one holding the redis lock, then read what it stored
+	const callers = 10
+	users := make([]*cachedUser, callers)
+	errs := make([]error, callers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			users[i], errs[i] = GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, loader.callCount())
+	for i := 0; i < callers; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, &cachedUser{ID: 1, Name: "johndoe"}, users[i])
+	}
+}
+
+func TestGetOrSet_RedisLockWaitTooLong(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := newRedisCacheManager(t)
+	manager.SetWaitTime(50 * time.Millisecond)
+
+	mutex, err := manager.AcquireLock(ctx, "cache:user:id:1")
+	require.NoError(t, err)
+	defer SafeUnlock(mutex)
+
+	loader := &countingLoader{user: &cachedUser{ID: 1}}
+	_, err = GetOrSet(ctx, manager, "cache:user:id:1", loader.load)
+	assert.Equal(t, ErrWaitTooLong, err)
+	assert.Equal(t, 0, loader.callCount())
+}
@@ -1,6 +1,7 @@
 package cacher
 
 import (
+	"context"
 	"github.com/go-redsync/redsync/v4"
 	redigosync "github.com/go-redsync/redsync/v4/redis/redigo"
 	redigo "github.com/gomodule/redigo/redis"
@@ -19,20 +20,32 @@ const (
 
 var nilValue = []byte("null")
 
+// the drivers of the cache manager
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+)
+
 type (
+	// Mutex the lock of a key, held until it's unlocked or expired
+	Mutex interface {
+		Unlock() (bool, error)
+	}
+
 	CacheManager interface {
-		Get(key string) (any, error)
-		GetOrLock(key string) (any, *redsync.Mutex, error)
-		StoreWithoutBlocking(Item) error
-		StoreMultiWithoutBlocking([]Item) error
-		DeleteByKeys([]string) error
-		StoreNil(cacheKey string) error
-		IncreaseCachedValueByOne(key string) error
-		Expire(string, time.Duration) error
-
-		GetTTL(string) (int64, error)
-
-		AcquireLock(string) (*redsync.Mutex, error)
+		Get(ctx context.Context, key string) (any, error)
+		GetOrLock(ctx context.Context, key string) (any, Mutex, error)
+		StoreWithoutBlocking(context.Context, Item) error
+		StoreMultiWithoutBlocking(context.Context, []Item) error
+		DeleteByKeys(context.Context, []string) error
+		StoreNil(ctx context.Context, cacheKey string) error
+		IncreaseCachedValueByOne(ctx context.Context, key string) error
+		IncreaseCachedValueBy(ctx context.Context, key string, value int64) error
+		Expire(context.Context, string, time.Duration) error
+
+		GetTTL(context.Context, string) (int64, error)
+
+		AcquireLock(context.Context, string) (Mutex, error)
 		SetDefaultTTL(time.Duration)
 		SetNilTTL(time.Duration)
 		SetConnectionPool(*redigo.Pool)
@@ -56,7 +69,7 @@ type cacheManager struct {
 	lockTries    int
 }
 
-// NewCacheManager :nodoc:
+// NewCacheManager return the redis CacheManager, its connection pools must be set
 func NewCacheManager() CacheManager {
 	return &cacheManager{
 		defaultTTL:     defaultTTL,
@@ -68,12 +81,16 @@ func NewCacheManager() CacheManager {
 	}
 }
 
-func (k *cacheManager) Get(key string) (cachedItem any, err error) {
+func (k *cacheManager) Get(ctx context.Context, key string) (cachedItem any, err error) {
 	if k.disableCaching {
 		return
 	}
 
+	_, span := startSpan(ctx, "cacher.Get", key)
+	defer func() { endSpan(span, err) }()
+
 	cachedItem, err = get(k.connPool.Get(), key)
+	observeGet(cachedItem, err)
 	if err != nil && err != ErrKeyNotExist && err != redigo.ErrNil || cachedItem != nil {
 		return
 	}
@@ -82,22 +99,29 @@ func (k *cacheManager) Get(key string) (cachedItem any, err error) {
 }
 
 // GetOrLock :nodoc:
-func (k *cacheManager) GetOrLock(key string) (cachedItem any, mutex *redsync.Mutex, err error) {
+func (k *cacheManager) GetOrLock(ctx context.Context, key string) (cachedItem any, mutex Mutex, err error) {
 	if k.disableCaching {
 		return
 	}
 
+	ctx, span := startSpan(ctx, "cacher.GetOrLock", key)
+	defer func() { endSpan(span, err) }()
+
 	cachedItem, err = get(k.connPool.Get(), key)
+	observeGet(cachedItem, err)
 	if err != nil && err != ErrKeyNotExist && err != redigo.ErrNil || cachedItem != nil {
 		return
 	}
 
-	mutex, err = k.AcquireLock(key)
+	mutex, err = k.AcquireLock(ctx, key)
 	if err == nil {
 		return
 	}
 
 	start := time.Now()
+	defer func() {
+		cacheLockWaitSeconds.Observe(time.Since(start).Seconds())
+	}()
 	for {
 		b := &backoff.Backoff{
 			Min:    20 * time.Millisecond,
@@ -109,7 +133,7 @@ func (k *cacheManager) GetOrLock(key string) (cachedItem any, mutex *redsync.Mut
 			cachedItem, err = get(k.connPool.Get(), key)
 			if err != nil {
 				if err == ErrKeyNotExist {
-					mutex, err = k.AcquireLock(key)
+					mutex, err = k.AcquireLock(ctx, key)
 					if err == nil {
 						return nil, mutex, nil
 					}
@@ -130,31 +154,57 @@ func (k *cacheManager) GetOrLock(key string) (cachedItem any, mutex *redsync.Mut
 		time.Sleep(b.Duration())
 	}
 
+	cacheWaitTooLongTotal.Inc()
 	return nil, nil, ErrWaitTooLong
 }
 
 // IncreaseCachedValueByOne will increments the number stored at key by one.
 // If the key does not exist, it is set to 0 before performing the operation
-func (k *cacheManager) IncreaseCachedValueByOne(key string) error {
+func (k *cacheManager) IncreaseCachedValueByOne(ctx context.Context, key string) (err error) {
 	if k.disableCaching {
 		return nil
 	}
 
+	_, span := startSpan(ctx, "cacher.IncreaseCachedValueByOne", key)
+	defer func() { endSpan(span, err) }()
+
 	client := k.connPool.Get()
 	defer func() {
 		_ = client.Close()
 	}()
 
-	_, err := client.Do("INCR", key)
+	_, err = client.Do("INCR", key)
+	return err
+}
+
+// IncreaseCachedValueBy will increments the number stored at key by the given value.
+// If the key does not exist, it is set to 0 before performing the operation
+func (k *cacheManager) IncreaseCachedValueBy(ctx context.Context, key string, value int64) (err error) {
+	if k.disableCaching {
+		return nil
+	}
+
+	_, span := startSpan(ctx, "cacher.IncreaseCachedValueBy", key)
+	defer func() { endSpan(span, err) }()
+
+	client := k.connPool.Get()
+	defer func() {
+		_ = client.Close()
+	}()
+
+	_, err = client.Do("INCRBY", key, value)
 	return err
 }
 
 // Expire Set expire a key
-func (k *cacheManager) Expire(key string, duration time.Duration) (err error) {
+func (k *cacheManager) Expire(ctx context.Context, key string, duration time.Duration) (err error) {
 	if k.disableCaching {
 		return nil
 	}
 
+	_, span := startSpan(ctx, "cacher.Expire", key)
+	defer func() { endSpan(span, err) }()
+
 	client := k.connPool.Get()
 	defer func() {
 		_ = client.Close()
@@ -164,7 +214,10 @@ func (k *cacheManager) Expire(key string, duration time.Duration) (err error) {
 	return
 }
 
-func (k *cacheManager) GetTTL(name string) (value int64, err error) {
+func (k *cacheManager) GetTTL(ctx context.Context, name string) (value int64, err error) {
+	_, span := startSpan(ctx, "cacher.GetTTL", name)
+	defer func() { endSpan(span, err) }()
+
 	client := k.connPool.Get()
 	defer func() {
 		_ = client.Close()
@@ -179,37 +232,43 @@ func (k *cacheManager) GetTTL(name string) (value int64, err error) {
 	return
 }
 
-func (k *cacheManager) StoreWithoutBlocking(c Item) error {
+func (k *cacheManager) StoreWithoutBlocking(ctx context.Context, c Item) (err error) {
 	if k.disableCaching {
 		return nil
 	}
 
+	_, span := startSpan(ctx, "cacher.StoreWithoutBlocking", c.GetKey())
+	defer func() { endSpan(span, err) }()
+
 	client := k.connPool.Get()
 	defer func() {
 		_ = client.Close()
 	}()
 
-	_, err := client.Do("SETEX", c.GetKey(), k.decideCacheTTL(c), c.GetValue())
+	_, err = client.Do("SETEX", c.GetKey(), decideCacheTTL(c, k.defaultTTL), c.GetValue())
 	return err
 }
 
 // StoreMultiWithoutBlocking Store multiple items
-func (k *cacheManager) StoreMultiWithoutBlocking(items []Item) error {
+func (k *cacheManager) StoreMultiWithoutBlocking(ctx context.Context, items []Item) (err error) {
 	if k.disableCaching {
 		return nil
 	}
 
+	_, span := startSpan(ctx, "cacher.StoreMultiWithoutBlocking", itemKeys(items)...)
+	defer func() { endSpan(span, err) }()
+
 	client := k.connPool.Get()
 	defer func() {
 		_ = client.Close()
 	}()
 
-	err := client.Send("MULTI")
+	err = client.Send("MULTI")
 	if err != nil {
 		return err
 	}
 	for _, item := range items {
-		err = client.Send("SETEX", item.GetKey(), k.decideCacheTTL(item), item.GetValue())
+		err = client.Send("SETEX", item.GetKey(), decideCacheTTL(item, k.defaultTTL), item.GetValue())
 		if err != nil {
 			return err
 		}
@@ -220,7 +279,7 @@ func (k *cacheManager) StoreMultiWithoutBlocking(items []Item) error {
 }
 
 // DeleteByKeys Delete by multiple keys
-func (k *cacheManager) DeleteByKeys(keys []string) error {
+func (k *cacheManager) DeleteByKeys(ctx context.Context, keys []string) (err error) {
 	if k.disableCaching {
 		return nil
 	}
@@ -229,6 +288,9 @@ func (k *cacheManager) DeleteByKeys(keys []string) error {
 		return nil
 	}
 
+	_, span := startSpan(ctx, "cacher.DeleteByKeys", keys...)
+	defer func() { endSpan(span, err) }()
+
 	client := k.connPool.Get()
 	defer func() {
 		_ = client.Close()
@@ -239,19 +301,26 @@ func (k *cacheManager) DeleteByKeys(keys []string) error {
 		redisKeys = append(redisKeys, key)
 	}
 
-	_, err := client.Do("DEL", redisKeys...)
+	_, err = client.Do("DEL", redisKeys...)
 	return err
 }
 
 // AcquireLock :nodoc:
-func (k *cacheManager) AcquireLock(key string) (*redsync.Mutex, error) {
+func (k *cacheManager) AcquireLock(ctx context.Context, key string) (_ Mutex, err error) {
+	ctx, span := startSpan(ctx, "cacher.AcquireLock", key)
+	defer func() { endSpan(span, err) }()
+
 	p := redigosync.NewPool(k.lockConnPool)
 	r := redsync.New(p)
 	m := r.NewMutex("lock:"+key,
 		redsync.WithExpiry(k.lockDuration),
 		redsync.WithTries(k.lockTries))
 
-	return m, m.Lock()
+	// a nil mutex, not a typed nil, when the lock is not acquired
+	if err = m.LockContext(ctx); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 // SetDefaultTTL :nodoc:
@@ -294,18 +363,19 @@ func (k *cacheManager) SetDisableCaching(b bool) {
 }
 
 // StoreNil :nodoc:
-func (k *cacheManager) StoreNil(cacheKey string) error {
+func (k *cacheManager) StoreNil(ctx context.Context, cacheKey string) error {
 	item := NewItemWithCustomTTL(cacheKey, nilValue, k.nilTTL)
-	err := k.StoreWithoutBlocking(item)
+	err := k.StoreWithoutBlocking(ctx, item)
 	return err
 }
 
-func (k *cacheManager) decideCacheTTL(c Item) (ttl int64) {
+// decideCacheTTL the TTL of the item in seconds, or the default TTL when the item has none
+func decideCacheTTL(c Item, defaultTTL time.Duration) (ttl int64) {
 	if ttl = c.GetTTLInt64(); ttl > 0 {
 		return
 	}
 
-	return int64(k.defaultTTL.Seconds())
+	return int64(defaultTTL.Seconds())
 }
 
 func (k *cacheManager) isLocked(key string) bool {
@@ -0,0 +1,426 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	redigo "github.com/gomodule/redigo/redis"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval minimum time between two sweeps of the expired keys
+const memorySweepInterval = 1 * time.Minute
+
+type (
+	memoryItem struct {
+		value     []byte
+		expiredAt time.Time // zero means the item never expire
+	}
+
+	memoryLock struct {
+		released  chan struct{}
+		expiredAt time.Time
+	}
+
+	memoryMutex struct {
+		manager *memoryCacheManager
+		key     string
+		lock    *memoryLock
+	}
+)
+
+// memoryCacheManager an in-process CacheManager for the tests and the single node mode,
+// it behave like the redis one but the values are only visible to this process
+type memoryCacheManager struct {
+	mu        sync.Mutex
+	items     map[string]*memoryItem
+	locks     map[string]*memoryLock
+	lastSweep time.Time
+
+	nilTTL         time.Duration
+	defaultTTL     time.Duration
+	waitTime       time.Duration
+	disableCaching bool
+
+	lockDuration time.Duration
+	lockTries    int
+}
+
+// NewMemoryCacheManager :nodoc:
+func NewMemoryCacheManager() CacheManager {
+	return &memoryCacheManager{
+		items:          make(map[string]*memoryItem),
+		locks:          make(map[string]*memoryLock),
+		lastSweep:      time.Now(),
+		defaultTTL:     defaultTTL,
+		nilTTL:         defaultNilTTL,
+		lockDuration:   defaultLockDuration,
+		lockTries:      defaultLockTries,
+		waitTime:       defaultWaitTime,
+		disableCaching: false,
+	}
+}
+
+func (m *memoryCacheManager) Get(_ context.Context, key string) (any, error) {
+	if m.disableCaching {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cachedItem := m.get(key)
+	observeMemoryGet(cachedItem)
+	if cachedItem == nil {
+		return nil, nil
+	}
+	return cachedItem, nil
+}
+
+// GetOrLock :nodoc:
+func (m *memoryCacheManager) GetOrLock(ctx context.Context, key string) (cachedItem any, mutex Mutex, err error) {
+	if m.disableCaching {
+		return
+	}
+
+	var start time.Time
+	for {
+		m.mu.Lock()
+		value := m.get(key)
+		if start.IsZero() {
+			observeMemoryGet(value)
+		}
+		if value != nil {
+			m.mu.Unlock()
+			return value, nil, nil
+		}
+
+		mutex, lock := m.tryLock(key)
+		m.mu.Unlock()
+		if mutex != nil {
+			return nil, mutex, nil
+		}
+
+		if start.IsZero() {
+			start = time.Now()
+			defer func() {
+				cacheLockWaitSeconds.Observe(time.Since(start).Seconds())
+			}()
+		}
+
+		// wait until the lock is released or expired, then read the key again
+		wait := m.waitTime - time.Since(start)
+		if wait <= 0 {
+			cacheWaitTooLongTotal.Inc()
+			return nil, nil, ErrWaitTooLong
+		}
+		if untilExpired := time.Until(lock.expiredAt); untilExpired < wait {
+			wait = untilExpired
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		case <-lock.released:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// IncreaseCachedValueByOne :nodoc:
+func (m *memoryCacheManager) IncreaseCachedValueByOne(ctx context.Context, key string) error {
+	return m.IncreaseCachedValueBy(ctx, key, 1)
+}
+
+// IncreaseCachedValueBy increments the number stored at key by the given value, keeping its TTL.
+// If the key does not exist, it is set to 0 before performing the operation
+func (m *memoryCacheManager) IncreaseCachedValueBy(_ context.Context, key string, value int64) error {
+	if m.disableCaching {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if !ok || item.isExpired(time.Now()) {
+		item = &memoryItem{value: []byte("0")}
+	}
+
+	current, err := strconv.ParseInt(string(item.value), 10, 64)
+	if err != nil {
+		return errors.New("value is not an integer or out of range")
+	}
+
+	m.set(key, &memoryItem{
+		value:     []byte(strconv.FormatInt(current+value, 10)),
+		expiredAt: item.expiredAt,
+	})
+	return nil
+}
+
+// Expire set the TTL of a key, like redis a TTL below one second delete the key
+func (m *memoryCacheManager) Expire(_ context.Context, key string, duration time.Duration) error {
+	if m.disableCaching {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.get(key) == nil {
+		return nil
+	}
+
+	seconds := int64(duration.Seconds())
+	if seconds <= 0 {
+		delete(m.items, key)
+		return nil
+	}
+
+	m.items[key].expiredAt = time.Now().Add(time.Duration(seconds) * time.Second)
+	return nil
+}
+
+// GetTTL return the TTL of the key in seconds, like redis -2 when the key doesn't exist and -1 when it never expire
+func (m *memoryCacheManager) GetTTL(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.get(key) == nil {
+		return -2, nil
+	}
+
+	item := m.items[key]
+	if item.expiredAt.IsZero() {
+		return -1, nil
+	}
+
+	return int64((time.Until(item.expiredAt) + 500*time.Millisecond) / time.Second), nil
+}
+
+func (m *memoryCacheManager) StoreWithoutBlocking(ctx context.Context, c Item) error {
+	return m.StoreMultiWithoutBlocking(ctx, []Item{c})
+}
+
+// StoreMultiWithoutBlocking Store multiple items
+func (m *memoryCacheManager) StoreMultiWithoutBlocking(_ context.Context, items []Item) error {
+	if m.disableCaching {
+		return nil
+	}
+
+	now := time.Now()
+	newItems := make([]*memoryItem, len(items))
+	for i, item := range items {
+		ttl := decideCacheTTL(item, m.defaultTTL)
+		if ttl <= 0 {
+			return fmt.Errorf("invalid expire time of key %s", item.GetKey())
+		}
+
+		value, err := memoryValue(item.GetValue())
+		if err != nil {
+			return err
+		}
+		newItems[i] = &memoryItem{value: value, expiredAt: now.Add(time.Duration(ttl) * time.Second)}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, item := range items {
+		m.set(item.GetKey(), newItems[i])
+	}
+	return nil
+}
+
+// DeleteByKeys Delete by multiple keys
+func (m *memoryCacheManager) DeleteByKeys(_ context.Context, keys []string) error {
+	if m.disableCaching {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.items, key)
+	}
+	return nil
+}
+
+// AcquireLock :nodoc:
+func (m *memoryCacheManager) AcquireLock(ctx context.Context, key string) (Mutex, error) {
+	for try := 1; ; try++ {
+		m.mu.Lock()
+		mutex, lock := m.tryLock(key)
+		m.mu.Unlock()
+		if mutex != nil {
+			return mutex, nil
+		}
+		if try >= m.lockTries {
+			return nil, ErrLockNotAcquired
+		}
+
+		timer := time.NewTimer(time.Until(lock.expiredAt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-lock.released:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// SetDefaultTTL :nodoc:
+func (m *memoryCacheManager) SetDefaultTTL(d time.Duration) {
+	m.defaultTTL = d
+}
+
+func (m *memoryCacheManager) SetNilTTL(d time.Duration) {
+	m.nilTTL = d
+}
+
+// SetConnectionPool the memory cache has no connection, it's a no-op
+func (m *memoryCacheManager) SetConnectionPool(*redigo.Pool) {}
+
+// SetLockConnectionPool the memory cache has no connection, it's a no-op
+func (m *memoryCacheManager) SetLockConnectionPool(*redigo.Pool) {}
+
+// SetLockDuration :nodoc:
+func (m *memoryCacheManager) SetLockDuration(d time.Duration) {
+	m.lockDuration = d
+}
+
+// SetLockTries :nodoc:
+func (m *memoryCacheManager) SetLockTries(t int) {
+	m.lockTries = t
+}
+
+// SetWaitTime :nodoc:
+func (m *memoryCacheManager) SetWaitTime(d time.Duration) {
+	m.waitTime = d
+}
+
+// SetDisableCaching :nodoc:
+func (m *memoryCacheManager) SetDisableCaching(b bool) {
+	m.disableCaching = b
+}
+
+// StoreNil :nodoc:
+func (m *memoryCacheManager) StoreNil(ctx context.Context, cacheKey string) error {
+	item := NewItemWithCustomTTL(cacheKey, nilValue, m.nilTTL)
+	return m.StoreWithoutBlocking(ctx, item)
+}
+
+// get return a copy of the value of the key, nil when it doesn't exist and empty, not nil, when the value is empty
+// like redis. m.mu must be held.
+func (m *memoryCacheManager) get(key string) []byte {
+	item, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	if item.isExpired(time.Now()) {
+		delete(m.items, key)
+		return nil
+	}
+
+	return append([]byte{}, item.value...)
+}
+
+// observeMemoryGet like observeGet, a nil value is a miss rather than a typed nil counted as a hit
+func observeMemoryGet(value []byte) {
+	if value == nil {
+		observeGet(nil, nil)
+		return
+	}
+	observeGet(value, nil)
+}
+
+// set store the item and sweep the expired keys once in a while, so the keys which are never read again
+// don't stay in memory. m.mu must be held.
+func (m *memoryCacheManager) set(key string, item *memoryItem) {
+	m.items[key] = item
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) < memorySweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for k, v := range m.items {
+		if v.isExpired(now) {
+			delete(m.items, k)
+		}
+	}
+}
+
+// tryLock lock the key when it's not locked or its lock is expired, otherwise return the current lock.
+// m.mu must be held.
+func (m *memoryCacheManager) tryLock(key string) (Mutex, *memoryLock) {
+	now := time.Now()
+	if lock, ok := m.locks[key]; ok {
+		if now.Before(lock.expiredAt) {
+			return nil, lock
+		}
+		// wake up the callers waiting for the expired lock
+		close(lock.released)
+	}
+
+	lock := &memoryLock{
+		released:  make(chan struct{}),
+		expiredAt: now.Add(m.lockDuration),
+	}
+	m.locks[key] = lock
+	return &memoryMutex{manager: m, key: key, lock: lock}, nil
+}
+
+// Unlock release the lock, false with ErrLockExpired when the lock expired before
+func (l *memoryMutex) Unlock() (bool, error) {
+	m := l.manager
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.locks[l.key] != l.lock {
+		return false, ErrLockExpired
+	}
+
+	delete(m.locks, l.key)
+	close(l.lock.released)
+	if time.Now().After(l.lock.expiredAt) {
+		return false, ErrLockExpired
+	}
+	return true, nil
+}
+
+func (i *memoryItem) isExpired(now time.Time) bool {
+	return !i.expiredAt.IsZero() && !now.Before(i.expiredAt)
+}
+
+// memoryValue format the value like redis store it, everything is stored as bytes
+func memoryValue(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return append([]byte(nil), v...), nil
+	case string:
+		return []byte(v), nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case int64:
+		return []byte(strconv.FormatInt(v, 10)), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case nil:
+		return []byte{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported cache value %T", value)
+	}
+}
@@ -0,0 +1,193 @@
+package cacher
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheManager_TTLExpiry(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	require.NoError(t, manager.StoreWithoutBlocking(ctx, NewItemWithCustomTTL("key", "value", time.Second)))
+
+	cached, err := manager.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), cached)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	cached, err = manager.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+	ttl, err := manager.GetTTL(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-2), ttl)
+}
+
+func TestMemoryCacheManager_EmptyValue(t *testing.T) {
+	ctx := context.Background()
+	redisManager, _ := newRedisCacheManager(t)
+
+	// an empty value exists like in redis, it isn't read as a miss
+	for name, manager := range map[string]CacheManager{"memory": NewMemoryCacheManager(), "redis": redisManager} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, manager.StoreWithoutBlocking(ctx, NewItemWithCustomTTL("key", "", time.Minute)))
+
+			cached, err := manager.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, []byte{}, cached)
+
+			cached, mutex, err := manager.GetOrLock(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, []byte{}, cached)
+			assert.Nil(t, mutex)
+		})
+	}
+}
+
+func TestMemoryCacheManager_DefaultTTL(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	manager.SetDefaultTTL(time.Minute)
+	require.NoError(t, manager.StoreWithoutBlocking(ctx, NewItem("key", 1)))
+
+	ttl, err := manager.GetTTL(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), ttl)
+}
+
+func TestMemoryCacheManager_IncreaseKeepTTL(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	require.NoError(t, manager.StoreWithoutBlocking(ctx, NewItemWithCustomTTL("counter", int64(5), time.Minute)))
+
+	require.NoError(t, manager.IncreaseCachedValueBy(ctx, "counter", 10))
+	require.NoError(t, manager.IncreaseCachedValueByOne(ctx, "counter"))
+
+	cached, err := manager.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("16"), cached)
+	ttl, err := manager.GetTTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), ttl)
+
+	// like redis a missing counter start from zero and never expire
+	require.NoError(t, manager.IncreaseCachedValueByOne(ctx, "new_counter"))
+	cached, err = manager.Get(ctx, "new_counter")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), cached)
+	ttl, err = manager.GetTTL(ctx, "new_counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl)
+
+	require.NoError(t, manager.StoreWithoutBlocking(ctx, NewItem("text", "value")))
+	assert.Error(t, manager.IncreaseCachedValueByOne(ctx, "text"))
+}
+
+func TestMemoryCacheManager_Expire(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	require.NoError(t, manager.IncreaseCachedValueByOne(ctx, "counter"))
+
+	require.NoError(t, manager.Expire(ctx, "counter", 2*time.Minute))
+	ttl, err := manager.GetTTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), ttl)
+
+	// a TTL below one second delete the key
+	for _, duration := range []time.Duration{0, 500 * time.Millisecond, -time.Second} {
+		require.NoError(t, manager.IncreaseCachedValueByOne(ctx, "counter"))
+		require.NoError(t, manager.Expire(ctx, "counter", duration))
+
+		cached, err := manager.Get(ctx, "counter")
+		require.NoError(t, err)
+		assert.Nil(t, cached, duration.String())
+	}
+
+	// expiring a missing key is a no-op
+	require.NoError(t, manager.Expire(ctx, "missing", time.Minute))
+	cached, err := manager.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+}
+
+func TestMemoryCacheManager_LockWait(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+
+	_, mutex, err := manager.GetOrLock(ctx, "key")
+	require.NoError(t, err)
+	require.NotNil(t, mutex)
+
+	// the waiting caller read the value stored by the lock holder once it's released
+	type result struct {
+		value any
+		mutex Mutex
+		err   error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		value, mutex, err := manager.GetOrLock(ctx, "key")
+		resultCh <- result{value, mutex, err}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, manager.StoreWithoutBlocking(ctx, NewItem("key", "value")))
+	ok, err := mutex.Unlock()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	select {
+	case res := <-resultCh:
+		assert.NoError(t, res.err)
+		assert.Nil(t, res.mutex)
+		assert.Equal(t, []byte("value"), res.value)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiting caller wasn't woken up")
+	}
+}
+
+func TestMemoryCacheManager_LockExpiry(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	manager.SetLockDuration(100 * time.Millisecond)
+
+	_, expired, err := manager.GetOrLock(ctx, "key")
+	require.NoError(t, err)
+	require.NotNil(t, expired)
+
+	// the lock holder never unlock, the waiting caller take the lock once it expires
+	begin := time.Now()
+	_, mutex, err := manager.GetOrLock(ctx, "key")
+	require.NoError(t, err)
+	require.NotNil(t, mutex)
+	assert.GreaterOrEqual(t, time.Since(begin), 100*time.Millisecond)
+	assert.Less(t, time.Since(begin), time.Second)
+
+	// unlocking the expired lock doesn't release the new one
+	ok, err := expired.Unlock()
+	assert.Equal(t, ErrLockExpired, err)
+	assert.False(t, ok)
+	_, err = manager.AcquireLock(ctx, "key")
+	assert.Equal(t, ErrLockNotAcquired, err)
+
+	ok, err = mutex.Unlock()
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestMemoryCacheManager_WaitTooLong(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMemoryCacheManager()
+	manager.SetWaitTime(50 * time.Millisecond)
+
+	_, mutex, err := manager.GetOrLock(ctx, "key")
+	require.NoError(t, err)
+	defer SafeUnlock(mutex)
+
+	_, _, err = manager.GetOrLock(ctx, "key")
+	assert.Equal(t, ErrWaitTooLong, err)
+}
@@ -0,0 +1,40 @@
+package cacher
+
+import (
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cacheHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cacher_hits_total",
+		Help: "Number of the cache reads that found the key.",
+	})
+
+	cacheMissesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cacher_misses_total",
+		Help: "Number of the cache reads that didn't find the key.",
+	})
+
+	cacheLockWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "cacher_lock_wait_seconds",
+		Help:    "Time spent by GetOrLock waiting for the lock held by another caller.",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 15},
+	})
+
+	cacheWaitTooLongTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cacher_wait_too_long_total",
+		Help: "Number of GetOrLock calls that returned ErrWaitTooLong.",
+	})
+)
+
+// observeGet count the result of a read as a hit or a miss, a failed read is neither
+func observeGet(cachedItem any, err error) {
+	switch {
+	case cachedItem != nil:
+		cacheHitsTotal.Inc()
+	case err == nil, err == ErrKeyNotExist, err == redigo.ErrNil:
+		cacheMissesTotal.Inc()
+	}
+}
@@ -0,0 +1,45 @@
+package cacher
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/irvankadhafi/user-balance-transfer-service/cacher"
+
+// startSpan start a client span of the operation when the caller is traced, otherwise the span is a no-op
+func startSpan(ctx context.Context, operation string, keys ...string) (context.Context, trace.Span) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		// the span of an empty context is a no-op span
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+
+	return otel.Tracer(instrumentationName).Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			attribute.StringSlice("cacher.keys", keys),
+		),
+	)
+}
+
+// endSpan end the span started by startSpan, a missing key is not an error
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != ErrKeyNotExist {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func itemKeys(items []Item) []string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.GetKey()
+	}
+	return keys
+}
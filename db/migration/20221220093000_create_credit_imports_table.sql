@@ -0,0 +1,16 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS "credit_imports" (
+    id SERIAL PRIMARY KEY,
+    reference TEXT NOT NULL,
+    user_id INT NOT NULL,
+    amount INT NOT NULL,
+    user_balance_history_id INT NOT NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+ALTER TABLE "credit_imports" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+ALTER TABLE "credit_imports" ADD FOREIGN KEY ("user_balance_history_id") REFERENCES "user_balance_histories" ("id");
+ALTER TABLE "credit_imports" ADD CONSTRAINT "credit_imports_reference_unique" unique ("reference");
+
+-- +migrate Down
+DROP TABLE IF EXISTS "credit_imports";
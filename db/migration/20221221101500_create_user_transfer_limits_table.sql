@@ -0,0 +1,18 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS "user_transfer_limits" (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    min_amount INT,
+    max_amount INT,
+    daily_amount INT,
+    monthly_amount INT,
+    hourly_count INT,
+    "updated_at" TIMESTAMP NOT NULL DEFAULT 'now()',
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+ALTER TABLE "user_transfer_limits" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+ALTER TABLE "user_transfer_limits" ADD CONSTRAINT "user_transfer_limits_user_id_unique" unique ("user_id");
+
+-- +migrate Down
+DROP TABLE IF EXISTS "user_transfer_limits";
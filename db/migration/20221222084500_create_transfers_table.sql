@@ -0,0 +1,24 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS "transfers" (
+    id SERIAL PRIMARY KEY,
+    from_user_id INT NOT NULL,
+    to_user_id INT NOT NULL,
+    amount INT NOT NULL,
+    status TEXT NOT NULL,
+    session_id INT NOT NULL,
+    author TEXT NOT NULL,
+    screening_reasons TEXT NOT NULL DEFAULT '',
+    reviewed_by INT,
+    reviewed_at TIMESTAMP,
+    "updated_at" TIMESTAMP NOT NULL DEFAULT 'now()',
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("from_user_id") REFERENCES "users" ("id");
+ALTER TABLE "transfers" ADD FOREIGN KEY ("to_user_id") REFERENCES "users" ("id");
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reviewed_by") REFERENCES "users" ("id");
+CREATE INDEX IF NOT EXISTS "transfers_from_user_id_created_at_idx" ON "transfers" ("from_user_id", "created_at");
+CREATE INDEX IF NOT EXISTS "transfers_status_idx" ON "transfers" ("status");
+
+-- +migrate Down
+DROP TABLE IF EXISTS "transfers";
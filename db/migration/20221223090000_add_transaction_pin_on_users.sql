@@ -0,0 +1,5 @@
+-- +migrate Up notransaction
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "transaction_pin" TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE "users" DROP COLUMN IF EXISTS "transaction_pin";
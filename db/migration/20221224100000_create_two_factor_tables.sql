@@ -0,0 +1,28 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS "user_two_factors" (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    secret TEXT NOT NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    enabled_at TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()',
+    "updated_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+ALTER TABLE "user_two_factors" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+ALTER TABLE "user_two_factors" ADD CONSTRAINT "user_two_factors_user_id_unique" unique ("user_id");
+
+CREATE TABLE IF NOT EXISTS "user_recovery_codes" (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+ALTER TABLE "user_recovery_codes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+CREATE INDEX IF NOT EXISTS "user_recovery_codes_user_id_code_hash_idx" ON "user_recovery_codes" ("user_id", "code_hash");
+
+-- +migrate Down
+DROP TABLE IF EXISTS "user_recovery_codes";
+DROP TABLE IF EXISTS "user_two_factors";
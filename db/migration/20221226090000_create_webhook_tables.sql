@@ -0,0 +1,40 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS "webhook_subscriptions" (
+    id SERIAL PRIMARY KEY,
+    user_id INT,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT NOT NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()',
+    "updated_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+ALTER TABLE "webhook_subscriptions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+ALTER TABLE "webhook_subscriptions" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id");
+CREATE INDEX IF NOT EXISTS "webhook_subscriptions_user_id_idx" ON "webhook_subscriptions" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT 'now()',
+    delivered_at TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()',
+    "updated_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("subscription_id") REFERENCES "webhook_subscriptions" ("id");
+CREATE INDEX IF NOT EXISTS "webhook_deliveries_subscription_id_idx" ON "webhook_deliveries" ("subscription_id");
+CREATE INDEX IF NOT EXISTS "webhook_deliveries_status_next_attempt_at_idx" ON "webhook_deliveries" ("status", "next_attempt_at");
+
+-- +migrate Down
+DROP TABLE IF EXISTS "webhook_deliveries";
+DROP TABLE IF EXISTS "webhook_subscriptions";
@@ -0,0 +1,21 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS "outbox" (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_id INT NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+CREATE INDEX IF NOT EXISTS "outbox_unpublished_idx" ON "outbox" ("id") WHERE "published_at" IS NULL;
+
+-- the relay deliver at least once, a republished event must not create a second delivery
+CREATE UNIQUE INDEX IF NOT EXISTS "webhook_deliveries_subscription_id_event_id_unique" ON "webhook_deliveries" ("subscription_id", "event_id");
+
+-- +migrate Down
+DROP INDEX IF EXISTS "webhook_deliveries_subscription_id_event_id_unique";
+DROP TABLE IF EXISTS "outbox";
@@ -0,0 +1,18 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS "auth_events" (
+    id SERIAL PRIMARY KEY,
+    user_id INT,
+    event_type TEXT NOT NULL,
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    result TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    "created_at" TIMESTAMP NOT NULL DEFAULT 'now()'
+);
+
+ALTER TABLE "auth_events" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+CREATE INDEX IF NOT EXISTS "auth_events_user_id_id_idx" ON "auth_events" ("user_id", "id");
+CREATE INDEX IF NOT EXISTS "auth_events_created_at_idx" ON "auth_events" ("created_at");
+
+-- +migrate Down
+DROP TABLE IF EXISTS "auth_events";
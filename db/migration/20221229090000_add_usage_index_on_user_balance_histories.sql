@@ -0,0 +1,5 @@
+-- +migrate Up notransaction
+CREATE INDEX IF NOT EXISTS "user_balance_histories_user_balance_id_type_created_at_idx" ON "user_balance_histories" ("user_balance_id", "type", "created_at");
+
+-- +migrate Down
+DROP INDEX IF EXISTS "user_balance_histories_user_balance_id_type_created_at_idx";
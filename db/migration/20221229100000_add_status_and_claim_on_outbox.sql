@@ -0,0 +1,13 @@
+-- +migrate Up notransaction
+ALTER TABLE "outbox" ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'PENDING';
+ALTER TABLE "outbox" ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP;
+UPDATE "outbox" SET status = 'PUBLISHED' WHERE published_at IS NOT NULL;
+
+DROP INDEX IF EXISTS "outbox_unpublished_idx";
+CREATE INDEX IF NOT EXISTS "outbox_pending_idx" ON "outbox" ("id") WHERE "status" = 'PENDING';
+
+-- +migrate Down
+DROP INDEX IF EXISTS "outbox_pending_idx";
+CREATE INDEX IF NOT EXISTS "outbox_unpublished_idx" ON "outbox" ("id") WHERE "published_at" IS NULL;
+ALTER TABLE "outbox" DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE "outbox" DROP COLUMN IF EXISTS status;
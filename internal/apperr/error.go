@@ -0,0 +1,111 @@
+package apperr
+
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"net/http"
+)
+
+// Code kode error domain, dipakai oleh delivery untuk menentukan status http dan grpc
+type Code string
+
+// Code constants
+const (
+	CodeInvalidArgument    Code = "INVALID_ARGUMENT"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeAlreadyExists      Code = "ALREADY_EXISTS"
+	CodeUnauthenticated    Code = "UNAUTHENTICATED"
+	CodePermissionDenied   Code = "PERMISSION_DENIED"
+	CodeFailedPrecondition Code = "FAILED_PRECONDITION"
+	// CodeConflict the resource is in a state that conflict with the request
+	CodeConflict Code = "CONFLICT"
+	// CodeUnprocessable the request is valid but rejected by a business rule, e.g. a transfer limit
+	CodeUnprocessable Code = "UNPROCESSABLE"
+	// CodeLocked the action is locked after too many failed attempts
+	CodeLocked Code = "LOCKED"
+	// CodeTooManyRequests the rate limit of the caller is reached
+	CodeTooManyRequests Code = "TOO_MANY_REQUESTS"
+	CodeInternal        Code = "INTERNAL"
+)
+
+// Error error domain dengan kodenya, message aman untuk ditampilkan ke client
+type Error struct {
+	Code    Code
+	Message string
+}
+
+// New :nodoc:
+func New(code Code, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error :nodoc:
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CodeOf return the code of the error, an error which is not a domain error is internal
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
+
+// HTTPStatus :nodoc:
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeInvalidArgument:
+		return http.StatusBadRequest
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeAlreadyExists, CodeConflict:
+		return http.StatusConflict
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeFailedPrecondition:
+		return http.StatusPreconditionFailed
+	case CodeUnprocessable:
+		return http.StatusUnprocessableEntity
+	case CodeLocked:
+		return http.StatusLocked
+	case CodeTooManyRequests:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCCode :nodoc:
+func (c Code) GRPCCode() codes.Code {
+	switch c {
+	case CodeInvalidArgument:
+		return codes.InvalidArgument
+	case CodeNotFound:
+		return codes.NotFound
+	case CodeAlreadyExists:
+		return codes.AlreadyExists
+	case CodeUnauthenticated:
+		return codes.Unauthenticated
+	case CodePermissionDenied:
+		return codes.PermissionDenied
+	case CodeFailedPrecondition, CodeUnprocessable:
+		return codes.FailedPrecondition
+	case CodeConflict:
+		return codes.Aborted
+	case CodeLocked, CodeTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,94 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+type contextKey string
+
+// use module path to make it unique
+const clientIPCtxKey contextKey = "github.com/irvankadhafi/user-balance-transfer-service/internal/clientip.ClientIP"
+
+// NewContext set the client IP to context
+func NewContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPCtxKey, ip)
+}
+
+// FromContext get the client IP from context, empty when the context isn't from a request
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPCtxKey).(string)
+	return ip
+}
+
+// ParseTrustedProxies parse the CIDRs of the trusted proxies, a single IP is accepted as well, e.g. "10.0.0.0/8" or "10.0.0.1"
+func ParseTrustedProxies(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if ip := net.ParseIP(cidr); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// resolve the client IP of a connection from remoteAddr. The X-Forwarded-For and X-Real-IP headers are spoofable,
+// so they are only honoured when the connection is from a trusted proxy. X-Forwarded-For is read from the right,
+// the first hop that isn't a trusted proxy is the client.
+func resolve(remoteAddr, forwardedFor, realIP string, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if !isTrusted(ip, trustedProxies) {
+		return ip.String()
+	}
+
+	if forwardedFor != "" {
+		hops := strings.Split(forwardedFor, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				// the hops before a malformed one can't be trusted
+				return ip.String()
+			}
+			ip = hop
+			if !isTrusted(ip, trustedProxies) {
+				return ip.String()
+			}
+		}
+		return ip.String()
+	}
+
+	if hop := net.ParseIP(strings.TrimSpace(realIP)); hop != nil {
+		return hop.String()
+	}
+	return ip.String()
+}
+
+func isTrusted(ip net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package clientip
+
+import (
+	"context"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	networks, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.10 ", "fd00::/8", "::1"})
+	require.NoError(t, err)
+	require.Len(t, networks, 4)
+	assert.Equal(t, "10.0.0.0/8", networks[0].String())
+	assert.Equal(t, "192.168.1.10/32", networks[1].String())
+	assert.Equal(t, "fd00::/8", networks[2].String())
+	assert.Equal(t, "::1/128", networks[3].String())
+
+	_, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = ParseTrustedProxies([]string{"proxy.local"})
+	assert.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		ip           string
+	}{
+		{name: "no proxy", remoteAddr: "203.0.113.7:52100", ip: "203.0.113.7"},
+		{name: "spoofed x-forwarded-for from an untrusted address", remoteAddr: "203.0.113.7:52100", forwardedFor: "1.2.3.4", ip: "203.0.113.7"},
+		{name: "spoofed x-real-ip from an untrusted address", remoteAddr: "203.0.113.7:52100", realIP: "1.2.3.4", ip: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:52100", forwardedFor: "198.51.100.9", ip: "198.51.100.9"},
+		{name: "spoofed hop before the client", remoteAddr: "10.0.0.2:52100", forwardedFor: "1.2.3.4, 198.51.100.9", ip: "198.51.100.9"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.2:52100", forwardedFor: "198.51.100.9, 10.0.0.5, 10.0.0.3", ip: "198.51.100.9"},
+		{name: "every hop is a trusted proxy", remoteAddr: "10.0.0.2:52100", forwardedFor: "10.0.0.5, 10.0.0.3", ip: "10.0.0.5"},
+		{name: "malformed hop", remoteAddr: "10.0.0.2:52100", forwardedFor: "198.51.100.9, unknown, 10.0.0.3", ip: "10.0.0.3"},
+		{name: "x-real-ip from a trusted proxy", remoteAddr: "10.0.0.2:52100", realIP: "198.51.100.9", ip: "198.51.100.9"},
+		{name: "trusted proxy without header", remoteAddr: "10.0.0.2:52100", ip: "10.0.0.2"},
+		{name: "ipv6", remoteAddr: "[2001:db8::1]:52100", forwardedFor: "1.2.3.4", ip: "2001:db8::1"},
+		{name: "remote address without port", remoteAddr: "203.0.113.7", ip: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.ip, resolve(tt.remoteAddr, tt.forwardedFor, tt.realIP, trustedProxies))
+		})
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	var ip string
+	e := echo.New()
+	e.Pre(HTTPMiddleware(trustedProxies))
+	e.GET("/", func(c echo.Context) error {
+		ip = FromRequest(c.Request())
+		return c.NoContent(http.StatusOK)
+	})
+
+	// the headers are joined when x-forwarded-for is sent more than once
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:52100"
+	req.Header.Add(echo.HeaderXForwardedFor, "1.2.3.4")
+	req.Header.Add(echo.HeaderXForwardedFor, "198.51.100.9")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "198.51.100.9", ip)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:52100"
+	req.Header.Set(echo.HeaderXForwardedFor, "1.2.3.4")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "203.0.113.7", ip)
+}
+
+func TestFromRequest_WithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:52100"
+	req.Header.Set(echo.HeaderXForwardedFor, "1.2.3.4")
+
+	assert.Equal(t, "203.0.113.7", FromRequest(req))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	interceptor := UnaryServerInterceptor(trustedProxies)
+
+	call := func(peerAddr string, md metadata.MD) string {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(peerAddr), Port: 52100}})
+		ctx = metadata.NewIncomingContext(ctx, md)
+
+		var ip string
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+			ip = FromIncomingContext(ctx)
+			return nil, nil
+		})
+		require.NoError(t, err)
+		return ip
+	}
+
+	assert.Equal(t, "198.51.100.9", call("10.0.0.2", metadata.Pairs("x-forwarded-for", "198.51.100.9")))
+	assert.Equal(t, "198.51.100.9", call("10.0.0.2", metadata.Pairs("x-real-ip", "198.51.100.9")))
+	assert.Equal(t, "203.0.113.7", call("203.0.113.7", metadata.Pairs("x-forwarded-for", "1.2.3.4")))
+}
+
+func TestFromIncomingContext_WithoutInterceptor(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 52100}})
+
+	assert.Equal(t, "203.0.113.7", FromIncomingContext(ctx))
+	assert.Empty(t, FromIncomingContext(context.Background()))
+}
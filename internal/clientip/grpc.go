@@ -0,0 +1,41 @@
+package clientip
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"strings"
+)
+
+// UnaryServerInterceptor like HTTPMiddleware, resolve the client IP of the call from its peer address and set it to the context.
+// The `x-forwarded-for` and `x-real-ip` metadata are only honoured from trustedProxies.
+func UnaryServerInterceptor(trustedProxies []*net.IPNet) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var forwardedFor, realIP string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			forwardedFor = strings.Join(md.Get("x-forwarded-for"), ",")
+			if values := md.Get("x-real-ip"); len(values) > 0 {
+				realIP = values[0]
+			}
+		}
+
+		return handler(NewContext(ctx, resolve(peerAddr(ctx), forwardedFor, realIP, trustedProxies)), req)
+	}
+}
+
+// FromIncomingContext get the client IP of the call, the peer address is used when UnaryServerInterceptor isn't registered
+func FromIncomingContext(ctx context.Context) string {
+	if ip := FromContext(ctx); ip != "" {
+		return ip
+	}
+	return resolve(peerAddr(ctx), "", "", nil)
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package clientip
+
+import (
+	"github.com/labstack/echo"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HTTPMiddleware resolve the client IP of the request and set it to the request context.
+// The forwarding headers are only honoured from trustedProxies. Register it with Pre so every handler has it.
+func HTTPMiddleware(trustedProxies []*net.IPNet) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ip := resolve(req.RemoteAddr, strings.Join(req.Header.Values(echo.HeaderXForwardedFor), ","), req.Header.Get(echo.HeaderXRealIP), trustedProxies)
+
+			c.SetRequest(req.WithContext(NewContext(req.Context(), ip)))
+
+			return next(c)
+		}
+	}
+}
+
+// FromRequest get the client IP of the request, the remote address is used when HTTPMiddleware isn't registered
+func FromRequest(req *http.Request) string {
+	if ip := FromContext(req.Context()); ip != "" {
+		return ip
+	}
+	return resolve(req.RemoteAddr, "", "", nil)
+}
@@ -34,6 +34,17 @@ func HTTPPort() string {
 	return viper.GetString("ports.http")
 }
 
+// GRPCPort :nodoc:
+func GRPCPort() string {
+	return getStringOrDefault("ports.grpc", DefaultGRPCPort)
+}
+
+// ShutdownTimeout deadline of the graceful shutdown to drain the in-flight requests and the workers
+func ShutdownTimeout() time.Duration {
+	cfg := viper.GetString("shutdown_timeout")
+	return parseDuration(cfg, DefaultShutdownTimeout)
+}
+
 // DatabaseDSN :nodoc:
 func DatabaseDSN() string {
 	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
@@ -88,6 +99,12 @@ func DatabaseRetryAttempts() float64 {
 	return DefaultDatabaseRetryAttempts
 }
 
+// DatabasePingTimeout timeout of the ping of the connection check and the readiness
+func DatabasePingTimeout() time.Duration {
+	cfg := viper.GetString("postgres.ping_timeout")
+	return parseDuration(cfg, DefaultDatabasePingTimeout)
+}
+
 // DatabaseMaxIdleConns :nodoc:
 func DatabaseMaxIdleConns() int {
 	if viper.GetInt("postgres.max_idle_conns") <= 0 {
@@ -127,6 +144,11 @@ func RedisAuthCacheLockHost() string {
 	return viper.GetString("redis.auth_cache_lock_host")
 }
 
+// CacheDriver the cache manager used by the repositories, redis or memory
+func CacheDriver() string {
+	return getStringOrDefault("cache_driver", DefaultCacheDriver)
+}
+
 // DisableCaching :nodoc:
 func DisableCaching() bool {
 	return viper.GetBool("disable_caching")
@@ -171,19 +193,259 @@ func RedisMaxActiveConn() int {
 	return 50
 }
 
-// LoginByUsernamePasswordLockTTL :nodoc:
-func LoginByUsernamePasswordLockTTL() time.Duration {
-	cfg := viper.GetString("login.username_password.lock_ttl")
-	return parseDuration(cfg, DefaultLoginLockTTL)
+// LoginLockoutWindow the failed logins are forgotten after the window without failure
+func LoginLockoutWindow() time.Duration {
+	cfg := viper.GetString("login.lockout.window")
+	return parseDuration(cfg, DefaultLoginLockoutWindow)
 }
 
-// LoginByUsernamePasswordRetryAttempts :nodoc:
-func LoginByUsernamePasswordRetryAttempts() int {
-	if viper.IsSet("login.username_password.retry_attempts") {
-		return viper.GetInt("login.username_password.retry_attempts")
+// LoginLockoutAccountAttempts failed logins of an account before its logins are delayed
+func LoginLockoutAccountAttempts() int64 {
+	return getInt64OrDefault("login.lockout.account_attempts", DefaultLoginLockoutAccountAttempts)
+}
+
+// LoginLockoutIPAttempts failed logins of an IP, on every account, before its logins are delayed
+func LoginLockoutIPAttempts() int64 {
+	return getInt64OrDefault("login.lockout.ip_attempts", DefaultLoginLockoutIPAttempts)
+}
+
+// LoginLockoutBaseDelay delay after the first failure above the attempts, it's doubled on every next failure
+func LoginLockoutBaseDelay() time.Duration {
+	cfg := viper.GetString("login.lockout.base_delay")
+	return parseDuration(cfg, DefaultLoginLockoutBaseDelay)
+}
+
+// LoginLockoutMaxDelay :nodoc:
+func LoginLockoutMaxDelay() time.Duration {
+	cfg := viper.GetString("login.lockout.max_delay")
+	return parseDuration(cfg, DefaultLoginLockoutMaxDelay)
+}
+
+// AuthEventRetention the auth events older than this are pruned
+func AuthEventRetention() time.Duration {
+	cfg := viper.GetString("auth_event.retention")
+	return parseDuration(cfg, DefaultAuthEventRetention)
+}
+
+// AuthEventPruneInterval :nodoc:
+func AuthEventPruneInterval() time.Duration {
+	cfg := viper.GetString("auth_event.prune_interval")
+	return parseDuration(cfg, DefaultAuthEventPruneInterval)
+}
+
+// AuthEventPruneBatchSize maximum auth events deleted by one prune query
+func AuthEventPruneBatchSize() int {
+	if viper.IsSet("auth_event.prune_batch_size") {
+		return viper.GetInt("auth_event.prune_batch_size")
+	}
+
+	return DefaultAuthEventPruneBatchSize
+}
+
+// TransactionPinLockTTL :nodoc:
+func TransactionPinLockTTL() time.Duration {
+	cfg := viper.GetString("transaction_pin.lock_ttl")
+	return parseDuration(cfg, DefaultTransactionPinLockTTL)
+}
+
+// TransactionPinRetryAttempts :nodoc:
+func TransactionPinRetryAttempts() int {
+	if viper.IsSet("transaction_pin.retry_attempts") {
+		return viper.GetInt("transaction_pin.retry_attempts")
 	}
 
-	return DefaultLoginRetryAttempts
+	return DefaultTransactionPinRetryAttempts
+}
+
+// TransactionPinRequiredAmount transfer above this amount require the transaction pin, zero means every transfer
+func TransactionPinRequiredAmount() int64 {
+	return getInt64OrDefault("transaction_pin.required_amount", DefaultTransactionPinRequiredAmount)
+}
+
+// TwoFactorIssuer issuer shown by the authenticator app
+func TwoFactorIssuer() string {
+	return getStringOrDefault("two_factor.issuer", DefaultTwoFactorIssuer)
+}
+
+// LoginChallengeTTL how long the second step of a 2FA login is valid
+func LoginChallengeTTL() time.Duration {
+	cfg := viper.GetString("two_factor.challenge_ttl")
+	return parseDuration(cfg, DefaultLoginChallengeTTL)
+}
+
+// WebhookTimeout :nodoc:
+func WebhookTimeout() time.Duration {
+	cfg := viper.GetString("webhook.timeout")
+	return parseDuration(cfg, DefaultWebhookTimeout)
+}
+
+// WebhookMaxAttempts a delivery is failed after this many attempts
+func WebhookMaxAttempts() int {
+	if viper.IsSet("webhook.max_attempts") {
+		return viper.GetInt("webhook.max_attempts")
+	}
+
+	return DefaultWebhookMaxAttempts
+}
+
+// WebhookRetryMin first retry delay, doubled on every attempt
+func WebhookRetryMin() time.Duration {
+	cfg := viper.GetString("webhook.retry_min")
+	return parseDuration(cfg, DefaultWebhookRetryMin)
+}
+
+// WebhookRetryMax :nodoc:
+func WebhookRetryMax() time.Duration {
+	cfg := viper.GetString("webhook.retry_max")
+	return parseDuration(cfg, DefaultWebhookRetryMax)
+}
+
+// WebhookWorkerInterval :nodoc:
+func WebhookWorkerInterval() time.Duration {
+	cfg := viper.GetString("webhook.worker_interval")
+	return parseDuration(cfg, DefaultWebhookWorkerInterval)
+}
+
+// WebhookBatchSize :nodoc:
+func WebhookBatchSize() int {
+	if viper.IsSet("webhook.batch_size") {
+		return viper.GetInt("webhook.batch_size")
+	}
+
+	return DefaultWebhookBatchSize
+}
+
+// OutboxBatchSize :nodoc:
+func OutboxBatchSize() int {
+	if viper.IsSet("outbox.batch_size") {
+		return viper.GetInt("outbox.batch_size")
+	}
+
+	return DefaultOutboxBatchSize
+}
+
+// OutboxInterval :nodoc:
+func OutboxInterval() time.Duration {
+	cfg := viper.GetString("outbox.interval")
+	return parseDuration(cfg, DefaultOutboxInterval)
+}
+
+// OutboxMaxAttempts a message is failed after this many attempts and no longer block the later messages of its account
+func OutboxMaxAttempts() int {
+	if viper.IsSet("outbox.max_attempts") {
+		return viper.GetInt("outbox.max_attempts")
+	}
+
+	return DefaultOutboxMaxAttempts
+}
+
+// OutboxClaimLease how long a claimed batch is kept by a relay, it must outlive the publish of a whole batch
+func OutboxClaimLease() time.Duration {
+	cfg := viper.GetString("outbox.claim_lease")
+	return parseDuration(cfg, DefaultOutboxClaimLease)
+}
+
+// OutboxPublishers comma separated publishers of the relay: log, redis and webhook
+func OutboxPublishers() string {
+	return getStringOrDefault("outbox.publishers", DefaultOutboxPublishers)
+}
+
+// OutboxRedisStream :nodoc:
+func OutboxRedisStream() string {
+	return getStringOrDefault("outbox.redis_stream.name", DefaultOutboxRedisStream)
+}
+
+// OutboxRedisStreamMaxLen :nodoc:
+func OutboxRedisStreamMaxLen() int64 {
+	return getInt64OrDefault("outbox.redis_stream.max_len", DefaultOutboxRedisStreamLen)
+}
+
+// BalanceStreamHeartbeat interval of the keep alive comment of the balance stream and the ping of the redis subscription
+func BalanceStreamHeartbeat() time.Duration {
+	cfg := viper.GetString("balance_stream.heartbeat")
+	return parseDuration(cfg, DefaultBalanceStreamHeartbeat)
+}
+
+// BalanceStreamBufferSize number of updates buffered per subscriber, a subscriber that fall behind is dropped
+func BalanceStreamBufferSize() int {
+	if viper.IsSet("balance_stream.buffer_size") {
+		return viper.GetInt("balance_stream.buffer_size")
+	}
+
+	return DefaultBalanceStreamBufferSize
+}
+
+// RateLimitRoute rate limit of a route that override the default, e.g. method "POST" and path "/auth/login/",
+// a grpc method has the method "GRPC" and its full name as the path, e.g. "/userbalance.UserBalanceService/Login"
+type RateLimitRoute struct {
+	Method string        `mapstructure:"method"`
+	Path   string        `mapstructure:"path"`
+	Limit  int           `mapstructure:"limit"`
+	Period time.Duration `mapstructure:"period"`
+}
+
+// RateLimitEnabled :nodoc:
+func RateLimitEnabled() bool {
+	if viper.IsSet("rate_limit.enabled") {
+		return viper.GetBool("rate_limit.enabled")
+	}
+	return DefaultRateLimitEnabled
+}
+
+// RateLimitLimit default number of requests allowed per RateLimitPeriod, by user or IP and route
+func RateLimitLimit() int {
+	if viper.GetInt("rate_limit.limit") > 0 {
+		return viper.GetInt("rate_limit.limit")
+	}
+	return DefaultRateLimitLimit
+}
+
+// RateLimitPeriod :nodoc:
+func RateLimitPeriod() time.Duration {
+	cfg := viper.GetString("rate_limit.period")
+	return parseDuration(cfg, DefaultRateLimitPeriod)
+}
+
+// RateLimitRoutes the per route overrides of the default rate limit
+func RateLimitRoutes() []RateLimitRoute {
+	var routes []RateLimitRoute
+	if err := viper.UnmarshalKey("rate_limit.routes", &routes); err != nil {
+		log.WithField("rate_limit.routes", viper.Get("rate_limit.routes")).Error("failed to parse the rate limit routes: ", err)
+		return nil
+	}
+	return routes
+}
+
+// TrustedProxies CIDRs of the proxies whose X-Forwarded-For and X-Real-IP headers are honoured, none by default
+func TrustedProxies() []string {
+	return viper.GetStringSlice("trusted_proxies")
+}
+
+// TracingExporter exporter of the spans: none, stdout or file
+func TracingExporter() string {
+	return getStringOrDefault("tracing.exporter", DefaultTracingExporter)
+}
+
+// TracingFile file the spans are appended to by the file exporter
+func TracingFile() string {
+	return getStringOrDefault("tracing.file", DefaultTracingFile)
+}
+
+// TracingServiceName :nodoc:
+func TracingServiceName() string {
+	return getStringOrDefault("tracing.service_name", DefaultTracingServiceName)
+}
+
+// RedisPingTimeout timeout of the ping of every redis pool by the readiness
+func RedisPingTimeout() time.Duration {
+	cfg := viper.GetString("redis.ping_timeout")
+	return parseDuration(cfg, DefaultRedisPingTimeout)
+}
+
+// HealthMigrationsTimeout timeout of the readiness check that every migration is applied
+func HealthMigrationsTimeout() time.Duration {
+	cfg := viper.GetString("health.migrations_timeout")
+	return parseDuration(cfg, DefaultHealthMigrationsTimeout)
 }
 
 // CacheTTL :nodoc:
@@ -204,6 +466,87 @@ func RefreshTokenDuration() time.Duration {
 	return parseDuration(cfg, DefaultRefreshTokenDuration)
 }
 
+// TransferBatchMaxItems maximum number of recipients in a single batch transfer
+func TransferBatchMaxItems() int {
+	if viper.GetInt("transfer.batch_max_items") > 0 {
+		return viper.GetInt("transfer.batch_max_items")
+	}
+	return DefaultTransferBatchMaxItems
+}
+
+// TransferMinAmount minimum amount of a single transfer
+func TransferMinAmount() int64 {
+	return getInt64OrDefault("transfer.limit.min_amount", DefaultTransferMinAmount)
+}
+
+// TransferMaxAmount maximum amount of a single transfer, zero means unlimited
+func TransferMaxAmount() int64 {
+	return getInt64OrDefault("transfer.limit.max_amount", DefaultTransferMaxAmount)
+}
+
+// TransferDailyAmount maximum outgoing transfer amount of a day, zero means unlimited
+func TransferDailyAmount() int64 {
+	return getInt64OrDefault("transfer.limit.daily_amount", DefaultTransferDailyAmount)
+}
+
+// TransferMonthlyAmount maximum outgoing transfer amount of a month, zero means unlimited
+func TransferMonthlyAmount() int64 {
+	return getInt64OrDefault("transfer.limit.monthly_amount", DefaultTransferMonthlyAmount)
+}
+
+// TransferHourlyCount maximum number of outgoing transfer in an hour, zero means unlimited
+func TransferHourlyCount() int64 {
+	return getInt64OrDefault("transfer.limit.hourly_count", DefaultTransferHourlyCount)
+}
+
+// ScreeningNewDeviceAction action of a transfer from a session with a new device or ip address
+func ScreeningNewDeviceAction() string {
+	return getStringOrDefault("screening.new_device.action", DefaultScreeningAction)
+}
+
+// ScreeningNewRecipientAction action of a first transfer to a recipient above the threshold
+func ScreeningNewRecipientAction() string {
+	return getStringOrDefault("screening.new_recipient.action", DefaultScreeningAction)
+}
+
+// ScreeningNewRecipientThreshold minimum amount of a first transfer to a recipient that is screened
+func ScreeningNewRecipientThreshold() int64 {
+	return getInt64OrDefault("screening.new_recipient.amount_threshold", DefaultScreeningNewRecipientThreshold)
+}
+
+// ScreeningFanOutAction action of a transfer to too many recipients in a short window
+func ScreeningFanOutAction() string {
+	return getStringOrDefault("screening.fan_out.action", DefaultScreeningAction)
+}
+
+// ScreeningFanOutWindow :nodoc:
+func ScreeningFanOutWindow() time.Duration {
+	cfg := viper.GetString("screening.fan_out.window")
+	return parseDuration(cfg, DefaultScreeningFanOutWindow)
+}
+
+// ScreeningFanOutMaxRecipients maximum distinct recipients in the fan out window
+func ScreeningFanOutMaxRecipients() int {
+	if viper.GetInt("screening.fan_out.max_recipients") > 0 {
+		return viper.GetInt("screening.fan_out.max_recipients")
+	}
+	return DefaultScreeningFanOutMaxRecipients
+}
+
+func getStringOrDefault(key, defaultValue string) string {
+	if viper.IsSet(key) {
+		return viper.GetString(key)
+	}
+	return defaultValue
+}
+
+func getInt64OrDefault(key string, defaultValue int64) int64 {
+	if viper.IsSet(key) {
+		return viper.GetInt64(key)
+	}
+	return defaultValue
+}
+
 func parseDuration(in string, defaultDuration time.Duration) time.Duration {
 	dur, err := time.ParseDuration(in)
 	if err != nil {
@@ -3,17 +3,82 @@ package config
 import "time"
 
 const (
+	DefaultGRPCPort        = "3001"
+	DefaultShutdownTimeout = 30 * time.Second
+
 	DefaultDatabaseMaxIdleConns    = 3
 	DefaultDatabaseMaxOpenConns    = 5
 	DefaultDatabaseConnMaxLifetime = 1 * time.Hour
 	DefaultDatabasePingInterval    = 1 * time.Second
 	DefaultDatabaseRetryAttempts   = 3
+	DefaultDatabasePingTimeout     = 2 * time.Second
+
+	DefaultRedisPingTimeout = 1 * time.Second
+
+	DefaultHealthMigrationsTimeout = 3 * time.Second
+
+	DefaultCacheTTL    = 15 * time.Minute
+	DefaultCacheDriver = "redis"
 
-	DefaultLoginRetryAttempts = 3
-	DefaultCacheTTL           = 15 * time.Minute
-	DefaultLoginLockTTL       = 5 * time.Minute
+	DefaultLoginLockoutWindow          = 1 * time.Hour
+	DefaultLoginLockoutAccountAttempts = 3
+	DefaultLoginLockoutIPAttempts      = 20
+	DefaultLoginLockoutBaseDelay       = 1 * time.Second
+	DefaultLoginLockoutMaxDelay        = 15 * time.Minute
+
+	DefaultAuthEventRetention      = 90 * 24 * time.Hour
+	DefaultAuthEventPruneInterval  = 1 * time.Hour
+	DefaultAuthEventPruneBatchSize = 1000
 
 	DefaultSessionTokenLength   = 50
 	DefaultAccessTokenDuration  = 1 * time.Hour
 	DefaultRefreshTokenDuration = 24 * time.Hour * 1 // 1 day
+
+	DefaultTransferBatchMaxItems = 100
+
+	// zero transfer limit means unlimited
+	DefaultTransferMinAmount     = 1
+	DefaultTransferMaxAmount     = 0
+	DefaultTransferDailyAmount   = 0
+	DefaultTransferMonthlyAmount = 0
+	DefaultTransferHourlyCount   = 0
+
+	DefaultTransactionPinRetryAttempts  = 3
+	DefaultTransactionPinLockTTL        = 15 * time.Minute
+	DefaultTransactionPinRequiredAmount = 1000000
+
+	DefaultTwoFactorIssuer        = "User Balance Transfer Service"
+	DefaultLoginChallengeTTL      = 5 * time.Minute
+	DefaultTwoFactorRecoveryCodes = 10
+
+	DefaultWebhookTimeout        = 10 * time.Second
+	DefaultWebhookMaxAttempts    = 8
+	DefaultWebhookRetryMin       = 30 * time.Second
+	DefaultWebhookRetryMax       = 1 * time.Hour
+	DefaultWebhookWorkerInterval = 5 * time.Second
+	DefaultWebhookBatchSize      = 50
+
+	DefaultOutboxBatchSize      = 100
+	DefaultOutboxInterval       = 1 * time.Second
+	DefaultOutboxMaxAttempts    = 10
+	DefaultOutboxClaimLease     = 1 * time.Minute
+	DefaultOutboxPublishers     = "webhook"
+	DefaultOutboxRedisStream    = "balance-events"
+	DefaultOutboxRedisStreamLen = 100000
+
+	DefaultBalanceStreamHeartbeat  = 15 * time.Second
+	DefaultBalanceStreamBufferSize = 16
+
+	DefaultRateLimitEnabled = true
+	DefaultRateLimitLimit   = 60
+	DefaultRateLimitPeriod  = 1 * time.Minute
+
+	DefaultTracingExporter    = "none"
+	DefaultTracingFile        = "traces.jsonl"
+	DefaultTracingServiceName = "user-balance-transfer-service"
+
+	DefaultScreeningAction                = "REVIEW"
+	DefaultScreeningNewRecipientThreshold = 10000000
+	DefaultScreeningFanOutWindow          = 10 * time.Minute
+	DefaultScreeningFanOutMaxRecipients   = 5
 )
@@ -0,0 +1,37 @@
+package console
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// runAuthEventPruner delete the auth events older than the retention until the context is done,
+// a full batch is followed immediately by the next one
+func runAuthEventPruner(ctx context.Context, authUsecase model.AuthUsecase) {
+	interval := config.AuthEventPruneInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		wait := interval
+		count, err := authUsecase.PruneAuthEvents(ctx)
+		switch {
+		case err != nil:
+			log.Error(err)
+		case count >= int64(config.AuthEventPruneBatchSize()):
+			wait = 0
+		case count > 0:
+			log.WithField("count", count).Info("auth events are pruned")
+		}
+		timer.Reset(wait)
+	}
+}
@@ -5,9 +5,17 @@ import (
 	"errors"
 	goredis "github.com/go-redis/redis/v8"
 	redigo "github.com/gomodule/redigo/redis"
+	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/db"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/pubsub"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/ratelimit"
 	"github.com/labstack/echo"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"sync"
 	"time"
 )
 
@@ -22,6 +30,39 @@ type RedisConnectionPoolOptions struct {
 	MaxConnLifetime time.Duration
 }
 
+// newRedisConnectionPoolOptions return the redis connection pool options from the config
+func newRedisConnectionPoolOptions() *RedisConnectionPoolOptions {
+	return &RedisConnectionPoolOptions{
+		DialTimeout:     config.RedisDialTimeout(),
+		ReadTimeout:     config.RedisReadTimeout(),
+		WriteTimeout:    config.RedisWriteTimeout(),
+		IdleCount:       config.RedisMaxIdleConn(),
+		PoolSize:        config.RedisMaxActiveConn(),
+		IdleTimeout:     240 * time.Second,
+		MaxConnLifetime: 1 * time.Minute,
+	}
+}
+
+// newRateLimiter return the rate limiter of the config, its buckets are stored in the pool or in the process with the memory cache driver
+func newRateLimiter(pool *redigo.Pool) *ratelimit.RateLimiter {
+	store := ratelimit.NewMemoryStore()
+	if isRedisCacheDriver() {
+		store = ratelimit.NewRedisStore(pool)
+	}
+
+	rateLimiter := ratelimit.NewRateLimiter(store, config.RateLimitEnabled(), ratelimit.Rule{
+		Limit:  config.RateLimitLimit(),
+		Period: config.RateLimitPeriod(),
+	})
+	for _, route := range config.RateLimitRoutes() {
+		rateLimiter.SetRouteRule(route.Method, route.Path, ratelimit.Rule{
+			Limit:  route.Limit,
+			Period: route.Period,
+		})
+	}
+	return rateLimiter
+}
+
 // NewRedigoRedisConnectionPool uses redigo library to establish the redis connection pool
 func NewRedigoRedisConnectionPool(url string, opt *RedisConnectionPoolOptions) (*redigo.Pool, error) {
 	if !isValidRedisStandaloneURL(url) {
@@ -53,20 +94,133 @@ func isValidRedisStandaloneURL(url string) bool {
 	return err == nil
 }
 
+// isRedisCacheDriver whether the cache managers use redis, an unknown cache driver is fatal
+func isRedisCacheDriver() bool {
+	switch config.CacheDriver() {
+	case cacher.DriverRedis:
+		return true
+	case cacher.DriverMemory:
+		return false
+	default:
+		log.Fatal("unknown cache driver: ", config.CacheDriver())
+		return false
+	}
+}
+
+// newBalanceBroker return the balance broker of the cache driver, the pool is only used by redis
+func newBalanceBroker(pool *redigo.Pool) model.BalanceUpdateBroker {
+	if isRedisCacheDriver() {
+		return pubsub.NewRedisBalanceBroker(pool)
+	}
+	return pubsub.NewMemoryBalanceBroker()
+}
+
+// newCacheManager return the cache manager of the configured driver, the pools are only used by redis
+func newCacheManager(pool, lockPool *redigo.Pool) cacher.CacheManager {
+	var manager cacher.CacheManager
+	if isRedisCacheDriver() {
+		manager = cacher.NewCacheManager()
+		manager.SetConnectionPool(pool)
+		manager.SetLockConnectionPool(lockPool)
+	} else {
+		manager = cacher.NewMemoryCacheManager()
+	}
+	manager.SetDefaultTTL(config.CacheTTL())
+	return manager
+}
+
 func continueOrFatal(err error) {
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func gracefulShutdown(httpSvr *echo.Echo) {
-	db.StopTickerCh <- true
+// flushTracing export the pending spans before exiting
+func flushTracing(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		log.Error(err)
+	}
+}
+
+// shutdownTarget what gracefulShutdown stops, in the order of the fields
+type shutdownTarget struct {
+	// stopStreams close the open balance streams, they would keep the http server from draining
+	stopStreams context.CancelFunc
+	httpServer  *echo.Echo
+	grpcServer  *grpc.Server
+	stopWorkers context.CancelFunc
+	workers     *sync.WaitGroup
+	// closers close the redis pools and the database, in order
+	closers []func() error
+}
+
+// gracefulShutdown stop accepting requests, then wait for the in-flight requests such as the money transfers and for the workers.
+// What is still running after the timeout is closed. The closers are run last,
+// an error is only logged so every step is run.
+func gracefulShutdown(timeout time.Duration, target shutdownTarget) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	target.stopStreams()
+	shutdownServers(ctx, target.httpServer, target.grpcServer)
+
+	target.stopWorkers()
+	if !waitOrTimeout(ctx, target.workers) {
+		log.Warn("shutdown timeout, the workers are still running")
+	}
+
+	db.StopConnectionCheck()
+	for _, closer := range target.closers {
+		helper.WrapCloser(closer)
+	}
+}
+
+// shutdownServers drain both servers concurrently until the context is done, then close the remaining requests
+func shutdownServers(ctx context.Context, httpSvr *echo.Echo, grpcSvr *grpc.Server) {
+	var wg sync.WaitGroup
+	wg.Add(2)
 
-	if httpSvr != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+	go func() {
+		defer wg.Done()
 		if err := httpSvr.Shutdown(ctx); err != nil {
-			httpSvr.Logger.Fatal(err)
+			log.Error("failed to drain the http server: ", err)
+			helper.WrapCloser(httpSvr.Close)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		stopped := make(chan struct{})
+		go func() {
+			grpcSvr.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			log.Error("failed to drain the grpc server: ", ctx.Err())
+			grpcSvr.Stop()
 		}
+	}()
+
+	wg.Wait()
+}
+
+// waitOrTimeout return false when the context is done before the wait group
+func waitOrTimeout(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
@@ -0,0 +1,50 @@
+package console
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/delivery/httpsvc"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/labstack/echo"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestMemoryCacheDriver without a redis pool the memory driver still stream the balances and limit the requests
+func TestMemoryCacheDriver(t *testing.T) {
+	viper.Set("cache_driver", "memory")
+	viper.Set("rate_limit.limit", 1)
+	defer func() {
+		viper.Set("cache_driver", "")
+		viper.Set("rate_limit.limit", 0)
+	}()
+
+	broker := newBalanceBroker(nil)
+	updates, err := broker.Subscribe(context.Background(), 1)
+	require.NoError(t, err)
+	require.NoError(t, broker.Publish(context.Background(), &model.BalanceUpdate{UserID: 1}))
+	select {
+	case update := <-updates:
+		assert.Equal(t, 1, update.UserID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the update wasn't delivered")
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = httpsvc.HTTPErrorHandler
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, newRateLimiter(nil).Limit())
+
+	var codes []int
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes = append(codes, rec.Code)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+}
@@ -0,0 +1,345 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/db"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/repository"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/usecase"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var importCreditsCmd = &cobra.Command{
+	Use:   "import-credits",
+	Short: "import balance credits from csv",
+	Long: `This subcommand credit user balances from a csv file with the header user,amount,reference.
+The user column accept either username or email, and a reference that already imported is skipped`,
+	Run: importCredits,
+}
+
+// credit import row status
+const (
+	creditImportStatusValid   = "VALID"
+	creditImportStatusInvalid = "INVALID"
+	creditImportStatusSkipped = "SKIPPED"
+	creditImportStatusApplied = "APPLIED"
+	creditImportStatusFailed  = "FAILED"
+)
+
+var creditImportColumns = []string{"user", "amount", "reference"}
+
+type creditImportRow struct {
+	line      int
+	user      string
+	amount    string
+	reference string
+
+	userID  int
+	balance int64
+	status  string
+	message string
+}
+
+func init() {
+	importCreditsCmd.PersistentFlags().String("file", "", "csv file to import")
+	importCreditsCmd.PersistentFlags().String("output", "", "result csv file (default <file>.result.csv)")
+	importCreditsCmd.PersistentFlags().String("author", "system", "author written in the balance history")
+	importCreditsCmd.PersistentFlags().Bool("dry-run", false, "only validate the file and write the result")
+	importCreditsCmd.PersistentFlags().Bool("yes", false, "apply the credits without confirmation")
+	RootCmd.AddCommand(importCreditsCmd)
+}
+
+func importCredits(cmd *cobra.Command, args []string) {
+	file := cmd.Flag("file").Value.String()
+	if file == "" {
+		log.Fatal("--file is required")
+	}
+	output := cmd.Flag("output").Value.String()
+	if output == "" {
+		output = strings.TrimSuffix(file, ".csv") + ".result.csv"
+	}
+	author := cmd.Flag("author").Value.String()
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	rows, err := readCreditImportRows(file)
+	if err != nil {
+		log.WithField("file", file).Fatal("Failed to read csv: ", err)
+	}
+
+	// Initiate all connection like db, redis, etc
+	db.InitializePostgresConn()
+	var redisConn, redisLockConn *redigo.Pool
+	if isRedisCacheDriver() {
+		redisOpts := newRedisConnectionPoolOptions()
+
+		redisConn, err = NewRedigoRedisConnectionPool(config.RedisCacheHost(), redisOpts)
+		continueOrFatal(err)
+		defer helper.WrapCloser(redisConn.Close)
+
+		redisLockConn, err = NewRedigoRedisConnectionPool(config.RedisLockHost(), redisOpts)
+		continueOrFatal(err)
+		defer helper.WrapCloser(redisLockConn.Close)
+	}
+
+	generalCacher := newCacheManager(redisConn, redisLockConn)
+
+	userRepo := repository.NewUserRepository(db.PostgreSQL, generalCacher)
+	sessionRepo := repository.NewSessionRepository(db.PostgreSQL, generalCacher, userRepo)
+	creditImportRepo := repository.NewCreditImportRepository(db.PostgreSQL)
+	userBalanceUsecase := usecase.NewUserBalanceUsecase(
+		userRepo,
+		repository.NewUserBalanceRepository(db.PostgreSQL),
+		repository.NewUserBalanceHistoryRepository(db.PostgreSQL),
+		repository.NewGormTransactioner(db.PostgreSQL),
+		sessionRepo,
+		creditImportRepo,
+		repository.NewTransferLimitRepository(db.PostgreSQL),
+		repository.NewTransferRepository(db.PostgreSQL),
+		usecase.NewRuleTransferScreener(),
+		repository.NewOutboxRepository(db.PostgreSQL),
+		newBalanceBroker(redisConn),
+	)
+
+	ctx := context.Background()
+	validateCreditImportRows(ctx, rows, userRepo, creditImportRepo)
+	printCreditImportSummary("Dry run", rows)
+
+	if dryRun || !hasCreditImportStatus(rows, creditImportStatusValid) {
+		writeCreditImportResultOrFatal(output, rows)
+		return
+	}
+
+	if !yes && !confirm("Apply the valid credits?") {
+		log.Warn("import cancelled")
+		return
+	}
+
+	for _, row := range rows {
+		if row.status != creditImportStatusValid {
+			continue
+		}
+
+		err := userBalanceUsecase.ImportUserBalanceCredit(ctx, model.ImportUserBalanceCreditInput{
+			UserID:    row.userID,
+			Balance:   row.balance,
+			Reference: row.reference,
+			Author:    author,
+		})
+		switch err {
+		case nil:
+			row.status = creditImportStatusApplied
+		case usecase.ErrDuplicateReference:
+			row.status, row.message = creditImportStatusSkipped, err.Error()
+		default:
+			log.WithField("line", row.line).Error(err)
+			row.status, row.message = creditImportStatusFailed, err.Error()
+		}
+	}
+
+	printCreditImportSummary("Import", rows)
+	writeCreditImportResultOrFatal(output, rows)
+}
+
+func readCreditImportRows(file string) ([]*creditImportRow, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer helper.WrapCloser(f.Close)
+
+	reader := csv.NewReader(f)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range creditImportColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("missing column %q", column)
+		}
+	}
+
+	var rows []*creditImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		column := func(name string) string {
+			if i := index[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		rows = append(rows, &creditImportRow{
+			line:      line,
+			user:      column("user"),
+			amount:    column("amount"),
+			reference: column("reference"),
+		})
+	}
+
+	return rows, nil
+}
+
+func validateCreditImportRows(
+	ctx context.Context,
+	rows []*creditImportRow,
+	userRepo model.UserRepository,
+	creditImportRepo model.CreditImportRepository,
+) {
+	references := make(map[string]*creditImportRow)
+	for _, row := range rows {
+		row.status = creditImportStatusValid
+
+		balance, err := strconv.ParseInt(row.amount, 10, 64)
+		switch {
+		case row.user == "":
+			row.status, row.message = creditImportStatusInvalid, "user is required"
+			continue
+		case err != nil || balance <= 0:
+			row.status, row.message = creditImportStatusInvalid, "amount must be a positive integer"
+			continue
+		case row.reference == "":
+			row.status, row.message = creditImportStatusInvalid, "reference is required"
+			continue
+		}
+		row.balance = balance
+
+		if first, ok := references[row.reference]; ok {
+			row.status, row.message = creditImportStatusInvalid, fmt.Sprintf("duplicate reference of line %d", first.line)
+			continue
+		}
+		references[row.reference] = row
+
+		var user *model.User
+		if strings.Contains(row.user, "@") {
+			user, err = userRepo.FindByEmail(ctx, helper.FormatEmail(row.user))
+		} else {
+			user, err = userRepo.FindByUsername(ctx, row.user)
+		}
+		if err != nil {
+			log.WithField("line", row.line).Fatal("Failed to find user: ", err)
+		}
+		if user == nil {
+			row.status, row.message = creditImportStatusInvalid, "user not found"
+			continue
+		}
+		row.userID = user.ID
+	}
+
+	var validReferences []string
+	for reference, row := range references {
+		if row.status == creditImportStatusValid {
+			validReferences = append(validReferences, reference)
+		}
+	}
+
+	imported, err := creditImportRepo.FindByReferences(ctx, validReferences)
+	if err != nil {
+		log.Fatal("Failed to find imported references: ", err)
+	}
+	for _, creditImport := range imported {
+		row := references[creditImport.Reference]
+		row.status, row.message = creditImportStatusSkipped, usecase.ErrDuplicateReference.Error()
+	}
+}
+
+func printCreditImportSummary(title string, rows []*creditImportRow) {
+	counts := make(map[string]int)
+	amounts := make(map[string]int64)
+	for _, row := range rows {
+		counts[row.status]++
+		amounts[row.status] += row.balance
+	}
+
+	fmt.Printf("%s summary of %d rows\n", title, len(rows))
+	for _, status := range []string{
+		creditImportStatusValid,
+		creditImportStatusApplied,
+		creditImportStatusSkipped,
+		creditImportStatusInvalid,
+		creditImportStatusFailed,
+	} {
+		if counts[status] == 0 {
+			continue
+		}
+		fmt.Printf("  %-8s %6d rows, amount %d\n", status, counts[status], amounts[status])
+	}
+
+	for _, row := range rows {
+		if row.status == creditImportStatusInvalid || row.status == creditImportStatusFailed {
+			fmt.Printf("  line %d: %s %s\n", row.line, row.status, row.message)
+		}
+	}
+}
+
+func hasCreditImportStatus(rows []*creditImportRow, status string) bool {
+	for _, row := range rows {
+		if row.status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func writeCreditImportResultOrFatal(output string, rows []*creditImportRow) {
+	f, err := os.Create(output)
+	if err != nil {
+		log.WithField("output", output).Fatal("Failed to create result csv: ", err)
+	}
+	defer helper.WrapCloser(f.Close)
+
+	writer := csv.NewWriter(f)
+	records := [][]string{{"line", "user", "amount", "reference", "status", "message"}}
+	for _, row := range rows {
+		records = append(records, []string{
+			strconv.Itoa(row.line),
+			row.user,
+			row.amount,
+			row.reference,
+			row.status,
+			row.message,
+		})
+	}
+
+	if err = writer.WriteAll(records); err != nil {
+		log.WithField("output", output).Fatal("Failed to write result csv: ", err)
+	}
+
+	log.Infof("Result written to %s\n", output)
+}
+
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -17,6 +17,8 @@ var migrateCmd = &cobra.Command{
 	Run:   processMigration,
 }
 
+const migrationTable = "schema_migrations"
+
 func init() {
 	migrateCmd.PersistentFlags().Int("step", 0, "maximum migration steps")
 	migrateCmd.PersistentFlags().String("direction", "up", "migration direction")
@@ -31,11 +33,9 @@ func processMigration(cmd *cobra.Command, args []string) {
 		log.WithField("stepStr", stepStr).Fatal("Failed to parse step to int: ", err)
 	}
 
-	migrations := &migrate.FileMigrationSource{
-		Dir: "./db/migration",
-	}
+	migrations := newMigrationSource()
 
-	migrate.SetTable("schema_migrations")
+	migrate.SetTable(migrationTable)
 	db.InitializePostgresConn()
 	sqlDB, err := db.PostgreSQL.DB()
 	if err != nil {
@@ -58,3 +58,9 @@ func processMigration(cmd *cobra.Command, args []string) {
 	log.Infof("Applied %d migrations!\n", n)
 
 }
+
+func newMigrationSource() *migrate.FileMigrationSource {
+	return &migrate.FileMigrationSource{
+		Dir: "./db/migration",
+	}
+}
@@ -0,0 +1,145 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/db"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/publisher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/repository"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/usecase"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var outboxRelayCmd = &cobra.Command{
+	Use:   "outbox-relay",
+	Short: "publish the outbox events",
+	Long: `This subcommand publish the events written to the outbox to the publishers, in order per account and at least once.
+The publishers are log, redis (redis stream) and webhook (create the webhook deliveries)`,
+	Run: relayOutbox,
+}
+
+func init() {
+	outboxRelayCmd.PersistentFlags().String("publishers", config.OutboxPublishers(), "comma separated publishers: log, redis, webhook")
+	RootCmd.AddCommand(outboxRelayCmd)
+}
+
+func relayOutbox(cmd *cobra.Command, args []string) {
+	publishers := cmd.Flag("publishers").Value.String()
+	if !cmd.Flag("publishers").Changed {
+		// the flag default is read before the config is loaded
+		publishers = config.OutboxPublishers()
+	}
+
+	db.InitializePostgresConn()
+
+	var closers []func() error
+	defer func() {
+		for _, closer := range closers {
+			helper.WrapCloser(closer)
+		}
+	}()
+
+	var outboxPublishers []model.Publisher
+	for _, name := range strings.Split(publishers, ",") {
+		switch strings.TrimSpace(name) {
+		case "log":
+			outboxPublishers = append(outboxPublishers, publisher.NewLogPublisher())
+		case "redis":
+			redisConn, err := NewRedigoRedisConnectionPool(config.RedisCacheHost(), newRedisConnectionPoolOptions())
+			continueOrFatal(err)
+			closers = append(closers, redisConn.Close)
+			outboxPublishers = append(outboxPublishers, publisher.NewRedisStreamPublisher(redisConn, config.OutboxRedisStream(), config.OutboxRedisStreamMaxLen()))
+		case "webhook":
+			// the users are read through the cache like the server does, the memory cache need no redis
+			var redisConn, redisLockConn *redigo.Pool
+			if isRedisCacheDriver() {
+				redisOpts := newRedisConnectionPoolOptions()
+
+				var err error
+				redisConn, err = NewRedigoRedisConnectionPool(config.RedisCacheHost(), redisOpts)
+				continueOrFatal(err)
+				closers = append(closers, redisConn.Close)
+
+				redisLockConn, err = NewRedigoRedisConnectionPool(config.RedisLockHost(), redisOpts)
+				continueOrFatal(err)
+				closers = append(closers, redisLockConn.Close)
+			}
+
+			webhookUsecase := usecase.NewWebhookUsecase(
+				repository.NewWebhookRepository(db.PostgreSQL),
+				repository.NewUserRepository(db.PostgreSQL, newCacheManager(redisConn, redisLockConn)),
+			)
+			outboxPublishers = append(outboxPublishers, publisher.NewWebhookPublisher(webhookUsecase))
+		case "":
+		default:
+			log.Fatal(fmt.Sprintf("unknown publisher %q", name))
+		}
+	}
+	if len(outboxPublishers) == 0 {
+		log.Fatal("no publisher")
+	}
+
+	relay := usecase.NewOutboxRelay(
+		repository.NewOutboxRepository(db.PostgreSQL),
+		repository.NewGormTransactioner(db.PostgreSQL),
+		publisher.NewMultiPublisher(outboxPublishers...),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	// a lost database stop the relay like a signal, its error is fatal once the relay stopped
+	lostCh := make(chan error, 1)
+	go func() {
+		select {
+		case <-sigCh:
+		case err := <-db.ConnectionLost():
+			lostCh <- err
+		}
+		log.Info("stopping outbox relay")
+		cancel()
+	}()
+
+	log.Infof("outbox relay started with publishers %s", publishers)
+	runOutboxRelay(ctx, relay)
+	select {
+	case err := <-lostCh:
+		log.Fatal(err)
+	default:
+	}
+}
+
+// runOutboxRelay relay the outbox until the context is done, a full batch is followed immediately by the next one
+func runOutboxRelay(ctx context.Context, relay model.OutboxRelay) {
+	interval := config.OutboxInterval()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		wait := interval
+		count, err := relay.Relay(ctx)
+		switch {
+		case err != nil:
+			log.Error(err)
+		case count >= config.OutboxBatchSize():
+			wait = 0
+		}
+		timer.Reset(wait)
+	}
+}
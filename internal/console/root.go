@@ -3,6 +3,8 @@ package console
 import (
 	runtime "github.com/banzaicloud/logrus-runtime-formatter"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/requestid"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"os"
@@ -50,6 +52,8 @@ func setupLogger() {
 
 	log.SetFormatter(&formatter)
 	log.SetOutput(os.Stdout)
+	log.AddHook(requestid.LogrusHook{})
+	log.AddHook(tracing.LogrusHook{})
 
 	logLevel, err := log.ParseLevel(config.LogLevel())
 	if err != nil {
@@ -2,6 +2,8 @@ package console
 
 import (
 	"context"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/db"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
@@ -25,7 +27,23 @@ func seedUser(cmd *cobra.Command, args []string) {
 	// Initiate all connection like db, redis, etc
 	db.InitializePostgresConn()
 
-	userRepo := repository.NewUserRepository(db.PostgreSQL, nil)
+	// the created users may be cached as not found, the memory cache need no redis
+	var redisConn, redisLockConn *redigo.Pool
+	if isRedisCacheDriver() {
+		redisOpts := newRedisConnectionPoolOptions()
+
+		var err error
+		redisConn, err = NewRedigoRedisConnectionPool(config.RedisCacheHost(), redisOpts)
+		continueOrFatal(err)
+		defer helper.WrapCloser(redisConn.Close)
+
+		redisLockConn, err = NewRedigoRedisConnectionPool(config.RedisLockHost(), redisOpts)
+		continueOrFatal(err)
+		defer helper.WrapCloser(redisLockConn.Close)
+	}
+	generalCacher := newCacheManager(redisConn, redisLockConn)
+
+	userRepo := repository.NewUserRepository(db.PostgreSQL, generalCacher)
 
 	cipherPwd, err := helper.HashString("123456")
 	if err != nil {
@@ -47,6 +65,7 @@ func seedUser(cmd *cobra.Command, args []string) {
 		Username: "irvan",
 		Email:    "irvan@mail.com",
 		Password: cipherPwd,
+		Role:     model.RoleAdmin,
 	}
 	err = userRepo.Create(context.Background(), user2)
 	if err != nil {
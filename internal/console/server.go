@@ -1,23 +1,32 @@
 package console
 
 import (
+	"context"
 	"fmt"
+	redigo "github.com/gomodule/redigo/redis"
 	"github.com/irvankadhafi/user-balance-transfer-service/auth"
-	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/clientip"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/db"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/delivery/grpcsvc"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/delivery/httpsvc"
-	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/health"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/metrics"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/repository"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/requestid"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/usecase"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"time"
+	"sync"
+	"syscall"
 )
 
 var runCmd = &cobra.Command{
@@ -32,59 +41,97 @@ func init() {
 }
 
 func run(cmd *cobra.Command, args []string) {
+	shutdownTracing, err := tracing.Init()
+	continueOrFatal(err)
+	defer flushTracing(shutdownTracing)
+
+	trustedProxies, err := clientip.ParseTrustedProxies(config.TrustedProxies())
+	continueOrFatal(err)
+
 	// Initiate all connection like db, redis, etc
 	db.InitializePostgresConn()
-	authenticationCacher := cacher.NewCacheManager()
-	generalCacher := cacher.NewCacheManager()
 	pgDB, err := db.PostgreSQL.DB()
 	continueOrFatal(err)
-	defer helper.WrapCloser(pgDB.Close)
-
-	redisOpts := &RedisConnectionPoolOptions{
-		DialTimeout:     config.RedisDialTimeout(),
-		ReadTimeout:     config.RedisReadTimeout(),
-		WriteTimeout:    config.RedisWriteTimeout(),
-		IdleCount:       config.RedisMaxIdleConn(),
-		PoolSize:        config.RedisMaxActiveConn(),
-		IdleTimeout:     240 * time.Second,
-		MaxConnLifetime: 1 * time.Minute,
-	}
 
-	authRedisConn, err := NewRedigoRedisConnectionPool(config.RedisAuthCacheHost(), redisOpts)
-	continueOrFatal(err)
-	defer helper.WrapCloser(authRedisConn.Close)
+	metrics.RegisterDBStats(pgDB, "postgres")
 
-	authRedisLockConn, err := NewRedigoRedisConnectionPool(config.RedisAuthCacheLockHost(), redisOpts)
-	continueOrFatal(err)
-	defer helper.WrapCloser(authRedisLockConn.Close)
+	healthChecker := health.NewChecker()
+	healthChecker.Register("postgres", config.DatabasePingTimeout(), health.PostgresCheck(pgDB))
 
-	authenticationCacher.SetConnectionPool(authRedisConn)
-	authenticationCacher.SetLockConnectionPool(authRedisLockConn)
-	authenticationCacher.SetDefaultTTL(config.CacheTTL())
+	// with the memory cache driver the cache, the balance stream and the rate limiter are in the process, nothing dial redis
+	var redisPools []*redigo.Pool
+	var redisConn, authRedisConn, authRedisLockConn, redisLockConn *redigo.Pool
+	if isRedisCacheDriver() {
+		redisOpts := newRedisConnectionPoolOptions()
 
-	redisConn, err := NewRedigoRedisConnectionPool(config.RedisCacheHost(), redisOpts)
-	continueOrFatal(err)
-	defer helper.WrapCloser(redisConn.Close)
+		redisConn, err = NewRedigoRedisConnectionPool(config.RedisCacheHost(), redisOpts)
+		continueOrFatal(err)
 
-	redisLockConn, err := NewRedigoRedisConnectionPool(config.RedisLockHost(), redisOpts)
-	continueOrFatal(err)
-	defer helper.WrapCloser(redisLockConn.Close)
+		authRedisConn, err = NewRedigoRedisConnectionPool(config.RedisAuthCacheHost(), redisOpts)
+		continueOrFatal(err)
+
+		authRedisLockConn, err = NewRedigoRedisConnectionPool(config.RedisAuthCacheLockHost(), redisOpts)
+		continueOrFatal(err)
 
-	generalCacher.SetConnectionPool(redisConn)
-	generalCacher.SetLockConnectionPool(redisLockConn)
-	generalCacher.SetDefaultTTL(config.CacheTTL())
+		redisLockConn, err = NewRedigoRedisConnectionPool(config.RedisLockHost(), redisOpts)
+		continueOrFatal(err)
+
+		metrics.RegisterRedisPool("cache", redisConn)
+		metrics.RegisterRedisPool("auth_cache", authRedisConn)
+		metrics.RegisterRedisPool("auth_cache_lock", authRedisLockConn)
+		metrics.RegisterRedisPool("lock", redisLockConn)
+
+		healthChecker.Register("redis_cache", config.RedisPingTimeout(), health.RedisCheck(redisConn))
+		healthChecker.Register("redis_auth_cache", config.RedisPingTimeout(), health.RedisCheck(authRedisConn))
+		healthChecker.Register("redis_auth_cache_lock", config.RedisPingTimeout(), health.RedisCheck(authRedisLockConn))
+		healthChecker.Register("redis_lock", config.RedisPingTimeout(), health.RedisCheck(redisLockConn))
+
+		redisPools = []*redigo.Pool{redisConn, redisLockConn, authRedisLockConn, authRedisConn}
+	}
+
+	authenticationCacher := newCacheManager(authRedisConn, authRedisLockConn)
+	generalCacher := newCacheManager(redisConn, redisLockConn)
+
+	healthChecker.Register("migrations", config.HealthMigrationsTimeout(), health.MigrationsCheck(pgDB, migrationTable, newMigrationSource()))
 
 	userRepo := repository.NewUserRepository(db.PostgreSQL, generalCacher)
-	userUsecase := usecase.NewUserUsecase(userRepo)
+	loginLockoutRepo := repository.NewLoginLockoutRepository(authenticationCacher)
+	userUsecase := usecase.NewUserUsecase(userRepo, loginLockoutRepo)
 
 	sessionRepo := repository.NewSessionRepository(db.PostgreSQL, authenticationCacher, userRepo)
-	authUsecase := usecase.NewAuthUsecase(userRepo, sessionRepo, userUsecase)
+	twoFactorRepo := repository.NewTwoFactorRepository(db.PostgreSQL, authenticationCacher)
+	authUsecase := usecase.NewAuthUsecase(
+		userRepo,
+		sessionRepo,
+		userUsecase,
+		twoFactorRepo,
+		loginLockoutRepo,
+		repository.NewAuthEventRepository(db.PostgreSQL),
+	)
 	userAuther := usecase.NewUserAutherAdapter(authUsecase)
 
 	gormTransationer := repository.NewGormTransactioner(db.PostgreSQL)
 	userBalanceRepo := repository.NewUserBalanceRepository(db.PostgreSQL)
 	userBalanceHistoryRepo := repository.NewUserBalanceHistoryRepository(db.PostgreSQL)
-	userBalanceUsecase := usecase.NewUserBalanceUsecase(userRepo, userBalanceRepo, userBalanceHistoryRepo, gormTransationer, sessionRepo)
+	creditImportRepo := repository.NewCreditImportRepository(db.PostgreSQL)
+	transferLimitRepo := repository.NewTransferLimitRepository(db.PostgreSQL)
+	transferRepo := repository.NewTransferRepository(db.PostgreSQL)
+	transferScreener := usecase.NewRuleTransferScreener(usecase.NewDefaultTransferScreeningRules(sessionRepo, transferRepo)...)
+	webhookUsecase := usecase.NewWebhookUsecase(repository.NewWebhookRepository(db.PostgreSQL), userRepo)
+	balanceBroker := newBalanceBroker(redisConn)
+	userBalanceUsecase := usecase.NewUserBalanceUsecase(
+		userRepo,
+		userBalanceRepo,
+		userBalanceHistoryRepo,
+		gormTransationer,
+		sessionRepo,
+		creditImportRepo,
+		transferLimitRepo,
+		transferRepo,
+		transferScreener,
+		repository.NewOutboxRepository(db.PostgreSQL),
+		balanceBroker,
+	)
 
 	bankBalanceRepo := repository.NewBankBalanceRepository(db.PostgreSQL)
 	bankBalanceHistoryRepo := repository.NewBankBalanceHistoryRepository(db.PostgreSQL)
@@ -93,39 +140,106 @@ func run(cmd *cobra.Command, args []string) {
 	httpServer := echo.New()
 	httpMiddleware := auth.NewAuthenticationMiddleware(authenticationCacher, userAuther)
 
-	httpServer.Pre(middleware.AddTrailingSlash())
+	httpServer.HTTPErrorHandler = httpsvc.HTTPErrorHandler
+	httpServer.Pre(requestid.HTTPMiddleware())
+	httpServer.Pre(clientip.HTTPMiddleware(trustedProxies))
+	httpServer.Pre(middleware.AddTrailingSlashWithConfig(middleware.TrailingSlashConfig{Skipper: httpsvc.SkipTrailingSlash}))
 	httpServer.Use(middleware.Logger())
 	httpServer.Use(middleware.Recover())
 	httpServer.Use(middleware.CORS())
+	httpServer.Use(metrics.HTTPMiddleware())
+	httpServer.Use(tracing.HTTPMiddleware())
+
+	rateLimiter := newRateLimiter(redisConn)
+	httpsvc.RouteService(httpServer, authUsecase, userUsecase, userBalanceUsecase, bankBalanceUsecase, webhookUsecase, httpMiddleware, healthChecker, rateLimiter)
+
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		requestid.UnaryServerInterceptor(),
+		clientip.UnaryServerInterceptor(trustedProxies),
+		httpMiddleware.UnaryServerInterceptor(grpcsvc.PublicMethods...),
+		rateLimiter.UnaryServerInterceptor(),
+	))
+	grpcsvc.RouteService(grpcServer, authUsecase, userBalanceUsecase)
+
+	streamCtx, stopStreams := context.WithCancel(context.Background())
+	defer stopStreams()
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	workers := &sync.WaitGroup{}
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		runWebhookWorker(workerCtx, webhookUsecase)
+	}()
+	go func() {
+		defer workers.Done()
+		runAuthEventPruner(workerCtx, authUsecase)
+	}()
+	go func() {
+		defer workers.Done()
+		balanceBroker.Run(streamCtx)
+	}()
 
-	httpsvc.RouteService(httpServer, authUsecase, userBalanceUsecase, bankBalanceUsecase, httpMiddleware)
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%s", config.GRPCPort()))
+	continueOrFatal(err)
+	httpListener, err := net.Listen("tcp", fmt.Sprintf(":%s", config.HTTPPort()))
+	continueOrFatal(err)
 
+	closers := make([]func() error, 0, len(redisPools)+1)
+	for _, pool := range redisPools {
+		closers = append(closers, pool.Close)
+	}
+	closers = append(closers, pgDB.Close)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
 	sigCh := make(chan os.Signal, 1)
-	errCh := make(chan error, 1)
-	quitCh := make(chan bool, 1)
-	signal.Notify(sigCh, os.Interrupt)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		log.WithField("signal", sig.String()).Info("shutting down")
+		stop()
+	}()
 
+	err = serve(ctx, httpListener, grpcListener, shutdownTarget{
+		stopStreams: stopStreams,
+		httpServer:  httpServer,
+		grpcServer:  grpcServer,
+		stopWorkers: stopWorkers,
+		workers:     workers,
+		closers:     closers,
+	})
+	if err != nil {
+		flushTracing(shutdownTracing)
+		log.Fatal(err)
+	}
+	log.Info("exiting")
+}
+
+// serve run the servers on the listeners until the context is done, a server fail or the database is lost,
+// then shut down the target with gracefulShutdown. It return the error which stopped the servers.
+func serve(ctx context.Context, httpListener, grpcListener net.Listener, target shutdownTarget) error {
+	errCh := make(chan error, 2)
 	go func() {
-		for {
-			select {
-			case <-sigCh:
-				gracefulShutdown(httpServer)
-				quitCh <- true
-			case e := <-errCh:
-				log.Error(e)
-				gracefulShutdown(httpServer)
-				quitCh <- true
-			}
+		if err := target.grpcServer.Serve(grpcListener); err != nil {
+			errCh <- err
 		}
 	}()
-
 	go func() {
-		// Start HTTP server
-		if err := httpServer.Start(fmt.Sprintf(":%s", config.HTTPPort())); err != nil && err != http.ErrServerClosed {
+		target.httpServer.Listener = httpListener
+		if err := target.httpServer.Start(""); err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
 
-	<-quitCh
-	log.Info("exiting")
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errCh:
+	case err = <-db.ConnectionLost():
+	}
+
+	gracefulShutdown(config.ShutdownTimeout(), target)
+	return err
 }
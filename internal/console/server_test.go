@@ -0,0 +1,202 @@
+package console
+
+import (
+	"context"
+	"github.com/labstack/echo"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serveTestSuite servers on ephemeral ports with a slow http route and a worker, the shutdown steps are recorded in order
+type serveTestSuite struct {
+	target       shutdownTarget
+	httpListener net.Listener
+	grpcListener net.Listener
+	started      chan struct{}
+
+	mu     sync.Mutex
+	events []string
+}
+
+func newServeTestSuite(t *testing.T, requestDuration, workerStopDuration time.Duration) *serveTestSuite {
+	t.Helper()
+
+	s := &serveTestSuite{started: make(chan struct{}, 1)}
+
+	httpServer := echo.New()
+	httpServer.HideBanner = true
+	httpServer.HidePort = true
+	httpServer.GET("/slow", func(c echo.Context) error {
+		s.started <- struct{}{}
+		time.Sleep(requestDuration)
+		s.record("request done")
+		return c.String(http.StatusOK, "done")
+	})
+
+	grpcServer := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(grpcServer, health.NewServer())
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workers := &sync.WaitGroup{}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		<-workerCtx.Done()
+		time.Sleep(workerStopDuration)
+		s.record("worker stopped")
+	}()
+
+	var err error
+	s.httpListener, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.grpcListener, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s.target = shutdownTarget{
+		stopStreams: func() { s.record("streams stopped") },
+		httpServer:  httpServer,
+		grpcServer:  grpcServer,
+		stopWorkers: stopWorkers,
+		workers:     workers,
+		closers: []func() error{
+			func() error { s.record("redis closed"); return nil },
+			func() error { s.record("db closed"); return nil },
+		},
+	}
+	return s
+}
+
+func (s *serveTestSuite) record(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+func (s *serveTestSuite) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.events...)
+}
+
+// serve run serve in the background, its error is sent to the returned channel
+func (s *serveTestSuite) serve(ctx context.Context) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serve(ctx, s.httpListener, s.grpcListener, s.target)
+	}()
+	return errCh
+}
+
+func (s *serveTestSuite) httpURL() string {
+	return "http://" + s.httpListener.Addr().String() + "/slow"
+}
+
+func (s *serveTestSuite) checkGRPCHealth(t *testing.T) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, s.grpcListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	res, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, res.GetStatus())
+}
+
+// get send a request to the slow route, its response body is sent to the returned channel
+func (s *serveTestSuite) get() <-chan string {
+	bodyCh := make(chan string, 1)
+	go func() {
+		res, err := http.Get(s.httpURL())
+		if err != nil {
+			bodyCh <- err.Error()
+			return
+		}
+		defer func() { _ = res.Body.Close() }()
+		body, _ := io.ReadAll(res.Body)
+		bodyCh <- string(body)
+	}()
+	return bodyCh
+}
+
+func TestServe_ShutdownInOrder(t *testing.T) {
+	s := newServeTestSuite(t, 300*time.Millisecond, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := s.serve(ctx)
+	s.checkGRPCHealth(t)
+
+	// the request is in-flight when the shutdown start
+	bodyCh := s.get()
+	select {
+	case <-s.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the http server is not serving")
+	}
+	cancel()
+
+	assert.Equal(t, "done", <-bodyCh)
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve didn't return")
+	}
+	assert.Equal(t, []string{"streams stopped", "request done", "worker stopped", "redis closed", "db closed"}, s.recorded())
+
+	// both servers no longer accept connections
+	_, err := http.Get(s.httpURL())
+	assert.Error(t, err)
+	_, err = net.DialTimeout("tcp", s.grpcListener.Addr().String(), time.Second)
+	assert.Error(t, err)
+}
+
+func TestServe_ServerFailure(t *testing.T) {
+	s := newServeTestSuite(t, 0, 0)
+	// the http server can't serve on a closed listener
+	require.NoError(t, s.httpListener.Close())
+
+	select {
+	case err := <-s.serve(context.Background()):
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve didn't return")
+	}
+	assert.Equal(t, []string{"streams stopped", "worker stopped", "redis closed", "db closed"}, s.recorded())
+}
+
+func TestServe_ShutdownTimeout(t *testing.T) {
+	viper.Set("shutdown_timeout", "200ms")
+	defer viper.Set("shutdown_timeout", "")
+
+	// the worker outlive the shutdown timeout, the connections are closed anyway
+	s := newServeTestSuite(t, 0, 2*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := s.serve(ctx)
+	s.checkGRPCHealth(t)
+
+	begin := time.Now()
+	cancel()
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve didn't return")
+	}
+	assert.Less(t, time.Since(begin), time.Second)
+	assert.Equal(t, []string{"streams stopped", "redis closed", "db closed"}, s.recorded())
+}
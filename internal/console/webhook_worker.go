@@ -0,0 +1,35 @@
+package console
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// runWebhookWorker send the due webhook deliveries until the context is done,
+// a full batch is followed immediately by the next one
+func runWebhookWorker(ctx context.Context, webhookUsecase model.WebhookUsecase) {
+	interval := config.WebhookWorkerInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		wait := interval
+		count, err := webhookUsecase.DeliverDueDeliveries(ctx)
+		switch {
+		case err != nil:
+			log.Error(err)
+		case count >= config.WebhookBatchSize():
+			wait = 0
+		}
+		timer.Reset(wait)
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/metrics"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
 	"github.com/jpillora/backoff"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormLogger "gorm.io/gorm/logger"
 	"regexp"
+	"sync"
 	"time"
 )
 
@@ -18,8 +21,9 @@ var (
 	// PostgreSQL represents gorm DB
 	PostgreSQL *gorm.DB
 
-	// StopTickerCh signal for closing ticker channel
-	StopTickerCh chan bool
+	stopTickerCh     chan struct{}
+	stopTickerChOnce sync.Once
+	connLostCh       chan error
 
 	sqlRegexp = regexp.MustCompile(`(\$\d+)|\?`)
 )
@@ -32,7 +36,8 @@ func InitializePostgresConn() {
 	}
 
 	PostgreSQL = conn
-	StopTickerCh = make(chan bool)
+	stopTickerCh = make(chan struct{})
+	connLostCh = make(chan error, 1)
 
 	go checkConnection(time.NewTicker(config.DatabasePingInterval()))
 
@@ -52,20 +57,29 @@ func InitializePostgresConn() {
 }
 
 func checkConnection(ticker *time.Ticker) {
+	defer ticker.Stop()
+
 	for {
 		select {
-		case <-StopTickerCh:
-			ticker.Stop()
+		case <-stopTickerCh:
 			return
 		case <-ticker.C:
-			if _, err := PostgreSQL.DB(); err != nil {
-				reconnectPostgresConn()
+			if err := pingPostgres(); err != nil {
+				log.Error("failed to ping postgresql database: ", err)
+				if err = reconnectPostgresConn(); err != nil {
+					// the buffered channel never block, the check is over once the connection is lost
+					connLostCh <- err
+					return
+				}
 			}
 		}
 	}
 }
 
-func reconnectPostgresConn() {
+// reconnectPostgresConn ping the database until it's reachable again.
+// The pool replace a broken connection with a new one on the ping, so the repositories sharing the pool keep working.
+// It return an error when the retries are exhausted, or nil when the connection check is stopped meanwhile.
+func reconnectPostgresConn() error {
 	b := backoff.Backoff{
 		Factor: 2,
 		Jitter: true,
@@ -75,23 +89,49 @@ func reconnectPostgresConn() {
 
 	postgresRetryAttempts := config.DatabaseRetryAttempts()
 
+	var err error
 	for b.Attempt() < postgresRetryAttempts {
-		conn, err := openPostgresConn(config.DatabaseDSN())
-		if err != nil {
-			log.WithField("databaseDSN", config.DatabaseDSN()).Error("failed to connect postgresql database: ", err)
+		err = pingPostgres()
+		if err == nil {
+			log.Info("Reconnected to PostgreSQL Server")
+			return nil
 		}
 
-		if conn != nil {
-			PostgreSQL = conn
-			break
+		log.WithField("databaseHost", config.DatabaseHost()).Error("failed to reconnect postgresql database: ", err)
+		select {
+		case <-stopTickerCh:
+			return nil
+		case <-time.After(b.Duration()):
 		}
-		time.Sleep(b.Duration())
 	}
 
-	if b.Attempt() >= postgresRetryAttempts {
-		log.Fatal("maximum retry to connect database")
+	return fmt.Errorf("maximum retry to connect database: %w", err)
+}
+
+// StopConnectionCheck stop the connection check started by InitializePostgresConn, it doesn't block and can be called more than once
+func StopConnectionCheck() {
+	stopTickerChOnce.Do(func() {
+		if stopTickerCh != nil {
+			close(stopTickerCh)
+		}
+	})
+}
+
+// ConnectionLost receive an error when the database is still unreachable after the retries,
+// the caller should shut down since the connection check is over
+func ConnectionLost() <-chan error {
+	return connLostCh
+}
+
+func pingPostgres() error {
+	conn, err := PostgreSQL.DB()
+	if err != nil {
+		return err
 	}
-	b.Reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.DatabasePingTimeout())
+	defer cancel()
+	return conn.PingContext(ctx)
 }
 
 func openPostgresConn(dsn string) (*gorm.DB, error) {
@@ -157,11 +197,19 @@ func (g *GormCustomLogger) Error(ctx context.Context, message string, values ...
 // Trace :nodoc:
 func (g *GormCustomLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
 	sql, rows := fc()
+	elapsed := time.Since(begin)
+	metrics.ObserveDBQuery(sql, elapsed, err)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// not found is an expected result, not a failed query
+		tracing.RecordDBQuery(ctx, begin, sql, nil)
+	} else {
+		tracing.RecordDBQuery(ctx, begin, sql, err)
+	}
+
 	if g.LogLevel <= 0 {
 		return
 	}
 
-	elapsed := time.Since(begin)
 	logger := log.WithFields(log.Fields{
 		"took": elapsed,
 	})
@@ -0,0 +1,74 @@
+package grpcsvc
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Login :nodoc:
+func (s *Service) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	ipAddress, userAgent := getClientInfo(ctx)
+	session, challenge, err := s.authUsecase.LoginByEmailPassword(ctx, model.LoginRequest{
+		Email:         req.GetEmail(),
+		PlainPassword: req.GetPassword(),
+		IPAddress:     ipAddress,
+		UserAgent:     userAgent,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	if challenge != nil {
+		return &pb.LoginResponse{
+			Challenge: &pb.LoginChallenge{
+				ChallengeToken: challenge.Token,
+				ExpiresAt:      timestamppb.New(challenge.ExpiredAt),
+			},
+		}, nil
+	}
+
+	return &pb.LoginResponse{Session: newSessionProto(session)}, nil
+}
+
+// VerifyLoginChallenge :nodoc:
+func (s *Service) VerifyLoginChallenge(ctx context.Context, req *pb.VerifyLoginChallengeRequest) (*pb.Session, error) {
+	ipAddress, userAgent := getClientInfo(ctx)
+	session, err := s.authUsecase.VerifyLoginChallenge(ctx, model.VerifyLoginChallengeRequest{
+		ChallengeToken: req.GetChallengeToken(),
+		Code:           req.GetCode(),
+		IPAddress:      ipAddress,
+		UserAgent:      userAgent,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newSessionProto(session), nil
+}
+
+// RefreshToken :nodoc:
+func (s *Service) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.Session, error) {
+	ipAddress, userAgent := getClientInfo(ctx)
+	session, err := s.authUsecase.RefreshToken(ctx, model.RefreshTokenRequest{
+		RefreshToken: req.GetRefreshToken(),
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newSessionProto(session), nil
+}
+
+func newSessionProto(session *model.Session) *pb.Session {
+	return &pb.Session{
+		TokenType:             "Bearer",
+		AccessToken:           session.AccessToken,
+		AccessTokenExpiresAt:  timestamppb.New(session.AccessTokenExpiredAt),
+		RefreshToken:          session.RefreshToken,
+		RefreshTokenExpiresAt: timestamppb.New(session.RefreshTokenExpiredAt),
+	}
+}
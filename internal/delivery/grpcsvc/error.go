@@ -0,0 +1,40 @@
+package grpcsvc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// grpc errors
+var (
+	ErrInvalidArgument = status.Error(codes.InvalidArgument, "invalid argument")
+	ErrInternal        = status.Error(codes.Internal, "internal system error")
+)
+
+// grpcError return the grpc error of the domain error with the grpc code of its domain code,
+// a validation error as invalid argument with the failed fields, any other error is logged and returned as internal
+func grpcError(err error) error {
+	var (
+		appErr           *apperr.Error
+		validationErrors validator.ValidationErrors
+	)
+	if errors.As(err, &appErr) && appErr.Code != apperr.CodeInternal {
+		return status.Error(appErr.Code.GRPCCode(), appErr.Message)
+	}
+	if errors.As(err, &validationErrors) {
+		fields := make([]string, 0, len(validationErrors))
+		for _, validationError := range validationErrors {
+			fields = append(fields, fmt.Sprintf("%s failed on the '%s' tag", validationError.Field(), validationError.Tag()))
+		}
+		return status.Errorf(codes.InvalidArgument, "invalid argument: %s", strings.Join(fields, ", "))
+	}
+
+	logrus.Error(err)
+	return ErrInternal
+}
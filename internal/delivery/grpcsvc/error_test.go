@@ -0,0 +1,47 @@
+package grpcsvc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestGRPCError(t *testing.T) {
+	validationErr := (&model.AddUserBalanceInput{UserID: 1, SessionID: 1}).Validate()
+
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{name: "invalid argument", err: apperr.New(apperr.CodeInvalidArgument, "transfer batch exceed the maximum items"), code: codes.InvalidArgument, message: "transfer batch exceed the maximum items"},
+		{name: "not found", err: apperr.New(apperr.CodeNotFound, "record not found"), code: codes.NotFound, message: "record not found"},
+		{name: "already exists", err: apperr.New(apperr.CodeAlreadyExists, "email already registered"), code: codes.AlreadyExists, message: "email already registered"},
+		{name: "unauthenticated", err: apperr.New(apperr.CodeUnauthenticated, "unauthenticated"), code: codes.Unauthenticated, message: "unauthenticated"},
+		{name: "permission denied", err: apperr.New(apperr.CodePermissionDenied, "transfer denied"), code: codes.PermissionDenied, message: "transfer denied"},
+		{name: "failed precondition", err: apperr.New(apperr.CodeFailedPrecondition, "transaction pin is not set"), code: codes.FailedPrecondition, message: "transaction pin is not set"},
+		{name: "conflict", err: apperr.New(apperr.CodeConflict, "transfer is not pending review"), code: codes.Aborted, message: "transfer is not pending review"},
+		{name: "unprocessable", err: apperr.New(apperr.CodeUnprocessable, "balance not enough"), code: codes.FailedPrecondition, message: "balance not enough"},
+		{name: "locked", err: apperr.New(apperr.CodeLocked, "transaction pin is locked"), code: codes.ResourceExhausted, message: "transaction pin is locked"},
+		{name: "too many requests", err: apperr.New(apperr.CodeTooManyRequests, "too many requests"), code: codes.ResourceExhausted, message: "too many requests"},
+		{name: "internal", err: apperr.New(apperr.CodeInternal, "database is down"), code: codes.Internal, message: "internal system error"},
+		{name: "wrapped domain error", err: fmt.Errorf("transfer: %w", apperr.New(apperr.CodeUnprocessable, "balance not enough")), code: codes.FailedPrecondition, message: "balance not enough"},
+		{name: "validation error", err: validationErr, code: codes.InvalidArgument, message: "invalid argument: balance failed on the 'required' tag"},
+		{name: "unknown error", err: errors.New("pq: connection refused"), code: codes.Internal, message: "internal system error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := status.FromError(grpcError(tt.err))
+			assert.True(t, ok)
+			assert.Equal(t, tt.code, st.Code())
+			assert.Equal(t, tt.message, st.Message())
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package grpcsvc
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/clientip"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// PublicMethods methods that can be called without an access token
+var PublicMethods = []string{
+	"/userbalance.UserBalanceService/Login",
+	"/userbalance.UserBalanceService/VerifyLoginChallenge",
+	"/userbalance.UserBalanceService/RefreshToken",
+}
+
+// Service grpc service
+type Service struct {
+	pb.UnimplementedUserBalanceServiceServer
+	authUsecase        model.AuthUsecase
+	userBalanceUsecase model.UserBalanceUsecase
+}
+
+// RouteService register the service to the grpc server
+func RouteService(
+	server *grpc.Server,
+	authUsecase model.AuthUsecase,
+	userBalanceUsecase model.UserBalanceUsecase,
+) {
+	pb.RegisterUserBalanceServiceServer(server, &Service{
+		authUsecase:        authUsecase,
+		userBalanceUsecase: userBalanceUsecase,
+	})
+}
+
+// getAuthUserFromCtx return the user loaded by the auth interceptor
+func getAuthUserFromCtx(ctx context.Context) *model.User {
+	authUser := auth.GetUserFromCtx(ctx)
+	if authUser == nil {
+		return nil
+	}
+
+	return &model.User{
+		ID:        authUser.ID,
+		SessionID: authUser.SessionID,
+	}
+}
+
+// getClientInfo return the client ip address resolved by the clientip interceptor and the user agent from the metadata
+func getClientInfo(ctx context.Context) (ipAddress, userAgent string) {
+	ipAddress = clientip.FromIncomingContext(ctx)
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+
+	return ipAddress, userAgent
+}
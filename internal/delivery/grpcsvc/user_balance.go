@@ -0,0 +1,169 @@
+package grpcsvc
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/usecase"
+	"github.com/irvankadhafi/user-balance-transfer-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+)
+
+// GetBalance :nodoc:
+func (s *Service) GetBalance(ctx context.Context, _ *pb.GetBalanceRequest) (*pb.UserBalance, error) {
+	user := getAuthUserFromCtx(ctx)
+
+	balance, err := s.userBalanceUsecase.GetCurrentUserBalanceByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &pb.UserBalance{
+		Id:             int64(balance.ID),
+		UserId:         int64(balance.UserID),
+		Balance:        balance.Balance,
+		BalanceAchieve: balance.BalanceAchieve,
+		CreatedAt:      timestamppb.New(balance.CreatedAt),
+	}, nil
+}
+
+// AddBalance :nodoc:
+func (s *Service) AddBalance(ctx context.Context, req *pb.AddBalanceRequest) (*pb.AddBalanceResponse, error) {
+	user := getAuthUserFromCtx(ctx)
+
+	err := s.userBalanceUsecase.AddUserBalance(ctx, model.AddUserBalanceInput{
+		UserID:    user.ID,
+		Balance:   req.GetBalance(),
+		SessionID: user.SessionID,
+		Author:    req.GetAuthor(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &pb.AddBalanceResponse{}, nil
+}
+
+// TransferBalance transfer balance to another user, a transfer held for review is returned with the PENDING_REVIEW status
+func (s *Service) TransferBalance(ctx context.Context, req *pb.TransferBalanceRequest) (*pb.Transfer, error) {
+	user := getAuthUserFromCtx(ctx)
+
+	transfer, err := s.userBalanceUsecase.TransferUserBalance(ctx, model.TransferUserBalanceInput{
+		FromUserID: user.ID,
+		ToUserID:   int(req.GetToUserId()),
+		Balance:    req.GetBalance(),
+		SessionID:  user.SessionID,
+		Author:     req.GetAuthor(),
+		Pin:        req.GetPin(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newTransferProto(transfer), nil
+}
+
+// TransferBalanceBatch :nodoc:
+func (s *Service) TransferBalanceBatch(ctx context.Context, req *pb.TransferBalanceBatchRequest) (*pb.TransferBalanceBatchResponse, error) {
+	user := getAuthUserFromCtx(ctx)
+
+	mode := model.TransferBatchMode(req.GetMode())
+	if mode == "" {
+		mode = model.TransferBatchModeAtomic
+	}
+
+	items := make([]model.TransferBatchItem, len(req.GetTransfers()))
+	for i, transfer := range req.GetTransfers() {
+		items[i] = model.TransferBatchItem{
+			ToUserID: int(transfer.GetToUserId()),
+			Balance:  transfer.GetBalance(),
+		}
+	}
+
+	results, err := s.userBalanceUsecase.TransferUserBalanceBatch(ctx, model.TransferUserBalanceBatchInput{
+		FromUserID: user.ID,
+		Items:      items,
+		Mode:       mode,
+		SessionID:  user.SessionID,
+		Author:     req.GetAuthor(),
+		Pin:        req.GetPin(),
+	})
+	if err != nil && err != usecase.ErrTransferBatchFailed {
+		return nil, grpcError(err)
+	}
+
+	res := &pb.TransferBalanceBatchResponse{Mode: string(mode)}
+	for _, result := range results {
+		res.Results = append(res.Results, &pb.TransferBatchItemResult{
+			ToUserId: int64(result.ToUserID),
+			Balance:  result.Balance,
+			Status:   string(result.Status),
+			Reason:   result.Reason,
+		})
+	}
+
+	if err == usecase.ErrTransferBatchFailed {
+		// the results of an aborted batch are sent as the detail of the status
+		st, detailErr := status.New(codes.Aborted, "transfer batch aborted").WithDetails(res)
+		if detailErr != nil {
+			return nil, grpcError(detailErr)
+		}
+		return nil, st.Err()
+	}
+
+	return res, nil
+}
+
+// GetBalanceStatement return the balance histories in [from, to)
+func (s *Service) GetBalanceStatement(ctx context.Context, req *pb.GetBalanceStatementRequest) (*pb.BalanceStatement, error) {
+	user := getAuthUserFromCtx(ctx)
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, ErrInvalidArgument
+	}
+
+	statement, err := s.userBalanceUsecase.GetUserBalanceStatement(ctx, user.ID, req.GetFrom().AsTime(), req.GetTo().AsTime())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	res := &pb.BalanceStatement{
+		UserId:         int64(statement.UserID),
+		From:           timestamppb.New(statement.From),
+		To:             timestamppb.New(statement.To),
+		OpeningBalance: statement.OpeningBalance,
+		ClosingBalance: statement.ClosingBalance,
+		TotalCredit:    statement.Totals[model.CREDIT],
+		TotalDebit:     statement.Totals[model.DEBIT],
+	}
+	for _, history := range statement.Histories {
+		res.Histories = append(res.Histories, &pb.BalanceHistory{
+			Id:            int64(history.ID),
+			BalanceBefore: history.BalanceBefore,
+			BalanceAfter:  history.BalanceAfter,
+			Activity:      history.Activity,
+			Type:          string(history.Type),
+			Author:        history.Author,
+			CreatedAt:     timestamppb.New(history.CreatedAt),
+		})
+	}
+
+	return res, nil
+}
+
+func newTransferProto(transfer *model.Transfer) *pb.Transfer {
+	res := &pb.Transfer{
+		Id:         int64(transfer.ID),
+		FromUserId: int64(transfer.FromUserID),
+		ToUserId:   int64(transfer.ToUserID),
+		Amount:     transfer.Amount,
+		Status:     string(transfer.Status),
+		CreatedAt:  timestamppb.New(transfer.CreatedAt),
+	}
+	if transfer.ScreeningReasons != "" {
+		res.ScreeningReasons = strings.Split(transfer.ScreeningReasons, "; ")
+	}
+
+	return res
+}
@@ -0,0 +1,81 @@
+package httpsvc
+
+import (
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+func (s *Service) handleGetPendingTransfers() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		transfers, err := s.userBalanceUsecase.FindPendingTransfers(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, transfers)
+	}
+}
+
+func (s *Service) handleReviewPendingTransfer(approve bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		transferID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return ErrInvalidArgument
+		}
+
+		transfer, err := s.userBalanceUsecase.ReviewPendingTransfer(ctx, user.ID, transferID, approve)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, transfer)
+	}
+}
+
+func (s *Service) handleGetLoginLockouts() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		query := model.LoginLockoutQuery{}
+		if err := c.Bind(&query); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+
+		lockouts, err := s.authUsecase.FindLoginLockouts(ctx, user.ID, query)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, lockouts)
+	}
+}
+
+func (s *Service) handleUnlockLogin() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		req := model.LoginLockoutQuery{}
+		if err := c.Bind(&req); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+
+		if err := s.authUsecase.UnlockLogin(ctx, user.ID, req); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
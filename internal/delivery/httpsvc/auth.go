@@ -3,8 +3,8 @@ package httpsvc
 import (
 	"context"
 	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/clientip"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
-	"github.com/irvankadhafi/user-balance-transfer-service/internal/usecase"
 	"github.com/irvankadhafi/user-balance-transfer-service/utils"
 	"github.com/labstack/echo"
 	"github.com/sirupsen/logrus"
@@ -19,6 +19,12 @@ type loginResponse struct {
 	RefreshTokenExpiresAt string `json:"refresh_token_expires_at"`
 }
 
+type loginChallengeResponse struct {
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	ChallengeToken     string `json:"challenge_token"`
+	ChallengeExpiresAt string `json:"challenge_expires_at"`
+}
+
 func (s *Service) handleLoginByEmailPassword() echo.HandlerFunc {
 	type request struct {
 		Email    string `json:"email"`
@@ -31,33 +37,127 @@ func (s *Service) handleLoginByEmailPassword() echo.HandlerFunc {
 			return ErrInvalidArgument
 		}
 
-		session, err := s.authUsecase.LoginByEmailPassword(c.Request().Context(), model.LoginRequest{
+		session, challenge, err := s.authUsecase.LoginByEmailPassword(c.Request().Context(), model.LoginRequest{
 			Email:         req.Email,
 			PlainPassword: req.Password,
-			IPAddress:     c.RealIP(),
+			IPAddress:     clientip.FromRequest(c.Request()),
 			UserAgent:     c.Request().UserAgent(),
 		})
-		switch err {
-		case nil:
-			break
-		case usecase.ErrNotFound, usecase.ErrUnauthorized:
-			return ErrEmailPasswordNotMatch
-		case usecase.ErrLoginMaxAttempts:
-			return ErrLoginByEmailPasswordLocked
-		default:
+		if err != nil {
+			return err
+		}
+
+		if challenge != nil {
+			return c.JSON(http.StatusOK, loginChallengeResponse{
+				TwoFactorRequired:  true,
+				ChallengeToken:     challenge.Token,
+				ChallengeExpiresAt: utils.FormatTimeRFC3339(&challenge.ExpiredAt),
+			})
+		}
+
+		return c.JSON(http.StatusOK, newLoginResponse(session))
+	}
+}
+
+func (s *Service) handleVerifyLoginChallenge() echo.HandlerFunc {
+	type request struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+
+	return func(c echo.Context) error {
+		req := request{}
+		if err := c.Bind(&req); err != nil {
 			logrus.Error(err)
-			return ErrInternal
+			return ErrInvalidArgument
 		}
 
-		res := loginResponse{
-			TokenType:             "Bearer",
-			AccessToken:           session.AccessToken,
-			AccessTokenExpiresAt:  utils.FormatTimeRFC3339(&session.AccessTokenExpiredAt),
-			RefreshToken:          session.RefreshToken,
-			RefreshTokenExpiresAt: utils.FormatTimeRFC3339(&session.RefreshTokenExpiredAt),
+		session, err := s.authUsecase.VerifyLoginChallenge(c.Request().Context(), model.VerifyLoginChallengeRequest{
+			ChallengeToken: req.ChallengeToken,
+			Code:           req.Code,
+			IPAddress:      clientip.FromRequest(c.Request()),
+			UserAgent:      c.Request().UserAgent(),
+		})
+		if err != nil {
+			return err
 		}
 
-		return c.JSON(http.StatusOK, res)
+		return c.JSON(http.StatusOK, newLoginResponse(session))
+	}
+}
+
+func (s *Service) handleEnrollTwoFactor() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		enrollment, err := s.authUsecase.EnrollTwoFactor(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+func (s *Service) handleEnableTwoFactor() echo.HandlerFunc {
+	type request struct {
+		Code string `json:"code"`
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+
+		recoveryCodes, err := s.authUsecase.EnableTwoFactor(ctx, user.ID, req.Code)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, response{RecoveryCodes: recoveryCodes})
+	}
+}
+
+func (s *Service) handleDisableTwoFactor() echo.HandlerFunc {
+	type request struct {
+		Code string `json:"code"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+
+		if err := s.authUsecase.DisableTwoFactor(ctx, user.ID, req.Code); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+func newLoginResponse(session *model.Session) loginResponse {
+	return loginResponse{
+		TokenType:             "Bearer",
+		AccessToken:           session.AccessToken,
+		AccessTokenExpiresAt:  utils.FormatTimeRFC3339(&session.AccessTokenExpiredAt),
+		RefreshToken:          session.RefreshToken,
+		RefreshTokenExpiresAt: utils.FormatTimeRFC3339(&session.RefreshTokenExpiredAt),
 	}
 }
 
@@ -75,18 +175,11 @@ func (s *Service) handleRefreshToken() echo.HandlerFunc {
 
 		session, err := s.authUsecase.RefreshToken(c.Request().Context(), model.RefreshTokenRequest{
 			RefreshToken: req.RefreshToken,
-			IPAddress:    c.RealIP(),
+			IPAddress:    clientip.FromRequest(c.Request()),
 			UserAgent:    c.Request().UserAgent(),
 		})
-		switch err {
-		case nil:
-		//case usecase.ErrRefreshTokenExpired, usecase.ErrNotFound:
-		//	return ErrUnauthenticated
-		//case usecase.ErrDiscrepantAppID:
-		//	return ErrUnauthorized
-		default:
-			logrus.Error(err)
-			return ErrInternal
+		if err != nil {
+			return err
 		}
 
 		res := loginResponse{
@@ -102,24 +195,31 @@ func (s *Service) handleRefreshToken() echo.HandlerFunc {
 
 func (s *Service) handleLogout() echo.HandlerFunc {
 	return func(c echo.Context) error {
-		//ctx := c.Request().Context()
-		//requester := GetAuthUserFromCtx(ctx)
-		//
-		//err := s.authUsecase.DeleteSessionByID(c.Request().Context(), requester.SessionID)
-		//switch err {
-		//case nil:
-		//	break
-		////case usecase.ErrNotFound:
-		////	return ErrNotFound
-		//default:
-		//	logrus.Error(err)
-		//	return httpValidationOrInternalErr(err)
-		//}
+		ctx := c.Request().Context()
+		requester := GetAuthUserFromCtx(ctx)
+
+		if err := s.authUsecase.DeleteSessionByID(ctx, requester.SessionID); err != nil {
+			return err
+		}
 
 		return c.NoContent(http.StatusNoContent)
 	}
 }
 
+func (s *Service) handleGetSecurityEvents() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		events, err := s.authUsecase.FindSecurityEvents(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, events)
+	}
+}
+
 // GetAuthUserFromCtx ..
 func GetAuthUserFromCtx(ctx context.Context) *model.User {
 	authUser := auth.GetUserFromCtx(ctx)
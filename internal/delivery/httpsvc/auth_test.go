@@ -0,0 +1,75 @@
+package httpsvc
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/clientip"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/ratelimit"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeAuthUsecase record the IP address of the login requests
+type fakeAuthUsecase struct {
+	model.AuthUsecase
+	ipAddresses []string
+}
+
+func (f *fakeAuthUsecase) LoginByEmailPassword(_ context.Context, req model.LoginRequest) (*model.Session, *model.LoginChallenge, error) {
+	f.ipAddresses = append(f.ipAddresses, req.IPAddress)
+	return &model.Session{}, nil, nil
+}
+
+func (f *fakeAuthUsecase) VerifyLoginChallenge(_ context.Context, req model.VerifyLoginChallengeRequest) (*model.Session, error) {
+	f.ipAddresses = append(f.ipAddresses, req.IPAddress)
+	return &model.Session{}, nil
+}
+
+func (f *fakeAuthUsecase) RefreshToken(_ context.Context, req model.RefreshTokenRequest) (*model.Session, error) {
+	f.ipAddresses = append(f.ipAddresses, req.IPAddress)
+	return &model.Session{}, nil
+}
+
+func TestAuthHandlers_ClientIP(t *testing.T) {
+	trustedProxies, err := clientip.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	authUsecase := &fakeAuthUsecase{}
+	e := echo.New()
+	e.Pre(clientip.HTTPMiddleware(trustedProxies))
+	RouteService(e, authUsecase, nil, nil, nil, nil, auth.NewAuthenticationMiddleware(nil, nil), nil, ratelimit.NewRateLimiter(nil, false, ratelimit.Rule{}))
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		ip           string
+	}{
+		// a spoofed x-forwarded-for can't move the lockout counter of an attacker to another IP
+		{remoteAddr: "203.0.113.7:52100", forwardedFor: "1.2.3.4", ip: "203.0.113.7"},
+		{remoteAddr: "10.0.0.2:52100", forwardedFor: "1.2.3.4, 198.51.100.9", ip: "198.51.100.9"},
+	}
+
+	for _, path := range []string{"/auth/login/", "/auth/login/2fa/", "/auth/refresh-token/"} {
+		for _, tt := range tests {
+			t.Run(path+" from "+tt.remoteAddr, func(t *testing.T) {
+				authUsecase.ipAddresses = nil
+
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set(echo.HeaderXForwardedFor, tt.forwardedFor)
+				req.RemoteAddr = tt.remoteAddr
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, []string{tt.ip}, authUsecase.ipAddresses)
+			})
+		}
+	}
+}
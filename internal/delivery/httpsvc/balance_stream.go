@@ -0,0 +1,86 @@
+package httpsvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/labstack/echo"
+	"net/http"
+	"time"
+)
+
+const balanceStreamEvent = "balance"
+
+// handleGetUserBalanceStream stream the balance of the user as server-sent events. The current balance is sent first,
+// then every change with its history. The stream is closed when the client fall behind, the client should reconnect.
+func (s *Service) handleGetUserBalanceStream() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		// subscribe before reading the balance, so no change is missed in between
+		updates, err := s.userBalanceUsecase.SubscribeBalanceUpdates(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		balance, err := s.userBalanceUsecase.GetCurrentUserBalanceByUserID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+
+		if err = writeBalanceStreamEvent(res, &model.BalanceUpdate{UserID: user.ID, Balance: balance}); err != nil {
+			return nil
+		}
+
+		heartbeat := time.NewTicker(config.BalanceStreamHeartbeat())
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case update, ok := <-updates:
+				if !ok {
+					return nil
+				}
+				if err = writeBalanceStreamEvent(res, update); err != nil {
+					return nil
+				}
+			case <-heartbeat.C:
+				if _, err = fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+					return nil
+				}
+				res.Flush()
+			}
+		}
+	}
+}
+
+// writeBalanceStreamEvent write the update as a single event, the history id is used as the event id
+func writeBalanceStreamEvent(res *echo.Response, update *model.BalanceUpdate) error {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	if update.History != nil {
+		if _, err = fmt.Fprintf(res, "id: %d\n", update.History.ID); err != nil {
+			return err
+		}
+	}
+	if _, err = fmt.Fprintf(res, "event: %s\ndata: %s\n\n", balanceStreamEvent, data); err != nil {
+		return err
+	}
+
+	res.Flush()
+	return nil
+}
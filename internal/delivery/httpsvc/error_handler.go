@@ -0,0 +1,113 @@
+package httpsvc
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/requestid"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// errorResponse envelope of every error response
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// HTTPErrorHandler write every error returned by the handlers and middlewares as an errorResponse.
+// A domain error use the status of its code, an unknown error is logged and hidden behind an internal error.
+func HTTPErrorHandler(err error, c echo.Context) {
+	status, res := newErrorResponse(err)
+	res.RequestID = requestid.FromContext(c.Request().Context())
+	if status >= http.StatusInternalServerError {
+		logrus.WithContext(c.Request().Context()).WithFields(logrus.Fields{
+			"method": c.Request().Method,
+			"path":   c.Path(),
+		}).Error(err)
+	}
+
+	if c.Response().Committed {
+		return
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, res)
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+func newErrorResponse(err error) (int, *errorResponse) {
+	var (
+		httpErr          *echo.HTTPError
+		appErr           *apperr.Error
+		validationErrors validator.ValidationErrors
+	)
+
+	switch {
+	case errors.As(err, &httpErr):
+		res := &errorResponse{Code: codeFromHTTPStatus(httpErr.Code)}
+		switch msg := httpErr.Message.(type) {
+		case string:
+			res.Message = msg
+		case echo.Map:
+			res.Message, _ = msg["message"].(string)
+		}
+		if res.Message == "" {
+			res.Message = strings.ToLower(http.StatusText(httpErr.Code))
+		}
+		return httpErr.Code, res
+	case errors.As(err, &appErr) && appErr.Code != apperr.CodeInternal:
+		return appErr.Code.HTTPStatus(), &errorResponse{
+			Code:    string(appErr.Code),
+			Message: appErr.Message,
+		}
+	case errors.As(err, &validationErrors):
+		return http.StatusBadRequest, &errorResponse{
+			Code:    string(apperr.CodeInvalidArgument),
+			Message: "invalid argument",
+			Details: validationFields(validationErrors),
+		}
+	default:
+		return http.StatusInternalServerError, &errorResponse{
+			Code:    string(apperr.CodeInternal),
+			Message: "internal system error",
+		}
+	}
+}
+
+// codeFromHTTPStatus return the domain code of the status, a status without a domain code use its upper snake case text
+func codeFromHTTPStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return string(apperr.CodeInvalidArgument)
+	case http.StatusNotFound:
+		return string(apperr.CodeNotFound)
+	case http.StatusConflict:
+		return string(apperr.CodeConflict)
+	case http.StatusUnauthorized:
+		return string(apperr.CodeUnauthenticated)
+	case http.StatusForbidden:
+		return string(apperr.CodePermissionDenied)
+	case http.StatusPreconditionFailed:
+		return string(apperr.CodeFailedPrecondition)
+	case http.StatusUnprocessableEntity:
+		return string(apperr.CodeUnprocessable)
+	case http.StatusLocked:
+		return string(apperr.CodeLocked)
+	case http.StatusTooManyRequests:
+		return string(apperr.CodeTooManyRequests)
+	case http.StatusInternalServerError:
+		return string(apperr.CodeInternal)
+	default:
+		return strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+	}
+}
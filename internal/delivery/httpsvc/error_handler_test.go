@@ -0,0 +1,87 @@
+package httpsvc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/requestid"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newValidationErrors the validation errors of an invalid input
+func newValidationErrors(t *testing.T, input interface{ Validate() error }) error {
+	t.Helper()
+
+	err := input.Validate()
+	require.IsType(t, validator.ValidationErrors{}, err)
+	return err
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+		details map[string]any
+	}{
+		{name: "invalid argument", err: apperr.New(apperr.CodeInvalidArgument, "transfer batch exceed the maximum items"), status: http.StatusBadRequest, code: "INVALID_ARGUMENT", message: "transfer batch exceed the maximum items"},
+		{name: "not found", err: apperr.New(apperr.CodeNotFound, "record not found"), status: http.StatusNotFound, code: "NOT_FOUND", message: "record not found"},
+		{name: "already exists", err: apperr.New(apperr.CodeAlreadyExists, "email already registered"), status: http.StatusConflict, code: "ALREADY_EXISTS", message: "email already registered"},
+		{name: "unauthenticated", err: apperr.New(apperr.CodeUnauthenticated, "unauthenticated"), status: http.StatusUnauthorized, code: "UNAUTHENTICATED", message: "unauthenticated"},
+		{name: "permission denied", err: apperr.New(apperr.CodePermissionDenied, "transfer denied"), status: http.StatusForbidden, code: "PERMISSION_DENIED", message: "transfer denied"},
+		{name: "failed precondition", err: apperr.New(apperr.CodeFailedPrecondition, "transaction pin is not set"), status: http.StatusPreconditionFailed, code: "FAILED_PRECONDITION", message: "transaction pin is not set"},
+		{name: "conflict", err: apperr.New(apperr.CodeConflict, "transfer is not pending review"), status: http.StatusConflict, code: "CONFLICT", message: "transfer is not pending review"},
+		{name: "unprocessable", err: apperr.New(apperr.CodeUnprocessable, "balance not enough"), status: http.StatusUnprocessableEntity, code: "UNPROCESSABLE", message: "balance not enough"},
+		{name: "locked", err: apperr.New(apperr.CodeLocked, "transaction pin is locked"), status: http.StatusLocked, code: "LOCKED", message: "transaction pin is locked"},
+		{name: "too many requests", err: apperr.New(apperr.CodeTooManyRequests, "too many requests"), status: http.StatusTooManyRequests, code: "TOO_MANY_REQUESTS", message: "too many requests"},
+		{name: "internal", err: apperr.New(apperr.CodeInternal, "database is down"), status: http.StatusInternalServerError, code: "INTERNAL", message: "internal system error"},
+		{name: "wrapped domain error", err: fmt.Errorf("transfer: %w", apperr.New(apperr.CodeUnprocessable, "balance not enough")), status: http.StatusUnprocessableEntity, code: "UNPROCESSABLE", message: "balance not enough"},
+		{name: "validation error", err: newValidationErrors(t, &model.AddUserBalanceInput{UserID: 1, SessionID: 1}), status: http.StatusBadRequest, code: "INVALID_ARGUMENT", message: "invalid argument", details: map[string]any{"balance": "Failed on the 'required' tag"}},
+		{name: "validation error of a field hidden from json", err: newValidationErrors(t, &model.SetTransactionPinInput{UserID: 1, Pin: "12"}), status: http.StatusBadRequest, code: "INVALID_ARGUMENT", message: "invalid argument", details: map[string]any{"pin": "Failed on the 'len' tag"}},
+		{name: "echo error", err: ErrInvalidArgument, status: http.StatusBadRequest, code: "INVALID_ARGUMENT", message: "invalid argument"},
+		{name: "echo error without a domain code", err: echo.ErrMethodNotAllowed, status: http.StatusMethodNotAllowed, code: "METHOD_NOT_ALLOWED", message: "Method Not Allowed"},
+		{name: "unknown error", err: errors.New("pq: connection refused"), status: http.StatusInternalServerError, code: "INTERNAL", message: "internal system error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user-balance/transfer/", nil)
+			req = req.WithContext(requestid.NewContext(req.Context(), "req-1"))
+			rec := httptest.NewRecorder()
+
+			HTTPErrorHandler(tt.err, echo.New().NewContext(req, rec))
+
+			assert.Equal(t, tt.status, rec.Code)
+			res := map[string]any{}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, tt.code, res["code"])
+			assert.Equal(t, tt.message, res["message"])
+			assert.Equal(t, "req-1", res["request_id"])
+			if tt.details == nil {
+				assert.NotContains(t, res, "details")
+			} else {
+				assert.Equal(t, tt.details, res["details"])
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandler_Head(t *testing.T) {
+	req := httptest.NewRequest(http.MethodHead, "/user-balance/", nil)
+	rec := httptest.NewRecorder()
+
+	HTTPErrorHandler(apperr.New(apperr.CodeNotFound, "record not found"), echo.New().NewContext(req, rec))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
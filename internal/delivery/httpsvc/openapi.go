@@ -0,0 +1,55 @@
+package httpsvc
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// openAPISpec OpenAPI document of every route, keep it in sync when a route is added or changed
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (s *Service) handleGetOpenAPI() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, openAPISpec)
+	}
+}
+
+// MissingOpenAPIRoutes return the routes that are not documented in the OpenAPI document, e.g. "GET /user-balance/"
+func MissingOpenAPIRoutes(routes []*echo.Route) ([]string, error) {
+	spec := struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+
+	var missing []string
+	for _, route := range routes {
+		path := openAPIPath(route.Path)
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; ok {
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("%s %s", route.Method, path))
+	}
+	sort.Strings(missing)
+
+	return missing, nil
+}
+
+// openAPIPath convert the echo path params to the OpenAPI path params, e.g. /webhooks/:id/ to /webhooks/{id}/
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + strings.TrimPrefix(segment, ":") + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
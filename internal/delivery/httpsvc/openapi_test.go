@@ -0,0 +1,55 @@
+package httpsvc
+
+import (
+	"encoding/json"
+	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/ratelimit"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// newRoutedEcho an echo with every route registered, no usecase or connection is needed to register them
+func newRoutedEcho() *echo.Echo {
+	e := echo.New()
+	RouteService(e, nil, nil, nil, nil, nil, auth.NewAuthenticationMiddleware(nil, nil), nil, ratelimit.NewRateLimiter(nil, false, ratelimit.Rule{}))
+	return e
+}
+
+func TestOpenAPIDocumentCoversEveryRoute(t *testing.T) {
+	missing, err := MissingOpenAPIRoutes(newRoutedEcho().Routes())
+	require.NoError(t, err)
+	assert.Empty(t, missing, "add the routes to internal/delivery/httpsvc/openapi.json")
+}
+
+func TestOpenAPIDocumentHasNoUnknownRoute(t *testing.T) {
+	routes := make(map[string]bool)
+	for _, route := range newRoutedEcho().Routes() {
+		routes[route.Method+" "+openAPIPath(route.Path)] = true
+	}
+
+	spec := struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	require.NoError(t, json.Unmarshal(openAPISpec, &spec))
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			route := strings.ToUpper(method) + " " + path
+			assert.True(t, routes[route], "%s is documented but not routed", route)
+		}
+	}
+}
+
+func TestMissingOpenAPIRoutes(t *testing.T) {
+	missing, err := MissingOpenAPIRoutes([]*echo.Route{
+		{Method: "GET", Path: "/user-balance/"},
+		{Method: "DELETE", Path: "/webhooks/:id/"},
+		{Method: "PUT", Path: "/webhooks/:id/"},
+		{Method: "GET", Path: "/unknown/"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET /unknown/", "PUT /webhooks/{id}/"}, missing)
+}
@@ -2,8 +2,11 @@ package httpsvc
 
 import (
 	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/health"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/metrics"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
-	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/ratelimit"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/usecase"
 	"github.com/labstack/echo"
 	"github.com/sirupsen/logrus"
 	"net/http"
@@ -13,47 +16,103 @@ import (
 type Service struct {
 	echo               *echo.Echo
 	authUsecase        model.AuthUsecase
+	userUsecase        model.UserUsecase
 	userBalanceUsecase model.UserBalanceUsecase
 	bankBalanceUsecase model.BankBalanceUsecase
+	webhookUsecase     model.WebhookUsecase
 	httpMiddleware     *auth.AuthenticationMiddleware
+	healthChecker      *health.Checker
+	rateLimiter        *ratelimit.RateLimiter
 }
 
 // RouteService add dependencies and use group for routing
 func RouteService(
 	echo *echo.Echo,
 	authUsecase model.AuthUsecase,
+	userUsecase model.UserUsecase,
 	userBalanceUsecase model.UserBalanceUsecase,
 	bankBalanceUsecase model.BankBalanceUsecase,
+	webhookUsecase model.WebhookUsecase,
 	authMiddleware *auth.AuthenticationMiddleware,
+	healthChecker *health.Checker,
+	rateLimiter *ratelimit.RateLimiter,
 ) {
 	srv := &Service{
 		echo:               echo,
 		authUsecase:        authUsecase,
+		userUsecase:        userUsecase,
 		userBalanceUsecase: userBalanceUsecase,
 		bankBalanceUsecase: bankBalanceUsecase,
+		webhookUsecase:     webhookUsecase,
 		httpMiddleware:     authMiddleware,
+		healthChecker:      healthChecker,
+		rateLimiter:        rateLimiter,
 	}
 	srv.initRoutes()
 }
 
+// SkipTrailingSlash skip the trailing slash middleware for the routes that are served without it
+func SkipTrailingSlash(c echo.Context) bool {
+	switch c.Request().URL.Path {
+	case "/openapi.json", "/metrics", "/healthz", "/readyz":
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *Service) initRoutes() {
+	s.echo.GET("/openapi.json", s.handleGetOpenAPI())
+	s.echo.GET("/metrics", metrics.Handler())
+	s.echo.GET("/healthz", health.LivenessHandler())
+	s.echo.GET("/readyz", health.ReadinessHandler(s.healthChecker))
+
 	// auth
-	s.echo.POST("/auth/login/", s.handleLoginByEmailPassword())
+	s.echo.POST("/auth/login/", s.handleLoginByEmailPassword(), s.rateLimiter.Limit())
+	s.echo.POST("/auth/login/2fa/", s.handleVerifyLoginChallenge(), s.rateLimiter.Limit())
+	s.echo.POST("/auth/refresh-token/", s.handleRefreshToken(), s.rateLimiter.Limit())
+	s.echo.POST("/auth/2fa/enroll/", s.handleEnrollTwoFactor(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/auth/2fa/enable/", s.handleEnableTwoFactor(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/auth/2fa/disable/", s.handleDisableTwoFactor(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/auth/logout/", s.handleLogout(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.GET("/me/security-events/", s.handleGetSecurityEvents(), s.httpMiddleware.MustAuthenticateAccessToken())
+
+	// transaction pin
+	s.echo.POST("/user/transaction-pin/", s.handleSetTransactionPin(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/user/transaction-pin/change/", s.handleChangeTransactionPin(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/user/transaction-pin/reset/", s.handleResetTransactionPin(), s.httpMiddleware.MustAuthenticateAccessToken())
 
 	s.echo.GET("/user-balance/", s.handleGetUserBalance(), s.httpMiddleware.MustAuthenticateAccessToken())
-	s.echo.POST("/user-balance/add/", s.handleAddUserBalance(), s.httpMiddleware.MustAuthenticateAccessToken())
-	s.echo.POST("/user-balance/transfer/", s.handleUserBalanceTransfer(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.GET("/user-balance/stream/", s.handleGetUserBalanceStream(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.GET("/user-balance/statement/", s.handleGetUserBalanceStatement(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/user-balance/add/", s.handleAddUserBalance(), s.httpMiddleware.MustAuthenticateAccessToken(), s.rateLimiter.Limit())
+	s.echo.POST("/user-balance/transfer/", s.handleUserBalanceTransfer(), s.httpMiddleware.MustAuthenticateAccessToken(), s.rateLimiter.Limit())
+	s.echo.POST("/user-balance/transfer/batch/", s.handleUserBalanceTransferBatch(), s.httpMiddleware.MustAuthenticateAccessToken(), s.rateLimiter.Limit())
 
-	s.echo.POST("/bank-balance/create/", s.handleCreateBankBalance(), s.httpMiddleware.MustAuthenticateAccessToken())
-	s.echo.POST("/bank-balance/add/", s.handleAddBankBalance(), s.httpMiddleware.MustAuthenticateAccessToken())
-	s.echo.POST("/bank-balance/transfer/", s.handleAddBankBalance(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/bank-balance/create/", s.handleCreateBankBalance(), s.httpMiddleware.MustAuthenticateAccessToken(), s.rateLimiter.Limit())
+	s.echo.POST("/bank-balance/add/", s.handleAddBankBalance(), s.httpMiddleware.MustAuthenticateAccessToken(), s.rateLimiter.Limit())
+	s.echo.POST("/bank-balance/transfer/", s.handleAddBankBalance(), s.httpMiddleware.MustAuthenticateAccessToken(), s.rateLimiter.Limit())
+
+	// webhook
+	s.echo.POST("/webhooks/", s.handleCreateWebhookSubscription(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.GET("/webhooks/", s.handleGetWebhookSubscriptions(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.DELETE("/webhooks/:id/", s.handleDeleteWebhookSubscription(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.GET("/webhooks/:id/deliveries/", s.handleGetWebhookDeliveries(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/webhooks/deliveries/:id/replay/", s.handleReplayWebhookDelivery(), s.httpMiddleware.MustAuthenticateAccessToken())
+
+	// admin
+	s.echo.GET("/admin/transfers/pending/", s.handleGetPendingTransfers(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/admin/transfers/:id/approve/", s.handleReviewPendingTransfer(true), s.httpMiddleware.MustAuthenticateAccessToken(), s.rateLimiter.Limit())
+	s.echo.POST("/admin/transfers/:id/reject/", s.handleReviewPendingTransfer(false), s.httpMiddleware.MustAuthenticateAccessToken(), s.rateLimiter.Limit())
+	s.echo.GET("/admin/login-lockouts/", s.handleGetLoginLockouts(), s.httpMiddleware.MustAuthenticateAccessToken())
+	s.echo.POST("/admin/login-lockouts/unlock/", s.handleUnlockLogin(), s.httpMiddleware.MustAuthenticateAccessToken())
 
 }
 
 func (s *Service) handleAddBankBalance() echo.HandlerFunc {
 	type request struct {
 		Code    string `json:"code"`
-		Balance string `json:"balance"`
+		Balance int64  `json:"balance"`
 		Author  string `json:"author"`
 	}
 
@@ -69,15 +128,12 @@ func (s *Service) handleAddBankBalance() echo.HandlerFunc {
 
 		err := s.bankBalanceUsecase.AddBankBalance(ctx, model.AddBankBalanceInput{
 			Code:      req.Code,
-			Balance:   utils.StringToInt64(req.Balance),
+			Balance:   req.Balance,
 			SessionID: user.SessionID,
 			Author:    req.Author,
 		})
-		switch err {
-		case nil:
-		default:
-			logrus.Error(err)
-			return ErrInternal
+		if err != nil {
+			return err
 		}
 
 		return c.JSON(http.StatusOK, "ok")
@@ -99,12 +155,12 @@ func (s *Service) handleCreateBankBalance() echo.HandlerFunc {
 			return ErrInvalidArgument
 		}
 
-		err := s.bankBalanceUsecase.CreateBankAccount(ctx, user.SessionID, req.Code)
-		switch err {
-		case nil:
-		default:
-			logrus.Error(err)
-			return ErrInternal
+		err := s.bankBalanceUsecase.CreateBankAccount(ctx, model.CreateBankAccountInput{
+			Code:      req.Code,
+			SessionID: user.SessionID,
+		})
+		if err != nil {
+			return err
 		}
 
 		return c.JSON(http.StatusOK, "ok")
@@ -128,8 +184,9 @@ func (s *Service) handleGetUserBalance() echo.HandlerFunc {
 func (s *Service) handleUserBalanceTransfer() echo.HandlerFunc {
 	type request struct {
 		ToUserID int    `json:"to_user_id"`
-		Balance  string `json:"balance"`
+		Balance  int64  `json:"balance"`
 		Author   string `json:"author"`
+		Pin      string `json:"pin"`
 	}
 
 	return func(c echo.Context) error {
@@ -141,27 +198,89 @@ func (s *Service) handleUserBalanceTransfer() echo.HandlerFunc {
 			logrus.Error(err)
 			return ErrInvalidArgument
 		}
-		err := s.userBalanceUsecase.TransferUserBalance(ctx, model.TransferUserBalanceInput{
+		transfer, err := s.userBalanceUsecase.TransferUserBalance(ctx, model.TransferUserBalanceInput{
 			FromUserID: user.ID,
 			ToUserID:   req.ToUserID,
-			Balance:    utils.StringToInt64(req.Balance),
+			Balance:    req.Balance,
 			SessionID:  user.SessionID,
 			Author:     req.Author,
+			Pin:        req.Pin,
+		})
+		if err != nil {
+			return err
+		}
+
+		if transfer.Status == model.TransferStatusPendingReview {
+			return c.JSON(http.StatusAccepted, transfer)
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+func (s *Service) handleUserBalanceTransferBatch() echo.HandlerFunc {
+	type transferItem struct {
+		ToUserID int   `json:"to_user_id"`
+		Balance  int64 `json:"balance"`
+	}
+
+	type request struct {
+		Mode      model.TransferBatchMode `json:"mode"`
+		Transfers []transferItem          `json:"transfers"`
+		Author    string                  `json:"author"`
+		Pin       string                  `json:"pin"`
+	}
+
+	type response struct {
+		Mode    model.TransferBatchMode          `json:"mode"`
+		Results []*model.TransferBatchItemResult `json:"results"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+		if req.Mode == "" {
+			req.Mode = model.TransferBatchModeAtomic
+		}
+
+		items := make([]model.TransferBatchItem, len(req.Transfers))
+		for i, transfer := range req.Transfers {
+			items[i] = model.TransferBatchItem{
+				ToUserID: transfer.ToUserID,
+				Balance:  transfer.Balance,
+			}
+		}
+
+		results, err := s.userBalanceUsecase.TransferUserBalanceBatch(ctx, model.TransferUserBalanceBatchInput{
+			FromUserID: user.ID,
+			Items:      items,
+			Mode:       req.Mode,
+			SessionID:  user.SessionID,
+			Author:     req.Author,
+			Pin:        req.Pin,
 		})
 		switch err {
 		case nil:
+		case usecase.ErrTransferBatchFailed:
+			// an aborted batch is not an error response, its results tell which transfer failed
+			return c.JSON(http.StatusUnprocessableEntity, response{Mode: req.Mode, Results: results})
 		default:
-			logrus.Error(err)
-			return ErrInternal
+			return err
 		}
 
-		return c.JSON(http.StatusOK, "ok")
+		return c.JSON(http.StatusOK, response{Mode: req.Mode, Results: results})
 	}
 }
 
 func (s *Service) handleAddUserBalance() echo.HandlerFunc {
 	type request struct {
-		Balance string `json:"balance"`
+		Balance int64  `json:"balance"`
 		Author  string `json:"author"`
 	}
 
@@ -177,15 +296,12 @@ func (s *Service) handleAddUserBalance() echo.HandlerFunc {
 
 		err := s.userBalanceUsecase.AddUserBalance(ctx, model.AddUserBalanceInput{
 			UserID:    user.ID,
-			Balance:   utils.StringToInt64(req.Balance),
+			Balance:   req.Balance,
 			SessionID: user.SessionID,
 			Author:    req.Author,
 		})
-		switch err {
-		case nil:
-		default:
-			logrus.Error(err)
-			return ErrInternal
+		if err != nil {
+			return err
 		}
 
 		return c.JSON(http.StatusOK, "ok")
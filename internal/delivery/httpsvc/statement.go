@@ -0,0 +1,177 @@
+package httpsvc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statement formats
+const (
+	statementFormatCSV = "csv"
+	statementFormatPDF = "pdf"
+)
+
+const statementDateLayout = "2006-01-02"
+
+func (s *Service) handleGetUserBalanceStatement() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		from, err := parseStatementTime(c.QueryParam("from"), false)
+		if err != nil {
+			return ErrInvalidArgument
+		}
+		to, err := parseStatementTime(c.QueryParam("to"), true)
+		if err != nil {
+			return ErrInvalidArgument
+		}
+
+		format := c.QueryParam("format")
+		if format == "" {
+			format = statementFormatCSV
+		}
+		if format != statementFormatCSV && format != statementFormatPDF {
+			return ErrInvalidArgument
+		}
+
+		statement, err := s.userBalanceUsecase.GetUserBalanceStatement(ctx, user.ID, from, to)
+		if err != nil {
+			return err
+		}
+
+		var (
+			body        []byte
+			contentType string
+		)
+		switch format {
+		case statementFormatPDF:
+			body, err = renderStatementPDF(statement)
+			contentType = "application/pdf"
+		default:
+			body, err = renderStatementCSV(statement)
+			contentType = "text/csv"
+		}
+		if err != nil {
+			logrus.Error(err)
+			return ErrInternal
+		}
+
+		filename := fmt.Sprintf("statement_%s_%s.%s", from.Format(statementDateLayout), to.Format(statementDateLayout), format)
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+		return c.Blob(http.StatusOK, contentType, body)
+	}
+}
+
+// parseStatementTime parse RFC3339 or a date, a date used as the end of the period include the whole day
+func parseStatementTime(value string, isEnd bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(statementDateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if isEnd {
+		return t.AddDate(0, 0, 1), nil
+	}
+	return t, nil
+}
+
+func renderStatementCSV(statement *model.UserBalanceStatement) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+
+	records := [][]string{
+		{"created_at", "activity", "type", "amount", "balance_before", "balance_after", "author"},
+		{statement.From.Format(time.RFC3339), "Opening Balance", "", "", "", formatInt64(statement.OpeningBalance), ""},
+	}
+	for _, history := range statement.Histories {
+		records = append(records, []string{
+			history.CreatedAt.Format(time.RFC3339),
+			history.Activity,
+			string(history.Type),
+			formatInt64(history.Amount()),
+			formatInt64(history.BalanceBefore),
+			formatInt64(history.BalanceAfter),
+			history.Author,
+		})
+	}
+	for _, transactionType := range []model.TransactionType{model.CREDIT, model.DEBIT} {
+		records = append(records, []string{"", "Total " + string(transactionType), string(transactionType), formatInt64(statement.Totals[transactionType]), "", "", ""})
+	}
+	records = append(records, []string{statement.To.Format(time.RFC3339), "Closing Balance", "", "", "", formatInt64(statement.ClosingBalance), ""})
+
+	if err := writer.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderStatementPDF(statement *model.UserBalanceStatement) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Balance Statement", false)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Balance Statement", "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("User ID: %d", statement.UserID), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Period: %s - %s", statement.From.Format(time.RFC3339), statement.To.Format(time.RFC3339)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Opening Balance: %d", statement.OpeningBalance), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	headers := []string{"Date", "Activity", "Type", "Amount", "Before", "After"}
+	widths := []float64{35, 65, 18, 24, 24, 24}
+	aligns := []string{"L", "L", "C", "R", "R", "R"}
+
+	pdf.SetFont("Helvetica", "B", 9)
+	for i, header := range headers {
+		pdf.CellFormat(widths[i], 7, header, "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, history := range statement.Histories {
+		row := []string{
+			history.CreatedAt.Format("2006-01-02 15:04:05"),
+			history.Activity,
+			string(history.Type),
+			formatInt64(history.Amount()),
+			formatInt64(history.BalanceBefore),
+			formatInt64(history.BalanceAfter),
+		}
+		for i, value := range row {
+			pdf.CellFormat(widths[i], 6, value, "1", 0, aligns[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, transactionType := range []model.TransactionType{model.CREDIT, model.DEBIT} {
+		pdf.CellFormat(0, 6, fmt.Sprintf("Total %s: %d", transactionType, statement.Totals[transactionType]), "", 1, "L", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Closing Balance: %d", statement.ClosingBalance), "", 1, "L", false, 0, "")
+
+	buf := &bytes.Buffer{}
+	if err := pdf.Output(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func formatInt64(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
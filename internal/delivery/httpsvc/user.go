@@ -0,0 +1,93 @@
+package httpsvc
+
+import (
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func (s *Service) handleSetTransactionPin() echo.HandlerFunc {
+	type request struct {
+		Pin string `json:"pin"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+
+		err := s.userUsecase.SetTransactionPin(ctx, model.SetTransactionPinInput{
+			UserID: user.ID,
+			Pin:    req.Pin,
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+func (s *Service) handleChangeTransactionPin() echo.HandlerFunc {
+	type request struct {
+		OldPin string `json:"old_pin"`
+		NewPin string `json:"new_pin"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+
+		err := s.userUsecase.ChangeTransactionPin(ctx, model.ChangeTransactionPinInput{
+			UserID: user.ID,
+			OldPin: req.OldPin,
+			NewPin: req.NewPin,
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+func (s *Service) handleResetTransactionPin() echo.HandlerFunc {
+	type request struct {
+		Password string `json:"password"`
+		NewPin   string `json:"new_pin"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+
+		err := s.userUsecase.ResetTransactionPin(ctx, model.ResetTransactionPinInput{
+			UserID:        user.ID,
+			PlainPassword: req.Password,
+			NewPin:        req.NewPin,
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
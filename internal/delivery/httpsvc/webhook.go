@@ -0,0 +1,117 @@
+package httpsvc
+
+import (
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+func (s *Service) handleCreateWebhookSubscription() echo.HandlerFunc {
+	type request struct {
+		URL        string                   `json:"url"`
+		EventTypes []model.WebhookEventType `json:"event_types"`
+		IsApp      bool                     `json:"is_app"`
+	}
+
+	type response struct {
+		*model.WebhookSubscription
+		Secret string `json:"secret"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			logrus.Error(err)
+			return ErrInvalidArgument
+		}
+
+		subscription, err := s.webhookUsecase.CreateSubscription(ctx, model.CreateWebhookSubscriptionInput{
+			UserID:     user.ID,
+			URL:        req.URL,
+			EventTypes: req.EventTypes,
+			IsApp:      req.IsApp,
+		})
+		if err != nil {
+			return err
+		}
+
+		// the secret is only shown once
+		return c.JSON(http.StatusCreated, response{WebhookSubscription: subscription, Secret: subscription.Secret})
+	}
+}
+
+func (s *Service) handleGetWebhookSubscriptions() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		subscriptions, err := s.webhookUsecase.FindSubscriptions(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, subscriptions)
+	}
+}
+
+func (s *Service) handleDeleteWebhookSubscription() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		subscriptionID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return ErrInvalidArgument
+		}
+
+		err = s.webhookUsecase.DeleteSubscription(ctx, user.ID, subscriptionID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+func (s *Service) handleGetWebhookDeliveries() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		subscriptionID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return ErrInvalidArgument
+		}
+
+		deliveries, err := s.webhookUsecase.FindDeliveries(ctx, user.ID, subscriptionID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+func (s *Service) handleReplayWebhookDelivery() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		user := GetAuthUserFromCtx(ctx)
+
+		deliveryID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return ErrInvalidArgument
+		}
+
+		delivery, err := s.webhookUsecase.ReplayDelivery(ctx, user.ID, deliveryID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusAccepted, delivery)
+	}
+}
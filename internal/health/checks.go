@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	redigo "github.com/gomodule/redigo/redis"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// PostgresCheck ping the database
+func PostgresCheck(db *sql.DB) CheckFunc {
+	return db.PingContext
+}
+
+// RedisCheck borrow a connection from the pool and ping the redis server
+func RedisCheck(pool *redigo.Pool) CheckFunc {
+	return func(ctx context.Context) error {
+		conn := pool.Get()
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		_, err := conn.Do("PING")
+		return err
+	}
+}
+
+// MigrationsCheck fail when a migration of the source isn't applied to the database yet
+func MigrationsCheck(db *sql.DB, table string, source migrate.MigrationSource) CheckFunc {
+	set := migrate.MigrationSet{TableName: table, DisableCreateTable: true}
+
+	return func(ctx context.Context) error {
+		planned, _, err := set.PlanMigration(db, "postgres", source, migrate.Up, 0)
+		if err != nil {
+			return err
+		}
+		if len(planned) > 0 {
+			return fmt.Errorf("%d migrations are not applied", len(planned))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// statuses of a check and of the report
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc check a dependency, return nil when it's usable
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Checker run the dependency checks of the readiness
+type Checker struct {
+	checks []check
+}
+
+// NewChecker :nodoc:
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Register add a dependency check, a check that doesn't return within the timeout is down
+func (c *Checker) Register(name string, timeout time.Duration, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// Report result of every check, the status is down when one of the checks is down
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult :nodoc:
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Check run every check concurrently
+func (c *Checker) Check(ctx context.Context) Report {
+	results := make([]CheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i := range c.checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c.checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(c.checks))}
+	for i, ch := range c.checks {
+		report.Checks[ch.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, ch check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, ch.timeout)
+	defer cancel()
+
+	// the check runs in its own goroutine, so a check that ignores the context can't block the report
+	errCh := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		errCh <- ch.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout after %s", ch.timeout)
+	}
+
+	res := CheckResult{Status: StatusUp, Duration: time.Since(start).String()}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+
+	return res
+}
@@ -0,0 +1,24 @@
+package health
+
+import (
+	"github.com/labstack/echo"
+	"net/http"
+)
+
+// LivenessHandler report the process is up, it doesn't check the dependencies
+func LivenessHandler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, echo.Map{"status": StatusUp})
+	}
+}
+
+// ReadinessHandler report the status of every dependency, the status is 503 when one of them is down
+func ReadinessHandler(checker *Checker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		report := checker.Check(c.Request().Context())
+		if report.Status != StatusUp {
+			return c.JSON(http.StatusServiceUnavailable, report)
+		}
+		return c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,114 @@
+package helper
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret the SHA1 secret of the RFC 6238 test vectors, "12345678901234567890" in base32
+var rfc6238Secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateTOTPCode_RFC6238Vectors(t *testing.T) {
+	// the RFC vectors have 8 digits, a 6 digits code is their last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := GenerateTOTPCode(rfc6238Secret, TOTPTimeStep(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "unix %d", tt.unix)
+	}
+}
+
+func TestGenerateTOTPCode_InvalidSecret(t *testing.T) {
+	_, err := GenerateTOTPCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidateTOTPCode_Window(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := TOTPTimeStep(now)
+
+	tests := []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{name: "previous step", offset: -1, ok: true},
+		{name: "current step", offset: 0, ok: true},
+		{name: "next step", offset: 1, ok: true},
+		{name: "two steps before", offset: -2, ok: false},
+		{name: "two steps after", offset: 2, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := GenerateTOTPCode(rfc6238Secret, current+tt.offset)
+			require.NoError(t, err)
+
+			step, ok := ValidateTOTPCode(rfc6238Secret, code, now)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, current+tt.offset, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPCode_Malformed(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870822", "abcdef"} {
+		_, ok := ValidateTOTPCode(rfc6238Secret, code, now)
+		assert.False(t, ok, "code %q", code)
+	}
+
+	// the surrounding spaces of a pasted code are ignored
+	_, ok := ValidateTOTPCode(rfc6238Secret, " 287082 ", now)
+	assert.True(t, ok)
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	code, err := GenerateTOTPCode(secret, 1)
+	require.NoError(t, err)
+	assert.Len(t, code, TOTPDigits)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("User Balance", "johndoe@mail.com", "SECRET")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/User%20Balance:johndoe@mail.com?"), uri)
+	assert.Contains(t, uri, "secret=SECRET")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	require.NoError(t, err)
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+
+	// the code match however it's typed
+	hash := HashRecoveryCode(code)
+	assert.Equal(t, hash, HashRecoveryCode(" "+strings.ToUpper(code)+" "))
+	assert.Equal(t, hash, HashRecoveryCode(strings.ReplaceAll(code, "-", "")))
+
+	other, err := GenerateRecoveryCode()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, HashRecoveryCode(other))
+}
@@ -0,0 +1,128 @@
+package helper
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// WebhookSignatureHeader header of the webhook signature, formatted as t=<unix timestamp>,v1=<hex hmac>
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// webhook url errors
+var (
+	ErrWebhookURLNotHTTPS       = errors.New("webhook url must use https")
+	ErrWebhookAddressNotAllowed = errors.New("webhook address is not public")
+)
+
+// lookupIPAddr resolve the webhook host, replaced in tests
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// GenerateRandomHex generate a random hex string of n bytes
+func GenerateRandomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SignWebhookPayload sign "<timestamp>.<payload>" with HMAC-SHA256, the timestamp let the receiver reject a replayed request
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// IsPublicIP whether the ip may receive a webhook, a loopback, private, link-local, multicast or unspecified address is
+// reachable from the server only, so a webhook to it could probe the internal network
+func IsPublicIP(ip net.IP) bool {
+	return ip != nil &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// ValidateWebhookURL accept an https url whose host only resolve to public addresses
+func ValidateWebhookURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(u.Scheme, "https") {
+		return ErrWebhookURLNotHTTPS
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("webhook url has no host: %s", rawURL)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrWebhookAddressNotAllowed
+		}
+		return nil
+	}
+
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+	return nil
+}
+
+// NewWebhookHTTPClient return the client sending the webhooks. The address is checked again when it's dialed,
+// so a host rebound to a private address after the subscription is created is still refused.
+// The redirects are not followed, a redirect is a failed delivery.
+func NewWebhookHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicIP(net.ParseIP(host)) {
+				return ErrWebhookAddressNotAllowed
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// a proxy would be dialed instead of the webhook host
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
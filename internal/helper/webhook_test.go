@@ -0,0 +1,106 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '1700000000.{"id":"evt_1"}' | openssl dgst -sha256 -hmac whsec_test
+	signature := SignWebhookPayload("whsec_test", 1700000000, []byte(`{"id":"evt_1"}`))
+	assert.Equal(t, "t=1700000000,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925", signature)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{ip: "93.184.216.34", public: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", public: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "fd00::1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "0.0.0.0"},
+		{ip: "::"},
+		{ip: "224.0.0.1"},
+		{ip: "::ffff:127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.public, IsPublicIP(net.ParseIP(tt.ip)), tt.ip)
+	}
+	assert.False(t, IsPublicIP(nil))
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	defer func(lookup func(context.Context, string) ([]net.IPAddr, error)) { lookupIPAddr = lookup }(lookupIPAddr)
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "partner.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		case "internal.example.com":
+			// one private address is enough to refuse the host
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("10.0.0.5")}}, nil
+		default:
+			return nil, errors.New("no such host")
+		}
+	}
+
+	tests := []struct {
+		url string
+		err error
+	}{
+		{url: "https://partner.example.com/hooks"},
+		{url: "https://93.184.216.34:8443/hooks"},
+		{url: "http://partner.example.com/hooks", err: ErrWebhookURLNotHTTPS},
+		{url: "ftp://partner.example.com/hooks", err: ErrWebhookURLNotHTTPS},
+		{url: "https://internal.example.com/hooks", err: ErrWebhookAddressNotAllowed},
+		{url: "https://127.0.0.1/hooks", err: ErrWebhookAddressNotAllowed},
+		{url: "https://[::1]/hooks", err: ErrWebhookAddressNotAllowed},
+		{url: "https://169.254.169.254/latest/meta-data", err: ErrWebhookAddressNotAllowed},
+		{url: "https://0.0.0.0/hooks", err: ErrWebhookAddressNotAllowed},
+	}
+
+	for _, tt := range tests {
+		err := ValidateWebhookURL(context.Background(), tt.url)
+		assert.Equal(t, tt.err, err, tt.url)
+	}
+
+	assert.Error(t, ValidateWebhookURL(context.Background(), "https://unknown.example.com/hooks"))
+	assert.Error(t, ValidateWebhookURL(context.Background(), "https:///hooks"))
+}
+
+func TestNewWebhookHTTPClient_RefuseAPrivateAddressAtDialTime(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// the test server listen on the loopback, like a host rebound to it after the subscription is created
+	res, err := NewWebhookHTTPClient(time.Second).Post(server.URL, "application/json", nil)
+	if res != nil {
+		_ = res.Body.Close()
+	}
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrWebhookAddressNotAllowed), err)
+	assert.False(t, called)
+}
+
+func TestNewWebhookHTTPClient_DoesNotFollowRedirects(t *testing.T) {
+	client := NewWebhookHTTPClient(time.Second)
+	assert.Equal(t, http.ErrUseLastResponse, client.CheckRedirect(nil, nil))
+}
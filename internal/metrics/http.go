@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// unmatchedRoute route label of a request without a registered route, so unknown paths don't create new series
+const unmatchedRoute = "unmatched"
+
+// Handler serve the registered metrics
+func Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.Handler())
+}
+
+// HTTPMiddleware observe the latency and the status of every request by its echo route.
+// The error is handled here like the logger middleware, so the status written by the error handler is observed.
+func HTTPMiddleware() echo.MiddlewareFunc {
+	var (
+		once   sync.Once
+		routes map[string]bool
+	)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// the routes are registered before serving, so they are only collected once
+			once.Do(func() {
+				routes = make(map[string]bool)
+				for _, route := range c.Echo().Routes() {
+					routes[route.Method+" "+route.Path] = true
+				}
+			})
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			method := c.Request().Method
+			route := c.Path()
+			if !routes[method+" "+route] {
+				route = unmatchedRoute
+			}
+
+			status := c.Response().Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+
+			return nil
+		}
+	}
+}
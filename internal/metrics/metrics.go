@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"strings"
+	"time"
+)
+
+// transfer statuses of the transfers_total metric
+const (
+	TransferStatusExecuted      = "executed"
+	TransferStatusPendingReview = "pending_review"
+	TransferStatusRejected      = "rejected"
+)
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of the http requests by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Latency of the database queries by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "status"})
+
+	transfersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transfers_total",
+		Help: "Number of the transfers between users by status, a rejected transfer has its reason.",
+	}, []string{"status", "reason"})
+
+	transferVolumeTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transfer_volume_total",
+		Help: "Balance moved by the executed transfers between users.",
+	})
+)
+
+// ObserveDBQuery observe the duration of the query, labeled by its sql operation, e.g. select
+func ObserveDBQuery(sql string, elapsed time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	dbQueryDuration.WithLabelValues(sqlOperation(sql), status).Observe(elapsed.Seconds())
+}
+
+// ObserveTransferExecuted count an executed transfer and its amount
+func ObserveTransferExecuted(amount int64) {
+	transfersTotal.WithLabelValues(TransferStatusExecuted, "").Inc()
+	transferVolumeTotal.Add(float64(amount))
+}
+
+// ObserveTransferPendingReview count a transfer that is held for review
+func ObserveTransferPendingReview() {
+	transfersTotal.WithLabelValues(TransferStatusPendingReview, "").Inc()
+}
+
+// ObserveTransferRejected count a rejected transfer, the reason must be a fixed value, e.g. balance_not_enough
+func ObserveTransferRejected(reason string) {
+	transfersTotal.WithLabelValues(TransferStatusRejected, reason).Inc()
+}
+
+// RegisterDBStats register the connection pool stats of the database
+func RegisterDBStats(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+
+	switch operation := strings.ToLower(fields[0]); operation {
+	case "select", "insert", "update", "delete", "savepoint", "release", "rollback", "begin", "commit":
+		return operation
+	default:
+		return "other"
+	}
+}
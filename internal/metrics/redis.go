@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type redisPoolCollector struct {
+	pool       *redigo.Pool
+	activeDesc *prometheus.Desc
+	idleDesc   *prometheus.Desc
+}
+
+// RegisterRedisPool register the connection stats of the redis pool, the name must be unique e.g. cache or lock
+func RegisterRedisPool(name string, pool *redigo.Pool) {
+	labels := prometheus.Labels{"pool": name}
+	prometheus.MustRegister(&redisPoolCollector{
+		pool: pool,
+		activeDesc: prometheus.NewDesc(
+			"redis_pool_active_connections",
+			"Number of the connections in the redis pool, including the idle connections.",
+			nil, labels,
+		),
+		idleDesc: prometheus.NewDesc(
+			"redis_pool_idle_connections",
+			"Number of the idle connections in the redis pool.",
+			nil, labels,
+		),
+	})
+}
+
+func (r *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.activeDesc
+	ch <- r.idleDesc
+}
+
+func (r *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := r.pool.Stats()
+	ch <- prometheus.MustNewConstMetric(r.activeDesc, prometheus.GaugeValue, float64(stats.ActiveCount))
+	ch <- prometheus.MustNewConstMetric(r.idleDesc, prometheus.GaugeValue, float64(stats.IdleCount))
+}
@@ -1,6 +1,8 @@
 package model
 
-import "context"
+import (
+	"context"
+)
 
 // LoginRequest request
 type LoginRequest struct {
@@ -19,11 +21,27 @@ type RefreshTokenRequest struct {
 
 // AuthUsecase usecases about IAM
 type AuthUsecase interface {
-	LoginByEmailPassword(ctx context.Context, req LoginRequest) (*Session, error)
+	// LoginByEmailPassword return the session, or a challenge when the user enabled 2FA
+	LoginByEmailPassword(ctx context.Context, req LoginRequest) (*Session, *LoginChallenge, error)
+	VerifyLoginChallenge(ctx context.Context, req VerifyLoginChallengeRequest) (*Session, error)
 
 	// AuthenticateToken authenticate the given token
 	AuthenticateToken(ctx context.Context, accessToken string) (*User, error)
 
 	RefreshToken(ctx context.Context, req RefreshTokenRequest) (*Session, error)
 	DeleteSessionByID(ctx context.Context, sessionID int) error
+
+	EnrollTwoFactor(ctx context.Context, userID int) (*TwoFactorEnrollment, error)
+	EnableTwoFactor(ctx context.Context, userID int, code string) (recoveryCodes []string, err error)
+	DisableTwoFactor(ctx context.Context, userID int, code string) error
+
+	// FindLoginLockouts return the lockout of the account and/or the IP, only an admin can see them
+	FindLoginLockouts(ctx context.Context, adminID int, query LoginLockoutQuery) ([]*LoginLockout, error)
+	// UnlockLogin reset the failed logins of the account and/or the IP, only an admin can unlock them
+	UnlockLogin(ctx context.Context, adminID int, query LoginLockoutQuery) error
+
+	// FindSecurityEvents return the latest logins, lockouts, token refreshes and logouts of the user
+	FindSecurityEvents(ctx context.Context, userID int) ([]*AuthEvent, error)
+	// PruneAuthEvents delete one batch of the auth events older than the retention
+	PruneAuthEvents(ctx context.Context) (int64, error)
 }
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// AuthEventType :nodoc:
+type AuthEventType string
+
+// AuthEventType constants
+const (
+	AuthEventLogin          AuthEventType = "LOGIN"
+	AuthEventTwoFactorLogin AuthEventType = "TWO_FACTOR_LOGIN"
+	AuthEventLockout        AuthEventType = "LOCKOUT"
+	AuthEventTokenRefresh   AuthEventType = "TOKEN_REFRESH"
+	AuthEventLogout         AuthEventType = "LOGOUT"
+)
+
+// AuthEventResult :nodoc:
+type AuthEventResult string
+
+// AuthEventResult constants
+const (
+	AuthEventResultSuccess AuthEventResult = "SUCCESS"
+	AuthEventResultFailure AuthEventResult = "FAILURE"
+)
+
+// AuthEvent the security audit log of the authentication.
+// UserID is empty when the event can't be tied to a user, e.g. a login with an unknown email.
+type AuthEvent struct {
+	ID        int             `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	UserID    *int            `json:"-"`
+	EventType AuthEventType   `json:"event_type"`
+	IPAddress string          `json:"ip_address"`
+	UserAgent string          `json:"user_agent"`
+	Result    AuthEventResult `json:"result"`
+	Reason    string          `json:"reason,omitempty"`
+	CreatedAt time.Time       `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+}
+
+// AuthEventRepository :nodoc:
+type AuthEventRepository interface {
+	Create(ctx context.Context, event *AuthEvent) error
+	// FindByUserID return the latest events of the user, newest first
+	FindByUserID(ctx context.Context, userID, limit int) ([]*AuthEvent, error)
+	// DeleteCreatedBefore delete up to limit events created before the given time and return how many were deleted
+	DeleteCreatedBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
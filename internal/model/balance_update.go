@@ -0,0 +1,21 @@
+package model
+
+import "context"
+
+// BalanceUpdate perubahan saldo user yang dikirim ke balance stream,
+// History kosong untuk saldo awal yang dikirim saat stream dibuka
+type BalanceUpdate struct {
+	UserID  int                 `json:"user_id"`
+	Balance *UserBalance        `json:"balance"`
+	History *UserBalanceHistory `json:"history,omitempty"`
+}
+
+// BalanceUpdateBroker menyebarkan perubahan saldo ke subscriber di semua instance
+type BalanceUpdateBroker interface {
+	Publish(ctx context.Context, updates ...*BalanceUpdate) error
+	// Subscribe return the updates of the user until the context is done,
+	// the channel is closed when the subscriber is dropped and should resync its balance
+	Subscribe(ctx context.Context, userID int) (<-chan *BalanceUpdate, error)
+	// Run receive the updates of the other instances until the context is done
+	Run(ctx context.Context)
+}
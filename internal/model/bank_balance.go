@@ -14,11 +14,26 @@ type BankBalance struct {
 	Enable         bool   `json:"enable"`
 }
 
+type CreateBankAccountInput struct {
+	Code      string `json:"code" validate:"required,bank_code"`
+	SessionID int    `json:"session_id" validate:"required"`
+}
+
+// Validate :nodoc:
+func (c *CreateBankAccountInput) Validate() error {
+	return validate.Struct(c)
+}
+
 type AddBankBalanceInput struct {
-	Code      string `json:"code"`
-	Balance   int64  `json:"balance"`
-	SessionID int    `json:"session_id"`
-	Author    string `json:"author"`
+	Code      string `json:"code" validate:"required,bank_code"`
+	Balance   int64  `json:"balance" validate:"required,gt=0"`
+	SessionID int    `json:"session_id" validate:"required"`
+	Author    string `json:"author" validate:"max=100"`
+}
+
+// Validate :nodoc:
+func (c *AddBankBalanceInput) Validate() error {
+	return validate.Struct(c)
 }
 
 // BankBalanceRepository menyediakan akses ke data saldo bank.
@@ -29,7 +44,7 @@ type BankBalanceRepository interface {
 }
 
 type BankBalanceUsecase interface {
-	CreateBankAccount(ctx context.Context, sessionID int, code string) error
+	CreateBankAccount(ctx context.Context, input CreateBankAccountInput) error
 	AddBankBalance(ctx context.Context, input AddBankBalanceInput) error
 	GetBankBalanceByID(ctx context.Context, bankBalanceID int) (*BankBalance, error)
 	TransferUserBalance(ctx context.Context, userIDFrom, userIDTo int, balance float64, code string) error
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+// CreditImport menyimpan data kredit saldo yang diimpor dari file, reference bersifat unik.
+type CreditImport struct {
+	ID                   int       `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	Reference            string    `json:"reference"`
+	UserID               int       `json:"user_id"`
+	Amount               int64     `json:"amount"`
+	UserBalanceHistoryID int       `json:"user_balance_history_id"`
+	CreatedAt            time.Time `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+}
+
+type ImportUserBalanceCreditInput struct {
+	UserID    int    `json:"user_id" validate:"required"`
+	Balance   int64  `json:"balance" validate:"required,gt=0"`
+	Reference string `json:"reference" validate:"required,max=255"`
+	Author    string `json:"author" validate:"max=100"`
+}
+
+// Validate :nodoc:
+func (c *ImportUserBalanceCreditInput) Validate() error {
+	return validate.Struct(c)
+}
+
+// CreditImportRepository menyediakan akses ke data kredit saldo yang diimpor.
+type CreditImportRepository interface {
+	CreateWithTransaction(ctx context.Context, tx *gorm.DB, creditImport *CreditImport) error
+	FindByReferenceWithTransaction(ctx context.Context, tx *gorm.DB, reference string) (*CreditImport, error)
+	FindByReferences(ctx context.Context, references []string) ([]*CreditImport, error)
+}
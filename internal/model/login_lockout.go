@@ -0,0 +1,50 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// LoginLockoutScope what the failed logins are counted by
+type LoginLockoutScope string
+
+// LoginLockoutScope constants
+const (
+	LoginLockoutScopeAccount LoginLockoutScope = "ACCOUNT"
+	LoginLockoutScopeIP      LoginLockoutScope = "IP"
+)
+
+// LoginLockout the failed logins of an account, by its email, or of an IP.
+// The logins are delayed until LockedUntil, the delay grow with every failure.
+type LoginLockout struct {
+	Scope          LoginLockoutScope `json:"scope"`
+	Key            string            `json:"key"`
+	FailedAttempts int64             `json:"failed_attempts"`
+	LockedUntil    *time.Time        `json:"locked_until,omitempty"`
+}
+
+// IsLocked :nodoc:
+func (l *LoginLockout) IsLocked(now time.Time) bool {
+	return l.LockedUntil != nil && l.LockedUntil.After(now)
+}
+
+// LoginLockoutRepository store the lockouts, a lockout is forgotten after a window without failure
+type LoginLockoutRepository interface {
+	// FindByScopeAndKey never return nil, a key without failure has zero FailedAttempts
+	FindByScopeAndKey(ctx context.Context, scope LoginLockoutScope, key string) (*LoginLockout, error)
+	// IncrementFailedAttempts return the failed attempts after the increment, the window restart on every failure
+	IncrementFailedAttempts(ctx context.Context, scope LoginLockoutScope, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, scope LoginLockoutScope, key string, until time.Time) error
+	DeleteByScopeAndKey(ctx context.Context, scope LoginLockoutScope, key string) error
+}
+
+// LoginLockoutQuery the account email and/or the IP of the lockouts, used by the admin to find and unlock them
+type LoginLockoutQuery struct {
+	Email     string `json:"email" query:"email" validate:"required_without=IPAddress,omitempty,email"`
+	IPAddress string `json:"ip_address" query:"ip_address" validate:"required_without=Email,omitempty,ip"`
+}
+
+// Validate :nodoc:
+func (q *LoginLockoutQuery) Validate() error {
+	return validate.Struct(q)
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+// OutboxStatus status of an outbox message
+type OutboxStatus string
+
+// OutboxStatus constants
+const (
+	OutboxStatusPending   OutboxStatus = "PENDING"
+	OutboxStatusPublished OutboxStatus = "PUBLISHED"
+	// OutboxStatusFailed the message failed too many times, it is not published again and doesn't block its account
+	OutboxStatusFailed OutboxStatus = "FAILED"
+)
+
+// OutboxMessage event yang ditulis di transaksi yang sama dengan perubahan saldo, lalu dipublish oleh relay.
+// AggregateID adalah user (akun) pemilik event, urutan publish dijaga per akun.
+// ClaimedUntil is set while a relay publish the message, another relay skip it and the later messages of its account.
+type OutboxMessage struct {
+	ID           int64            `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	AggregateID  int              `json:"aggregate_id"`
+	EventID      string           `json:"event_id"`
+	EventType    WebhookEventType `json:"event_type"`
+	Payload      string           `json:"payload"`
+	Status       OutboxStatus     `json:"status" gorm:"default:PENDING"`
+	Attempts     int              `json:"attempts"`
+	LastError    string           `json:"last_error"`
+	ClaimedUntil *time.Time       `json:"claimed_until"`
+	PublishedAt  *time.Time       `json:"published_at"`
+	CreatedAt    time.Time        `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+}
+
+// TableName :nodoc:
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+// Publisher publish an outbox message, an error make the relay retry the message later
+type Publisher interface {
+	Publish(ctx context.Context, message *OutboxMessage) error
+}
+
+// OutboxRepository menyediakan akses ke tabel outbox.
+type OutboxRepository interface {
+	CreateWithTransaction(ctx context.Context, tx *gorm.DB, messages []*OutboxMessage) error
+	// TryLockRelayWithTransaction take the relay lock until the transaction end, only one relay claim at a time
+	TryLockRelayWithTransaction(ctx context.Context, tx *gorm.DB) (bool, error)
+	// FindPendingWithTransaction find the pending messages ordered by id, the messages claimed by another relay included
+	FindPendingWithTransaction(ctx context.Context, tx *gorm.DB, limit int) ([]*OutboxMessage, error)
+	ClaimWithTransaction(ctx context.Context, tx *gorm.DB, ids []int64, claimedUntil time.Time) error
+	ReleaseClaimWithTransaction(ctx context.Context, tx *gorm.DB, ids []int64) error
+	MarkPublishedWithTransaction(ctx context.Context, tx *gorm.DB, ids []int64, publishedAt time.Time) error
+	// MarkFailedWithTransaction save the status, the attempts and the last error of a failed message and release its claim
+	MarkFailedWithTransaction(ctx context.Context, tx *gorm.DB, message *OutboxMessage) error
+}
+
+// OutboxRelay :nodoc:
+type OutboxRelay interface {
+	// Relay publish a batch of unpublished messages and return how many were published
+	Relay(ctx context.Context) (int, error)
+}
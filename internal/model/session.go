@@ -35,6 +35,7 @@ type SessionRepository interface {
 	Create(ctx context.Context, sess *Session) error
 	FindByToken(ctx context.Context, tokenType TokenType, token string) (*Session, error)
 	FindByID(ctx context.Context, id int) (*Session, error)
+	FindAllByUserID(ctx context.Context, userID int) ([]*Session, error)
 	CheckToken(ctx context.Context, token string) (exist bool, err error)
 	RefreshToken(ctx context.Context, oldSess, sess *Session) (*Session, error)
 	Delete(ctx context.Context, session *Session) error
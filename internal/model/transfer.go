@@ -0,0 +1,88 @@
+package model
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+// TransferStatus status of a transfer between users
+type TransferStatus string
+
+// TransferStatus constants
+const (
+	TransferStatusCompleted     TransferStatus = "COMPLETED"
+	TransferStatusPendingReview TransferStatus = "PENDING_REVIEW"
+	TransferStatusRejected      TransferStatus = "REJECTED"
+)
+
+// Transfer menyimpan data transfer saldo antar user, termasuk transfer yang menunggu review.
+type Transfer struct {
+	ID               int            `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	FromUserID       int            `json:"from_user_id"`
+	ToUserID         int            `json:"to_user_id"`
+	Amount           int64          `json:"amount"`
+	Status           TransferStatus `json:"status"`
+	SessionID        int            `json:"session_id"`
+	Author           string         `json:"author"`
+	ScreeningReasons string         `json:"screening_reasons"`
+	ReviewedBy       *int           `json:"reviewed_by"`
+	ReviewedAt       *time.Time     `json:"reviewed_at"`
+	CreatedAt        time.Time      `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+	UpdatedAt        time.Time      `json:"updated_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP"`
+}
+
+// ScreeningDecision decision of the transfer screening
+type ScreeningDecision string
+
+// ScreeningDecision constants, ordered by its severity
+const (
+	ScreeningDecisionAllow  ScreeningDecision = "ALLOW"
+	ScreeningDecisionReview ScreeningDecision = "REVIEW"
+	ScreeningDecisionDeny   ScreeningDecision = "DENY"
+)
+
+// Severity return the order of the decision, the most severe decision of the rules wins
+func (s ScreeningDecision) Severity() int {
+	switch s {
+	case ScreeningDecisionDeny:
+		return 2
+	case ScreeningDecisionReview:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// TransferScreeningRequest transfer yang akan di-screening sebelum dieksekusi
+type TransferScreeningRequest struct {
+	FromUserID int      `json:"from_user_id"`
+	ToUserID   int      `json:"to_user_id"`
+	Amount     int64    `json:"amount"`
+	Session    *Session `json:"session"`
+	// BatchRecipientIDs the recipients of the other transfers of the same batch, they are not saved yet
+	BatchRecipientIDs []int `json:"batch_recipient_ids"`
+}
+
+// TransferScreeningResult :nodoc:
+type TransferScreeningResult struct {
+	Decision ScreeningDecision `json:"decision"`
+	Reasons  []string          `json:"reasons"`
+}
+
+// TransferScreener screen a transfer before it is executed
+type TransferScreener interface {
+	Screen(ctx context.Context, req TransferScreeningRequest) (*TransferScreeningResult, error)
+}
+
+// TransferRepository menyediakan akses ke data transfer.
+type TransferRepository interface {
+	Create(ctx context.Context, transfer *Transfer) error
+	UpsertWithTransaction(ctx context.Context, tx *gorm.DB, transfer *Transfer) error
+	FindByID(ctx context.Context, id int) (*Transfer, error)
+	FindByIDForUpdateWithTransaction(ctx context.Context, tx *gorm.DB, id int) (*Transfer, error)
+	FindByStatus(ctx context.Context, status TransferStatus) ([]*Transfer, error)
+	CountCompletedByUserIDs(ctx context.Context, fromUserID, toUserID int) (int64, error)
+	// FindRecipientIDsSince find the distinct recipient of the user transfers created since the given time
+	FindRecipientIDsSince(ctx context.Context, fromUserID int, since time.Time) ([]int, error)
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+// UserTransferLimit menyimpan batas transfer khusus untuk seorang user,
+// kolom yang bernilai nil menggunakan batas dari config.
+type UserTransferLimit struct {
+	ID            int       `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	UserID        int       `json:"user_id"`
+	MinAmount     *int64    `json:"min_amount"`
+	MaxAmount     *int64    `json:"max_amount"`
+	DailyAmount   *int64    `json:"daily_amount"`
+	MonthlyAmount *int64    `json:"monthly_amount"`
+	HourlyCount   *int64    `json:"hourly_count"`
+	CreatedAt     time.Time `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+	UpdatedAt     time.Time `json:"updated_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP"`
+}
+
+// TransferLimit batas transfer yang berlaku untuk seorang user, nilai nol berarti tanpa batas.
+type TransferLimit struct {
+	MinAmount     int64 `json:"min_amount"`
+	MaxAmount     int64 `json:"max_amount"`
+	DailyAmount   int64 `json:"daily_amount"`
+	MonthlyAmount int64 `json:"monthly_amount"`
+	HourlyCount   int64 `json:"hourly_count"`
+}
+
+// Override return a copy of the limit with the non nil value of the user limit
+func (t TransferLimit) Override(userLimit *UserTransferLimit) TransferLimit {
+	if userLimit == nil {
+		return t
+	}
+
+	override := func(limit *int64, value *int64) {
+		if value != nil {
+			*limit = *value
+		}
+	}
+	override(&t.MinAmount, userLimit.MinAmount)
+	override(&t.MaxAmount, userLimit.MaxAmount)
+	override(&t.DailyAmount, userLimit.DailyAmount)
+	override(&t.MonthlyAmount, userLimit.MonthlyAmount)
+	override(&t.HourlyCount, userLimit.HourlyCount)
+	return t
+}
+
+// TransferUsage total transfer keluar seorang user pada hari, bulan dan jam berjalan.
+type TransferUsage struct {
+	DailyAmount   int64 `json:"daily_amount"`
+	MonthlyAmount int64 `json:"monthly_amount"`
+	HourlyCount   int64 `json:"hourly_count"`
+}
+
+// TransferLimitRepository menyediakan akses ke data batas dan penggunaan transfer user.
+type TransferLimitRepository interface {
+	FindByUserID(ctx context.Context, userID int) (*UserTransferLimit, error)
+	// GetOutgoingUsageWithTransaction sum the outgoing transfers of the day, month and hour from the balance histories.
+	// The balance of the user must be locked by the transaction, so a concurrent transfer can't change the usage until it ends.
+	GetOutgoingUsageWithTransaction(ctx context.Context, tx *gorm.DB, userID int, now time.Time) (*TransferUsage, error)
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// UserTwoFactor menyimpan secret TOTP milik user, 2FA aktif setelah EnabledAt terisi.
+type UserTwoFactor struct {
+	ID           int        `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"`
+	LastUsedStep int64      `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at"`
+	CreatedAt    time.Time  `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+	UpdatedAt    time.Time  `json:"updated_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP"`
+}
+
+// IsEnabled :nodoc:
+func (u *UserTwoFactor) IsEnabled() bool {
+	return u != nil && u.EnabledAt != nil
+}
+
+// UserRecoveryCode kode pemulihan sekali pakai, hanya hash-nya yang disimpan.
+type UserRecoveryCode struct {
+	ID        int        `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	UserID    int        `json:"user_id"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+}
+
+// LoginChallenge langkah kedua login untuk user yang mengaktifkan 2FA, disimpan di cache.
+type LoginChallenge struct {
+	Token     string    `json:"token"`
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// TwoFactorEnrollment hasil enrollment, ProvisioningURI dirender sebagai QR code oleh client.
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// VerifyLoginChallengeRequest request, Code berisi kode TOTP atau kode pemulihan
+type VerifyLoginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"-"`
+	IPAddress      string `json:"ip_address"`
+	UserAgent      string `json:"user_agent"`
+}
+
+// TwoFactorRepository menyediakan akses ke data 2FA dan challenge login.
+type TwoFactorRepository interface {
+	FindByUserID(ctx context.Context, userID int) (*UserTwoFactor, error)
+	Upsert(ctx context.Context, twoFactor *UserTwoFactor) error
+	Enable(ctx context.Context, twoFactor *UserTwoFactor, codeHashes []string) error
+	DeleteByUserID(ctx context.Context, userID int) error
+	UseTimeStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string, usedAt time.Time) (bool, error)
+
+	CreateLoginChallenge(ctx context.Context, challenge *LoginChallenge) error
+	FindLoginChallengeByToken(ctx context.Context, token string) (*LoginChallenge, error)
+	DeleteLoginChallengeByToken(ctx context.Context, token string) error
+}
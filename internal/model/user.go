@@ -4,29 +4,50 @@ import (
 	"context"
 )
 
+// Role role of the user
+type Role string
+
+// Role constants
+const (
+	RoleUser  Role = "USER"
+	RoleAdmin Role = "ADMIN"
+)
+
 // User :nodoc:
 type User struct {
 	ID       int    `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password" gorm:"->:false;<-"` // gorm create & update only (disabled read from db)
+	Role     Role   `json:"role" gorm:"default:USER"`
 
 	SessionID int `json:"session_id" gorm:"-"`
 }
 
+// IsAdmin :nodoc:
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
 	FindByID(ctx context.Context, id int) (*User, error)
 	FindByUsername(ctx context.Context, username string) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
-	IsLoginByEmailPasswordLocked(ctx context.Context, email string) (bool, error)
-	IncrementLoginByEmailPasswordRetryAttempts(ctx context.Context, username string) error
 	FindPasswordByID(ctx context.Context, id int) ([]byte, error)
+	FindTransactionPinByID(ctx context.Context, id int) ([]byte, error)
+	UpdateTransactionPinByID(ctx context.Context, id int, cipherPin string) error
+	IsTransactionPinLocked(ctx context.Context, id int) (bool, error)
+	IncrementTransactionPinRetryAttempts(ctx context.Context, id int) error
+	ResetTransactionPinRetryAttempts(ctx context.Context, id int) error
 }
 
 type UserUsecase interface {
 	Create(ctx context.Context, input CreateUserInput) (*User, error)
 	FindByID(ctx context.Context, userID int) (*User, error)
+	SetTransactionPin(ctx context.Context, input SetTransactionPinInput) error
+	ChangeTransactionPin(ctx context.Context, input ChangeTransactionPinInput) error
+	ResetTransactionPin(ctx context.Context, input ResetTransactionPinInput) error
 }
 
 // CreateUserInput :nodoc:
@@ -45,3 +66,38 @@ func (c *CreateUserInput) Validate() error {
 
 	return nil
 }
+
+// SetTransactionPinInput :nodoc:
+type SetTransactionPinInput struct {
+	UserID int    `json:"user_id" validate:"required"`
+	Pin    string `json:"-" field:"pin" validate:"required,len=6,numeric"`
+}
+
+// Validate :nodoc:
+func (c *SetTransactionPinInput) Validate() error {
+	return validate.Struct(c)
+}
+
+// ChangeTransactionPinInput :nodoc:
+type ChangeTransactionPinInput struct {
+	UserID int    `json:"user_id" validate:"required"`
+	OldPin string `json:"-" field:"old_pin" validate:"required"`
+	NewPin string `json:"-" field:"new_pin" validate:"required,len=6,numeric"`
+}
+
+// Validate :nodoc:
+func (c *ChangeTransactionPinInput) Validate() error {
+	return validate.Struct(c)
+}
+
+// ResetTransactionPinInput reset a forgotten pin by confirming the account password
+type ResetTransactionPinInput struct {
+	UserID        int    `json:"user_id" validate:"required"`
+	PlainPassword string `json:"-" field:"password" validate:"required"`
+	NewPin        string `json:"-" field:"new_pin" validate:"required,len=6,numeric"`
+}
+
+// Validate :nodoc:
+func (c *ResetTransactionPinInput) Validate() error {
+	return validate.Struct(c)
+}
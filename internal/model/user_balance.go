@@ -16,18 +16,82 @@ type UserBalance struct {
 }
 
 type AddUserBalanceInput struct {
-	UserID    int    `json:"user_id"`
-	Balance   int64  `json:"balance"`
-	SessionID int    `json:"session_id"`
-	Author    string `json:"author"`
+	UserID    int    `json:"user_id" validate:"required"`
+	Balance   int64  `json:"balance" validate:"required,gt=0"`
+	SessionID int    `json:"session_id" validate:"required"`
+	Author    string `json:"author" validate:"max=100"`
+}
+
+// Validate :nodoc:
+func (c *AddUserBalanceInput) Validate() error {
+	return validate.Struct(c)
 }
 
 type TransferUserBalanceInput struct {
-	FromUserID int    `json:"from_user_id"`
-	ToUserID   int    `json:"to_user_id"`
-	Balance    int64  `json:"balance"`
-	SessionID  int    `json:"session_id"`
-	Author     string `json:"author"`
+	FromUserID int    `json:"from_user_id" validate:"required"`
+	ToUserID   int    `json:"to_user_id" validate:"required,nefield=FromUserID"`
+	Balance    int64  `json:"balance" validate:"required,gt=0"`
+	SessionID  int    `json:"session_id" validate:"required"`
+	Author     string `json:"author" validate:"max=100"`
+	Pin        string `json:"-"`
+}
+
+// Validate :nodoc:
+func (c *TransferUserBalanceInput) Validate() error {
+	return validate.Struct(c)
+}
+
+// TransferBatchMode decide how a batch transfer react when one of its item failed
+type TransferBatchMode string
+
+// TransferBatchMode constants
+const (
+	// TransferBatchModeAtomic all items are applied or none of them
+	TransferBatchModeAtomic TransferBatchMode = "ATOMIC"
+	// TransferBatchModeBestEffort apply every valid item and report the failed ones
+	TransferBatchModeBestEffort TransferBatchMode = "BEST_EFFORT"
+)
+
+// TransferBatchItemStatus status of a single item in a batch transfer
+type TransferBatchItemStatus string
+
+// TransferBatchItemStatus constants
+const (
+	TransferBatchItemStatusSuccess TransferBatchItemStatus = "SUCCESS"
+	TransferBatchItemStatusFailed  TransferBatchItemStatus = "FAILED"
+	// TransferBatchItemStatusPendingReview item is held for review by the screening, it's executed once approved
+	TransferBatchItemStatusPendingReview TransferBatchItemStatus = "PENDING_REVIEW"
+	// TransferBatchItemStatusSkipped item is valid but not applied because the atomic batch is aborted
+	TransferBatchItemStatusSkipped TransferBatchItemStatus = "SKIPPED"
+)
+
+type TransferBatchItem struct {
+	ToUserID int   `json:"to_user_id"`
+	Balance  int64 `json:"balance"`
+}
+
+// TransferUserBalanceBatchInput the items are not validated here, an invalid item is reported in its result
+type TransferUserBalanceBatchInput struct {
+	FromUserID int                 `json:"from_user_id" validate:"required"`
+	Items      []TransferBatchItem `json:"items" validate:"required,min=1"`
+	Mode       TransferBatchMode   `json:"mode" validate:"oneof=ATOMIC BEST_EFFORT"`
+	SessionID  int                 `json:"session_id" validate:"required"`
+	Author     string              `json:"author" validate:"max=100"`
+	Pin        string              `json:"-"`
+}
+
+// Validate :nodoc:
+func (c *TransferUserBalanceBatchInput) Validate() error {
+	return validate.Struct(c)
+}
+
+// TransferBatchItemResult result of a single item in a batch transfer
+type TransferBatchItemResult struct {
+	ToUserID   int                     `json:"to_user_id"`
+	Balance    int64                   `json:"balance"`
+	Status     TransferBatchItemStatus `json:"status"`
+	Reason     string                  `json:"reason,omitempty"`
+	TransferID int                     `json:"transfer_id,omitempty"`
 }
 
 // UserBalanceRepository menyediakan akses ke data saldo user.
@@ -35,11 +99,19 @@ type UserBalanceRepository interface {
 	CreateWithTransaction(ctx context.Context, tx *gorm.DB, userBalance *UserBalance) error
 	UpsertWithTransaction(ctx context.Context, tx *gorm.DB, userBalance *UserBalance) error
 	GetCurrentUserBalanceByUserID(ctx context.Context, userID int) (*UserBalance, error)
+	// FindByUserIDsForUpdateWithTransaction lock the balances of the given users ordered by user id
+	FindByUserIDsForUpdateWithTransaction(ctx context.Context, tx *gorm.DB, userIDs []int) ([]*UserBalance, error)
 }
 
 // UserBalanceUsecase menyediakan fungsi-fungsi yang berkaitan dengan model UserBalance.
 type UserBalanceUsecase interface {
 	AddUserBalance(ctx context.Context, input AddUserBalanceInput) error
-	TransferUserBalance(ctx context.Context, input TransferUserBalanceInput) error
+	TransferUserBalance(ctx context.Context, input TransferUserBalanceInput) (*Transfer, error)
+	TransferUserBalanceBatch(ctx context.Context, input TransferUserBalanceBatchInput) ([]*TransferBatchItemResult, error)
+	ImportUserBalanceCredit(ctx context.Context, input ImportUserBalanceCreditInput) error
+	GetUserBalanceStatement(ctx context.Context, userID int, from, to time.Time) (*UserBalanceStatement, error)
 	GetCurrentUserBalanceByUserID(ctx context.Context, userID int) (*UserBalance, error)
+	FindPendingTransfers(ctx context.Context, reviewerID int) ([]*Transfer, error)
+	ReviewPendingTransfer(ctx context.Context, reviewerID, transferID int, approve bool) (*Transfer, error)
+	SubscribeBalanceUpdates(ctx context.Context, userID int) (<-chan *BalanceUpdate, error)
 }
@@ -28,7 +28,30 @@ type UserBalanceHistory struct {
 	CreatedAt     time.Time       `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
 }
 
+// Amount return the balance moved by the history
+func (u *UserBalanceHistory) Amount() int64 {
+	if u.BalanceAfter > u.BalanceBefore {
+		return u.BalanceAfter - u.BalanceBefore
+	}
+	return u.BalanceBefore - u.BalanceAfter
+}
+
+// UserBalanceStatement rekening koran saldo user pada suatu periode, From inklusif dan To eksklusif.
+type UserBalanceStatement struct {
+	UserID         int                       `json:"user_id"`
+	From           time.Time                 `json:"from"`
+	To             time.Time                 `json:"to"`
+	OpeningBalance int64                     `json:"opening_balance"`
+	ClosingBalance int64                     `json:"closing_balance"`
+	Totals         map[TransactionType]int64 `json:"totals"`
+	Histories      []*UserBalanceHistory     `json:"histories"`
+}
+
 // UserBalanceHistoryRepository menyediakan akses ke data riwayat saldo user.
 type UserBalanceHistoryRepository interface {
 	CreateWithTransaction(ctx context.Context, tx *gorm.DB, input *UserBalanceHistory) error
+	// FindByUserBalanceIDAndPeriod find the histories created in [from, to) ordered by the oldest
+	FindByUserBalanceIDAndPeriod(ctx context.Context, userBalanceID int, from, to time.Time) ([]*UserBalanceHistory, error)
+	// FindLastByUserBalanceIDBefore find the latest history created before the given time
+	FindLastByUserBalanceIDBefore(ctx context.Context, userBalanceID int, before time.Time) (*UserBalanceHistory, error)
 }
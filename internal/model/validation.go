@@ -2,9 +2,15 @@ package model
 
 import (
 	"github.com/go-playground/validator/v10"
+	"reflect"
+	"regexp"
+	"strings"
 	"sync"
 )
 
+// bankCodeRegex kode bank terdiri dari 4 sampai 20 huruf kapital atau angka, contoh ABCDE12345
+var bankCodeRegex = regexp.MustCompile(`^[A-Z0-9]{4,20}$`)
+
 // validate singleton, it's thread safe and cached the struct validation rules
 var validate *validator.Validate
 
@@ -13,5 +19,17 @@ var initOnce sync.Once
 func init() {
 	initOnce.Do(func() {
 		validate = validator.New()
+		// report the json name of a field in the validation errors, it's the name the client sent,
+		// a secret hidden from json names its request field in the field tag
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return field.Tag.Get("field")
+			}
+			return name
+		})
+		_ = validate.RegisterValidation("bank_code", func(fl validator.FieldLevel) bool {
+			return bankCodeRegex.MatchString(fl.Field().String())
+		})
 	})
 }
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WebhookEventType tipe event yang bisa di-subscribe
+type WebhookEventType string
+
+// WebhookEventType constants
+const (
+	WebhookEventBalanceCredited       WebhookEventType = "balance.credited"
+	WebhookEventBalanceDebited        WebhookEventType = "balance.debited"
+	WebhookEventTransferCompleted     WebhookEventType = "transfer.completed"
+	WebhookEventTransferPendingReview WebhookEventType = "transfer.pending_review"
+	WebhookEventTransferRejected      WebhookEventType = "transfer.rejected"
+)
+
+// WebhookEventTypes list of the supported event types
+var WebhookEventTypes = []WebhookEventType{
+	WebhookEventBalanceCredited,
+	WebhookEventBalanceDebited,
+	WebhookEventTransferCompleted,
+	WebhookEventTransferPendingReview,
+	WebhookEventTransferRejected,
+}
+
+// IsValid :nodoc:
+func (t WebhookEventType) IsValid() bool {
+	for _, eventType := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEventTypeList disimpan sebagai text yang dipisahkan koma
+type WebhookEventTypeList []WebhookEventType
+
+// Value :nodoc:
+func (l WebhookEventTypeList) Value() (driver.Value, error) {
+	eventTypes := make([]string, len(l))
+	for i, eventType := range l {
+		eventTypes[i] = string(eventType)
+	}
+	return strings.Join(eventTypes, ","), nil
+}
+
+// Scan :nodoc:
+func (l *WebhookEventTypeList) Scan(value any) error {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported webhook event types value %T", value)
+	}
+
+	*l = nil
+	for _, eventType := range strings.Split(s, ",") {
+		if eventType != "" {
+			*l = append(*l, WebhookEventType(eventType))
+		}
+	}
+	return nil
+}
+
+// WebhookEvent event yang dikirim ke subscriber, UserID menentukan subscription user yang menerimanya
+type WebhookEvent struct {
+	ID        string           `json:"id"`
+	Type      WebhookEventType `json:"type"`
+	UserID    int              `json:"user_id"`
+	Data      any              `json:"data"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// BalanceEventData data of the balance.* events
+type BalanceEventData struct {
+	UserID        int    `json:"user_id"`
+	Amount        int64  `json:"amount"`
+	BalanceBefore int64  `json:"balance_before"`
+	BalanceAfter  int64  `json:"balance_after"`
+	Activity      string `json:"activity"`
+}
+
+// TransferEventData data of the transfer.* events
+type TransferEventData struct {
+	TransferID int    `json:"transfer_id,omitempty"`
+	FromUserID int    `json:"from_user_id"`
+	ToUserID   int    `json:"to_user_id"`
+	Amount     int64  `json:"amount"`
+	Status     string `json:"status"`
+}
+
+// WebhookSubscription subscription milik user, UserID kosong berarti subscription aplikasi yang menerima event semua user.
+type WebhookSubscription struct {
+	ID         int                  `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	UserID     *int                 `json:"user_id"`
+	URL        string               `json:"url"`
+	Secret     string               `json:"-"`
+	EventTypes WebhookEventTypeList `json:"event_types" gorm:"type:text"`
+	Active     bool                 `json:"active"`
+	CreatedBy  int                  `json:"created_by"`
+	CreatedAt  time.Time            `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+	UpdatedAt  time.Time            `json:"updated_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP"`
+}
+
+// IsSubscribed :nodoc:
+func (s *WebhookSubscription) IsSubscribed(eventType WebhookEventType) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus status pengiriman webhook
+type WebhookDeliveryStatus string
+
+// WebhookDeliveryStatus constants
+const (
+	WebhookDeliveryStatusPending WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusSuccess WebhookDeliveryStatus = "SUCCESS"
+	WebhookDeliveryStatusFailed  WebhookDeliveryStatus = "FAILED"
+)
+
+// WebhookDelivery log pengiriman satu event ke satu subscription
+type WebhookDelivery struct {
+	ID             int                   `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	SubscriptionID int                   `json:"subscription_id"`
+	EventID        string                `json:"event_id"`
+	EventType      WebhookEventType      `json:"event_type"`
+	Payload        string                `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus int                   `json:"response_status"`
+	LastError      string                `json:"last_error"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at"`
+	CreatedAt      time.Time             `json:"created_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP" gorm:"->;<-:create"`
+	UpdatedAt      time.Time             `json:"updated_at" sql:"DEFAULT:'now()':::STRING::TIMESTAMP"`
+}
+
+// CreateWebhookSubscriptionInput input, IsApp hanya boleh digunakan oleh admin
+type CreateWebhookSubscriptionInput struct {
+	UserID     int                `json:"user_id" validate:"required"`
+	URL        string             `json:"url" validate:"required,url"`
+	EventTypes []WebhookEventType `json:"event_types" validate:"required,min=1"`
+	IsApp      bool               `json:"is_app"`
+}
+
+// Validate :nodoc:
+func (c *CreateWebhookSubscriptionInput) Validate() error {
+	return validate.Struct(c)
+}
+
+// WebhookPublisher publish the events to the subscribers
+type WebhookPublisher interface {
+	Publish(ctx context.Context, events ...*WebhookEvent) error
+}
+
+// WebhookRepository menyediakan akses ke data subscription dan delivery webhook.
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	FindSubscriptionByID(ctx context.Context, id int) (*WebhookSubscription, error)
+	FindSubscriptionsByUserID(ctx context.Context, userID int) ([]*WebhookSubscription, error)
+	FindAppSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+	FindActiveSubscriptionsForUser(ctx context.Context, userID int) ([]*WebhookSubscription, error)
+	DeactivateSubscription(ctx context.Context, id int) error
+
+	CreateDeliveries(ctx context.Context, deliveries []*WebhookDelivery) error
+	FindDeliveryByID(ctx context.Context, id int) (*WebhookDelivery, error)
+	FindDeliveriesBySubscriptionID(ctx context.Context, subscriptionID, limit int) ([]*WebhookDelivery, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+}
+
+// WebhookUsecase :nodoc:
+type WebhookUsecase interface {
+	WebhookPublisher
+
+	CreateSubscription(ctx context.Context, input CreateWebhookSubscriptionInput) (*WebhookSubscription, error)
+	FindSubscriptions(ctx context.Context, userID int) ([]*WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, userID, subscriptionID int) error
+	FindDeliveries(ctx context.Context, userID, subscriptionID int) ([]*WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, userID, deliveryID int) (*WebhookDelivery, error)
+
+	// DeliverDueDeliveries send the deliveries which are due and return how many were attempted
+	DeliverDueDeliveries(ctx context.Context) (int, error)
+}
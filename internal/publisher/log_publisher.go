@@ -0,0 +1,24 @@
+package publisher
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+type logPublisher struct{}
+
+// NewLogPublisher publisher that only write the message to the log, useful in development
+func NewLogPublisher() model.Publisher {
+	return &logPublisher{}
+}
+
+func (l *logPublisher) Publish(_ context.Context, message *model.OutboxMessage) error {
+	logrus.WithFields(logrus.Fields{
+		"id":          message.ID,
+		"aggregateID": message.AggregateID,
+		"eventID":     message.EventID,
+		"eventType":   message.EventType,
+	}).Info(message.Payload)
+	return nil
+}
@@ -0,0 +1,27 @@
+package publisher
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+)
+
+type multiPublisher struct {
+	publishers []model.Publisher
+}
+
+// NewMultiPublisher publish to every publisher in order. A failure stop the message,
+// so it is published again to all of them and every publisher must tolerate a duplicate.
+func NewMultiPublisher(publishers ...model.Publisher) model.Publisher {
+	return &multiPublisher{
+		publishers: publishers,
+	}
+}
+
+func (m *multiPublisher) Publish(ctx context.Context, message *model.OutboxMessage) error {
+	for _, publisher := range m.publishers {
+		if err := publisher.Publish(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package publisher
+
+import (
+	"context"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+)
+
+type redisStreamPublisher struct {
+	pool   *redigo.Pool
+	stream string
+	maxLen int64
+}
+
+// NewRedisStreamPublisher publisher that append the message to a redis stream, the stream is trimmed to about maxLen entries.
+// A single stream keep the order of the relay, so the messages of an account are read in order.
+func NewRedisStreamPublisher(pool *redigo.Pool, stream string, maxLen int64) model.Publisher {
+	return &redisStreamPublisher{
+		pool:   pool,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+func (r *redisStreamPublisher) Publish(ctx context.Context, message *model.OutboxMessage) error {
+	client, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	_, err = client.Do("XADD", r.stream, "MAXLEN", "~", r.maxLen, "*",
+		"event_id", message.EventID,
+		"event_type", string(message.EventType),
+		"aggregate_id", message.AggregateID,
+		"payload", message.Payload,
+	)
+	return err
+}
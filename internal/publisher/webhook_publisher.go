@@ -0,0 +1,27 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+)
+
+type webhookOutboxPublisher struct {
+	webhookUsecase model.WebhookPublisher
+}
+
+// NewWebhookPublisher publisher that create the webhook deliveries of the message
+func NewWebhookPublisher(webhookPublisher model.WebhookPublisher) model.Publisher {
+	return &webhookOutboxPublisher{
+		webhookUsecase: webhookPublisher,
+	}
+}
+
+func (w *webhookOutboxPublisher) Publish(ctx context.Context, message *model.OutboxMessage) error {
+	event := &model.WebhookEvent{}
+	if err := json.Unmarshal([]byte(message.Payload), event); err != nil {
+		return err
+	}
+
+	return w.webhookUsecase.Publish(ctx, event)
+}
@@ -0,0 +1,154 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/jpillora/backoff"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	balanceChannelPrefix  = "balance:user:"
+	balanceChannelPattern = balanceChannelPrefix + "*"
+)
+
+type redisBalanceBroker struct {
+	*localSubscribers
+	pool *redigo.Pool
+}
+
+// NewRedisBalanceBroker broker that publish the updates to the redis channel of the user.
+// Every instance hold a single pattern subscription and fan out the updates to its local subscribers.
+func NewRedisBalanceBroker(pool *redigo.Pool) model.BalanceUpdateBroker {
+	return &redisBalanceBroker{
+		localSubscribers: newLocalSubscribers(config.BalanceStreamBufferSize()),
+		pool:             pool,
+	}
+}
+
+func (r *redisBalanceBroker) Publish(ctx context.Context, updates ...*model.BalanceUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	client, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	for _, update := range updates {
+		payload, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		if err = client.Send("PUBLISH", balanceChannel(update.UserID), payload); err != nil {
+			return err
+		}
+	}
+	if err = client.Flush(); err != nil {
+		return err
+	}
+	for range updates {
+		if _, err = client.Receive(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run keep the pattern subscription until the context is done and reconnect with a backoff when it is lost.
+// The updates published while reconnecting are lost, so every local subscriber is dropped to resync its balance.
+// The subscribers are also dropped when it stops, so the open streams are closed on shutdown.
+func (r *redisBalanceBroker) Run(ctx context.Context) {
+	defer r.dropSubscribers()
+
+	b := &backoff.Backoff{
+		Min:    100 * time.Millisecond,
+		Max:    10 * time.Second,
+		Factor: 2,
+		Jitter: true,
+	}
+
+	for {
+		err := r.listen(ctx, b)
+		if ctx.Err() != nil {
+			return
+		}
+
+		logrus.Error(err)
+		r.dropSubscribers()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.Duration()):
+		}
+	}
+}
+
+func (r *redisBalanceBroker) listen(ctx context.Context, b *backoff.Backoff) error {
+	client, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	psc := redigo.PubSubConn{Conn: client}
+	defer func() {
+		_ = psc.Close()
+	}()
+
+	if err = psc.PSubscribe(balanceChannelPattern); err != nil {
+		return err
+	}
+
+	heartbeat := config.BalanceStreamHeartbeat()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// unblock the receive below
+				_ = psc.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := psc.Ping(""); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		switch msg := psc.ReceiveWithTimeout(2 * heartbeat).(type) {
+		case error:
+			return msg
+		case redigo.Subscription:
+			b.Reset()
+		case redigo.Message:
+			update := &model.BalanceUpdate{}
+			if err := json.Unmarshal(msg.Data, update); err != nil {
+				logrus.WithField("channel", msg.Channel).Error(err)
+				continue
+			}
+			r.dispatch(update)
+		}
+	}
+}
+
+func balanceChannel(userID int) string {
+	return fmt.Sprintf("%s%d", balanceChannelPrefix, userID)
+}
@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+// localSubscribers the subscribers of this instance, the brokers fan out the updates to them
+type localSubscribers struct {
+	bufferSize  int
+	mutex       sync.Mutex
+	subscribers map[int]map[chan *model.BalanceUpdate]struct{}
+}
+
+func newLocalSubscribers(bufferSize int) *localSubscribers {
+	return &localSubscribers{
+		bufferSize:  bufferSize,
+		subscribers: make(map[int]map[chan *model.BalanceUpdate]struct{}),
+	}
+}
+
+// Subscribe :nodoc:
+func (l *localSubscribers) Subscribe(ctx context.Context, userID int) (<-chan *model.BalanceUpdate, error) {
+	ch := make(chan *model.BalanceUpdate, l.bufferSize)
+
+	l.mutex.Lock()
+	if l.subscribers[userID] == nil {
+		l.subscribers[userID] = make(map[chan *model.BalanceUpdate]struct{})
+	}
+	l.subscribers[userID][ch] = struct{}{}
+	l.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		l.removeSubscriber(userID, ch)
+	}()
+
+	return ch, nil
+}
+
+// dispatch send the update to the local subscribers of the user without blocking,
+// a subscriber whose buffer is full is dropped
+func (l *localSubscribers) dispatch(update *model.BalanceUpdate) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for ch := range l.subscribers[update.UserID] {
+		select {
+		case ch <- update:
+		default:
+			logrus.WithField("userID", update.UserID).Warn("balance stream subscriber is too slow, dropped")
+			l.removeSubscriber(update.UserID, ch)
+		}
+	}
+}
+
+func (l *localSubscribers) dropSubscribers() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for userID, subscribers := range l.subscribers {
+		for ch := range subscribers {
+			l.removeSubscriber(userID, ch)
+		}
+	}
+}
+
+// removeSubscriber must be called with the mutex held, removing a removed subscriber is a no-op
+func (l *localSubscribers) removeSubscriber(userID int, ch chan *model.BalanceUpdate) {
+	subscribers := l.subscribers[userID]
+	if _, ok := subscribers[ch]; !ok {
+		return
+	}
+
+	delete(subscribers, ch)
+	close(ch)
+	if len(subscribers) == 0 {
+		delete(l.subscribers, userID)
+	}
+}
@@ -0,0 +1,33 @@
+package pubsub
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+)
+
+type memoryBalanceBroker struct {
+	*localSubscribers
+}
+
+// NewMemoryBalanceBroker broker that only deliver the updates to the subscribers of this process,
+// for the single node mode without redis
+func NewMemoryBalanceBroker() model.BalanceUpdateBroker {
+	return &memoryBalanceBroker{
+		localSubscribers: newLocalSubscribers(config.BalanceStreamBufferSize()),
+	}
+}
+
+func (m *memoryBalanceBroker) Publish(_ context.Context, updates ...*model.BalanceUpdate) error {
+	for _, update := range updates {
+		m.dispatch(update)
+	}
+	return nil
+}
+
+// Run there is no other instance to receive from, it only drop the subscribers when it stops
+// so the open streams are closed on shutdown
+func (m *memoryBalanceBroker) Run(ctx context.Context) {
+	<-ctx.Done()
+	m.dropSubscribers()
+}
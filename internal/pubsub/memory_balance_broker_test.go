@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMemoryBalanceBroker(t *testing.T) {
+	broker := NewMemoryBalanceBroker()
+	runCtx, stop := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		broker.Run(runCtx)
+		close(stopped)
+	}()
+
+	subscribeCtx, unsubscribe := context.WithCancel(context.Background())
+	defer unsubscribe()
+	updates, err := broker.Subscribe(subscribeCtx, 1)
+	require.NoError(t, err)
+	others, err := broker.Subscribe(context.Background(), 2)
+	require.NoError(t, err)
+
+	require.NoError(t, broker.Publish(context.Background(), &model.BalanceUpdate{UserID: 1}))
+
+	select {
+	case update := <-updates:
+		assert.Equal(t, 1, update.UserID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the update wasn't delivered")
+	}
+	assert.Empty(t, others)
+
+	// the open streams are closed when the broker stops
+	stop()
+	<-stopped
+	_, open := <-updates
+	assert.False(t, open)
+	_, open = <-others
+	assert.False(t, open)
+}
+
+func TestMemoryBalanceBroker_SlowSubscriber(t *testing.T) {
+	broker := NewMemoryBalanceBroker().(*memoryBalanceBroker)
+	broker.bufferSize = 1
+
+	updates, err := broker.Subscribe(context.Background(), 1)
+	require.NoError(t, err)
+
+	// the buffer is full on the second update, the subscriber is dropped instead of blocking the publisher
+	require.NoError(t, broker.Publish(context.Background(), &model.BalanceUpdate{UserID: 1}, &model.BalanceUpdate{UserID: 1}))
+
+	_, open := <-updates
+	assert.True(t, open)
+	_, open = <-updates
+	assert.False(t, open)
+}
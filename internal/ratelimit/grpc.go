@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/clientip"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strconv"
+)
+
+// GRPCMethod the method of a grpc route, e.g. SetRouteRule(GRPCMethod, "/userbalance.UserBalanceService/Login", rule)
+const GRPCMethod = "GRPC"
+
+// UnaryServerInterceptor like Limit, limit the calls of every method by its full method name with the same buckets store.
+// It returns ResourceExhausted with the `retry-after` trailer in seconds when a bucket is empty.
+// Put it after the auth interceptor to limit by user.
+func (r *RateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		res := r.allow(ctx, routeKey(GRPCMethod, info.FullMethod), clientip.FromIncomingContext(ctx))
+		if !res.Allowed {
+			if err := grpc.SetTrailer(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfterSeconds(res.RetryAfter)))); err != nil {
+				logrus.WithContext(ctx).Error(err)
+			}
+			return nil, status.Error(codes.ResourceExhausted, "too many requests")
+		}
+
+		return handler(ctx, req)
+	}
+}
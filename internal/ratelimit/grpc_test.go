@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/clientip"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_UnaryServerInterceptor(t *testing.T) {
+	rateLimiter := NewRateLimiter(NewMemoryStore(), true, Rule{Limit: 5, Period: time.Minute})
+	rateLimiter.SetRouteRule(GRPCMethod, "/userbalance.UserBalanceService/Login", Rule{Limit: 1, Period: time.Minute})
+	interceptor := rateLimiter.UnaryServerInterceptor()
+
+	call := func(ctx context.Context, method string) error {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+			return "ok", nil
+		})
+		return err
+	}
+
+	ctx := clientip.NewContext(context.Background(), "203.0.113.7")
+	assert.NoError(t, call(ctx, "/userbalance.UserBalanceService/Login"))
+	err := call(ctx, "/userbalance.UserBalanceService/Login")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// every method has its own bucket, with the default rule unless it's overridden
+	assert.NoError(t, call(ctx, "/userbalance.UserBalanceService/RefreshToken"))
+
+	// an authenticated call is limited by user across the IPs
+	for i := 0; i < 5; i++ {
+		userCtx := auth.SetUserToCtx(clientip.NewContext(context.Background(), fmt.Sprintf("198.51.100.%d", i+1)), auth.User{ID: 1})
+		assert.NoError(t, call(userCtx, "/userbalance.UserBalanceService/TransferBalance"))
+	}
+	userCtx := auth.SetUserToCtx(clientip.NewContext(context.Background(), "192.0.2.1"), auth.User{ID: 1})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call(userCtx, "/userbalance.UserBalanceService/TransferBalance")))
+
+	// a disabled limiter allows every call
+	disabled := NewRateLimiter(NewMemoryStore(), false, Rule{Limit: 1, Period: time.Minute}).UnaryServerInterceptor()
+	for i := 0; i < 2; i++ {
+		_, err := disabled(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/userbalance.UserBalanceService/Login"}, func(ctx context.Context, req any) (any, error) {
+			return "ok", nil
+		})
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/clientip"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimiter limit the requests of every route by IP, and by user as well when the request is authenticated.
+// The buckets are kept in the store, use the redis one so the limits apply across the instances.
+type RateLimiter struct {
+	store       Store
+	enabled     bool
+	defaultRule Rule
+	routeRules  map[string]Rule
+}
+
+// NewRateLimiter :nodoc:
+func NewRateLimiter(store Store, enabled bool, defaultRule Rule) *RateLimiter {
+	return &RateLimiter{
+		store:       store,
+		enabled:     enabled,
+		defaultRule: defaultRule,
+		routeRules:  make(map[string]Rule),
+	}
+}
+
+// SetRouteRule override the default rule of a route, e.g. SetRouteRule(http.MethodPost, "/auth/login/", rule)
+func (r *RateLimiter) SetRouteRule(method, path string, rule Rule) {
+	r.routeRules[routeKey(strings.ToUpper(method), path)] = rule
+}
+
+// Limit respond 429 with the Retry-After header when a bucket of the route is empty.
+// Put it after MustAuthenticateAccessToken to limit by user. A store error is logged and the request is allowed.
+func (r *RateLimiter) Limit() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			res := r.allow(req.Context(), routeKey(req.Method, c.Path()), clientip.FromRequest(req))
+			if !res.Allowed {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(res.RetryAfter)))
+				return apperr.New(apperr.CodeTooManyRequests, "too many requests")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// allow take a token from every bucket of the request, it's only allowed when all of them have one
+// and RetryAfter is the longest wait. A store error is logged and the request is allowed.
+func (r *RateLimiter) allow(ctx context.Context, route, ip string) Result {
+	if !r.enabled {
+		return Result{Allowed: true}
+	}
+
+	rule := r.ruleOf(route)
+	if rule.Limit <= 0 || rule.Period <= 0 {
+		return Result{Allowed: true}
+	}
+
+	res := Result{Allowed: true}
+	for _, identity := range identities(ctx, ip) {
+		taken, err := r.store.Take(ctx, bucketKey(route, identity), rule)
+		if err != nil {
+			logrus.WithContext(ctx).WithField("route", route).Error(err)
+			continue
+		}
+		if !taken.Allowed {
+			res.Allowed = false
+			if taken.RetryAfter > res.RetryAfter {
+				res.RetryAfter = taken.RetryAfter
+			}
+		}
+	}
+	return res
+}
+
+func (r *RateLimiter) ruleOf(route string) Rule {
+	if rule, ok := r.routeRules[route]; ok {
+		return rule
+	}
+	return r.defaultRule
+}
+
+// identities the buckets of a request, the user when the request is authenticated and the client IP.
+// A user switching IP or a client switching account still runs out of tokens.
+func identities(ctx context.Context, ip string) []string {
+	ids := make([]string, 0, 2)
+	if user := auth.GetUserFromCtx(ctx); user != nil {
+		ids = append(ids, fmt.Sprintf("user:%d", user.ID))
+	}
+	return append(ids, "ip:"+ip)
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+func bucketKey(route, identity string) string {
+	return fmt.Sprintf("rate_limit:%s:%s", route, identity)
+}
+
+// retryAfterSeconds round up, Retry-After has a second precision
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
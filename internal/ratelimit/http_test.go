@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/clientip"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIdentity(t *testing.T) {
+	trustedProxies, err := clientip.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	var ids []string
+	e := echo.New()
+	e.Pre(clientip.HTTPMiddleware(trustedProxies))
+	e.POST("/auth/login/", func(c echo.Context) error {
+		ids = append(ids, identities(c.Request().Context(), clientip.FromRequest(c.Request()))...)
+		return c.NoContent(http.StatusOK)
+	})
+
+	// a client rotating a spoofed x-forwarded-for still has one bucket
+	for _, spoofed := range []string{"1.1.1.1", "2.2.2.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login/", nil)
+		req.RemoteAddr = "203.0.113.7:52100"
+		req.Header.Set(echo.HeaderXForwardedFor, spoofed)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// behind a trusted proxy the client is the forwarded address
+	req := httptest.NewRequest(http.MethodPost, "/auth/login/", nil)
+	req.RemoteAddr = "10.0.0.2:52100"
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.9")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, []string{"ip:203.0.113.7", "ip:203.0.113.7", "ip:198.51.100.9"}, ids)
+
+	// an authenticated request is limited by both the user and the IP
+	ctx := auth.SetUserToCtx(context.Background(), auth.User{ID: 7})
+	assert.Equal(t, []string{"user:7", "ip:198.51.100.9"}, identities(ctx, "198.51.100.9"))
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	rateLimiter := NewRateLimiter(NewMemoryStore(), true, Rule{Limit: 2, Period: time.Minute})
+
+	e := echo.New()
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		_ = c.NoContent(apperr.CodeOf(err).HTTPStatus())
+	}
+	e.POST("/user-balance/transfer/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// stand in for MustAuthenticateAccessToken
+			if userID, err := strconv.Atoi(c.Request().Header.Get("X-User-ID")); err == nil {
+				c.SetRequest(c.Request().WithContext(auth.SetUserToCtx(c.Request().Context(), auth.User{ID: userID})))
+			}
+			return next(c)
+		}
+	}, rateLimiter.Limit())
+
+	send := func(userID, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/user-balance/transfer/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-User-ID", userID)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// a user switching IP runs out of the user bucket
+	assert.Equal(t, http.StatusOK, send("1", "203.0.113.1:52100").Code)
+	assert.Equal(t, http.StatusOK, send("1", "203.0.113.2:52100").Code)
+	rec := send("1", "203.0.113.3:52100")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	// a client switching account runs out of the IP bucket
+	assert.Equal(t, http.StatusOK, send("2", "198.51.100.9:52100").Code)
+	assert.Equal(t, http.StatusOK, send("3", "198.51.100.9:52100").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("4", "198.51.100.9:52100").Code)
+
+	// the other users and IPs are not affected
+	assert.Equal(t, http.StatusOK, send("5", "192.0.2.1:52100").Code)
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval minimum time between two sweeps of the expired buckets
+const memorySweepInterval = 1 * time.Minute
+
+type (
+	memoryBucket struct {
+		tokens    float64
+		ts        time.Time
+		expiredAt time.Time
+	}
+
+	memoryStore struct {
+		mu        sync.Mutex
+		buckets   map[string]*memoryBucket
+		lastSweep time.Time
+		now       func() time.Time
+	}
+)
+
+// NewMemoryStore store the buckets in the process, the limits only apply to this instance
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take refill the bucket by the elapsed time, then take a token when there is one, like the redis script
+func (m *memoryStore) Take(_ context.Context, key string, rule Rule) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	capacity := float64(rule.Limit)
+	ratePerMs := capacity / float64(rule.Period.Milliseconds())
+
+	bucket, ok := m.buckets[key]
+	if !ok || !now.Before(bucket.expiredAt) {
+		bucket = &memoryBucket{tokens: capacity, ts: now}
+		m.buckets[key] = bucket
+	}
+
+	if elapsed := now.Sub(bucket.ts); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+float64(elapsed)/float64(time.Millisecond)*ratePerMs)
+		bucket.ts = now
+	}
+	bucket.expiredAt = now.Add(rule.Period)
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return Result{Allowed: true}, nil
+	}
+
+	wait := math.Ceil((1 - bucket.tokens) / ratePerMs)
+	return Result{RetryAfter: time.Duration(wait) * time.Millisecond}, nil
+}
+
+// sweep delete the expired buckets once in a while, so the buckets of the clients which are gone don't stay in memory.
+// m.mu must be held.
+func (m *memoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < memorySweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, bucket := range m.buckets {
+		if !now.Before(bucket.expiredAt) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore().(*memoryStore)
+	store.now = func() time.Time { return now }
+	rule := Rule{Limit: 2, Period: time.Minute}
+
+	// the bucket start full, with a burst up to the limit
+	for i := 0; i < 2; i++ {
+		res, err := store.Take(ctx, "bucket", rule)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	res, err := store.Take(ctx, "bucket", rule)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 30*time.Second, res.RetryAfter)
+
+	// the other buckets are not affected
+	res, err = store.Take(ctx, "other", rule)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// one token is refilled every 30 seconds
+	now = now.Add(20 * time.Second)
+	res, err = store.Take(ctx, "bucket", rule)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 10*time.Second, res.RetryAfter)
+
+	now = now.Add(10 * time.Second)
+	res, err = store.Take(ctx, "bucket", rule)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore().(*memoryStore)
+	store.now = func() time.Time { return now }
+	store.lastSweep = now
+	rule := Rule{Limit: 1, Period: time.Minute}
+
+	res, err := store.Take(ctx, "bucket", rule)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// the bucket expires when it would be full again, and is swept from memory
+	now = now.Add(2 * memorySweepInterval)
+	res, err = store.Take(ctx, "other", rule)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.NotContains(t, store.buckets, "bucket")
+
+	res, err = store.Take(ctx, "bucket", rule)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	redigo "github.com/gomodule/redigo/redis"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Rule allow Limit requests per Period, with a burst up to Limit
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Result of a request against the bucket
+type Result struct {
+	Allowed bool
+	// RetryAfter the time until the next token, only set when the request isn't allowed
+	RetryAfter time.Duration
+}
+
+// tokenBucketScript refill the bucket by the elapsed time, then take a token when there is one.
+// KEYS[1] the bucket, ARGV capacity, refill rate per millisecond, now in milliseconds and the bucket TTL in milliseconds.
+// It returns whether the token is taken and the tokens left, as a string so the fraction isn't truncated.
+var tokenBucketScript = redigo.NewScript(1, `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+-- the clocks of the instances may differ slightly, never refill backward
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+ts = math.max(now, ts)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", ts)
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+// Store keep the token buckets
+type Store interface {
+	// Take a token from the bucket of the key, the bucket expires when it would be full again
+	Take(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+type redisStore struct {
+	pool *redigo.Pool
+}
+
+// NewRedisStore store the buckets in redis, so the limits apply across the instances
+func NewRedisStore(pool *redigo.Pool) Store {
+	return &redisStore{pool: pool}
+}
+
+func (r *redisStore) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ratePerMs := float64(rule.Limit) / float64(rule.Period.Milliseconds())
+	reply, err := redigo.Values(tokenBucketScript.Do(conn,
+		key,
+		rule.Limit,
+		strconv.FormatFloat(ratePerMs, 'f', -1, 64),
+		time.Now().UnixMilli(),
+		rule.Period.Milliseconds(),
+	))
+	if err != nil {
+		return Result{}, err
+	}
+
+	var (
+		allowed int
+		tokens  string
+	)
+	if _, err = redigo.Scan(reply, &allowed, &tokens); err != nil {
+		return Result{}, err
+	}
+	if allowed == 1 {
+		return Result{Allowed: true}, nil
+	}
+
+	left, err := strconv.ParseFloat(tokens, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	wait := math.Ceil((1 - left) / ratePerMs)
+	return Result{RetryAfter: time.Duration(wait) * time.Millisecond}, nil
+}
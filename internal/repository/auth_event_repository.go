@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+type authEventRepository struct {
+	db *gorm.DB
+}
+
+func NewAuthEventRepository(
+	db *gorm.DB,
+) model.AuthEventRepository {
+	return &authEventRepository{
+		db: db,
+	}
+}
+
+func (a *authEventRepository) Create(ctx context.Context, event *model.AuthEvent) error {
+	err := a.db.WithContext(ctx).Create(event).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"event": utils.Dump(event),
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *authEventRepository) FindByUserID(ctx context.Context, userID, limit int) ([]*model.AuthEvent, error) {
+	var events []*model.AuthEvent
+	err := a.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id desc").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (a *authEventRepository) DeleteCreatedBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	// postgres has no DELETE ... LIMIT, the batch is selected by a subquery
+	ids := a.db.Model(&model.AuthEvent{}).
+		Select("id").
+		Where("created_at < ?", before).
+		Order("id asc").
+		Limit(limit)
+
+	res := a.db.WithContext(ctx).Where("id IN (?)", ids).Delete(&model.AuthEvent{})
+	if res.Error != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"before": before,
+			"limit":  limit,
+		}).Error(res.Error)
+		return 0, res.Error
+	}
+
+	return res.RowsAffected, nil
+}
@@ -21,8 +21,7 @@ func NewBankBalanceHistoryRepository(
 }
 
 func (b *bankBalanceHistoryRepository) CreateWithTransaction(ctx context.Context, tx *gorm.DB, input *model.BankBalanceHistory) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":                utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"bankBalanceHistory": utils.Dump(input),
 	})
 
@@ -21,8 +21,7 @@ func NewBankBalanceRepository(
 }
 
 func (b *bankBalanceRepository) CreateWithTransaction(ctx context.Context, tx *gorm.DB, bankBalance *model.BankBalance) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":         utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"bankBalance": utils.Dump(bankBalance),
 	})
 
@@ -40,8 +39,7 @@ func (b *bankBalanceRepository) CreateWithTransaction(ctx context.Context, tx *g
 }
 
 func (b *bankBalanceRepository) UpsertWithTransaction(ctx context.Context, tx *gorm.DB, bankBalance *model.BankBalance) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":         utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"bankBalance": utils.Dump(bankBalance),
 	})
 	switch {
@@ -71,8 +69,7 @@ func (b *bankBalanceRepository) GetCurrentBankBalanceByCode(ctx context.Context,
 	case gorm.ErrRecordNotFound:
 		return &bankBalance, nil
 	default:
-		logrus.WithFields(logrus.Fields{
-			"ctx":  utils.DumpIncomingContext(ctx),
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"code": code,
 		}).Error(err)
 		return nil, err
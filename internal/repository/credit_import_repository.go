@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type creditImportRepository struct {
+	db *gorm.DB
+}
+
+func NewCreditImportRepository(
+	db *gorm.DB,
+) model.CreditImportRepository {
+	return &creditImportRepository{
+		db: db,
+	}
+}
+
+func (c *creditImportRepository) CreateWithTransaction(ctx context.Context, tx *gorm.DB, creditImport *model.CreditImport) error {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"creditImport": utils.Dump(creditImport),
+	})
+
+	err := tx.WithContext(ctx).Create(creditImport).Error
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *creditImportRepository) FindByReferenceWithTransaction(ctx context.Context, tx *gorm.DB, reference string) (*model.CreditImport, error) {
+	creditImport := &model.CreditImport{}
+	err := tx.WithContext(ctx).Take(creditImport, "reference = ?", reference).Error
+	switch err {
+	case nil:
+		return creditImport, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"reference": reference,
+		}).Error(err)
+		return nil, err
+	}
+}
+
+func (c *creditImportRepository) FindByReferences(ctx context.Context, references []string) ([]*model.CreditImport, error) {
+	var creditImports []*model.CreditImport
+	if len(references) == 0 {
+		return creditImports, nil
+	}
+
+	err := c.db.WithContext(ctx).Where("reference IN ?", references).Find(&creditImports).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"references": references,
+		}).Error(err)
+		return nil, err
+	}
+
+	return creditImports, nil
+}
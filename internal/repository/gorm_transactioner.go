@@ -12,6 +12,8 @@ type (
 		Begin(ctx context.Context) *gorm.DB
 		Commit(tx *gorm.DB) error
 		Rollback(tx *gorm.DB)
+		SavePoint(tx *gorm.DB, name string) error
+		RollbackTo(tx *gorm.DB, name string) error
 	}
 
 	gormTransactioner struct {
@@ -38,3 +40,13 @@ func (t *gormTransactioner) Commit(tx *gorm.DB) error {
 func (t *gormTransactioner) Rollback(tx *gorm.DB) {
 	tx.Rollback()
 }
+
+// SavePoint :nodoc:
+func (t *gormTransactioner) SavePoint(tx *gorm.DB, name string) error {
+	return tx.SavePoint(name).Error
+}
+
+// RollbackTo rollback the transaction to the given save point
+func (t *gormTransactioner) RollbackTo(tx *gorm.DB, name string) error {
+	return tx.RollbackTo(name).Error
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+type loginLockoutRepository struct {
+	cacheManager cacher.CacheManager
+}
+
+// NewLoginLockoutRepository the lockouts are only kept in the cache
+func NewLoginLockoutRepository(cacheManager cacher.CacheManager) model.LoginLockoutRepository {
+	return &loginLockoutRepository{cacheManager: cacheManager}
+}
+
+func (l *loginLockoutRepository) FindByScopeAndKey(ctx context.Context, scope model.LoginLockoutScope, key string) (*model.LoginLockout, error) {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"scope": scope,
+		"key":   key,
+	})
+
+	// the lockouts only live in the cache, a missing counter is zero and a missing lock is nil
+	attempts, err := cacher.GetOrSet(ctx, l.cacheManager, l.newAttemptsCacheKey(scope, key), func(context.Context) (*int64, error) {
+		return new(int64), nil
+	})
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	lockedUntil, err := cacher.GetOrSet(ctx, l.cacheManager, l.newLockedUntilCacheKey(scope, key), func(context.Context) (*int64, error) {
+		return nil, nil
+	})
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	lockout := &model.LoginLockout{
+		Scope:          scope,
+		Key:            key,
+		FailedAttempts: *attempts,
+	}
+	if lockedUntil != nil {
+		until := time.UnixMilli(*lockedUntil)
+		lockout.LockedUntil = &until
+	}
+
+	return lockout, nil
+}
+
+func (l *loginLockoutRepository) IncrementFailedAttempts(ctx context.Context, scope model.LoginLockoutScope, key string, window time.Duration) (int64, error) {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"scope": scope,
+		"key":   key,
+	})
+
+	cacheKey := l.newAttemptsCacheKey(scope, key)
+	if err := l.cacheManager.IncreaseCachedValueByOne(ctx, cacheKey); err != nil {
+		logger.Error(err)
+		return 0, err
+	}
+
+	// restart the window everytime the attempts is incremented
+	if err := l.cacheManager.Expire(ctx, cacheKey, window); err != nil {
+		logger.Error(err)
+		return 0, err
+	}
+
+	attempts, err := l.cacheManager.Get(ctx, cacheKey)
+	if err != nil {
+		logger.Error(err)
+		return 0, err
+	}
+
+	return utils.InterfaceBytesToType[int64](attempts), nil
+}
+
+// Lock store the time until the logins are delayed, the lock expire at that time
+func (l *loginLockoutRepository) Lock(ctx context.Context, scope model.LoginLockoutScope, key string, until time.Time) error {
+	// the cache TTL is in seconds, round up so the lock doesn't expire early
+	ttl := time.Until(until).Truncate(time.Second) + time.Second
+
+	item := cacher.NewItemWithCustomTTL(l.newLockedUntilCacheKey(scope, key), until.UnixMilli(), ttl)
+	if err := l.cacheManager.StoreWithoutBlocking(ctx, item); err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"scope": scope,
+			"key":   key,
+			"until": until,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (l *loginLockoutRepository) DeleteByScopeAndKey(ctx context.Context, scope model.LoginLockoutScope, key string) error {
+	err := l.cacheManager.DeleteByKeys(ctx, []string{
+		l.newAttemptsCacheKey(scope, key),
+		l.newLockedUntilCacheKey(scope, key),
+	})
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"scope": scope,
+			"key":   key,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (l *loginLockoutRepository) newAttemptsCacheKey(scope model.LoginLockoutScope, key string) string {
+	return fmt.Sprintf("cache:login_lockout:attempts:%s:%s", strings.ToLower(string(scope)), key)
+}
+
+func (l *loginLockoutRepository) newLockedUntilCacheKey(scope model.LoginLockoutScope, key string) string {
+	return fmt.Sprintf("cache:login_lockout:locked_until:%s:%s", strings.ToLower(string(scope)), key)
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLoginLockoutRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewLoginLockoutRepository(cacher.NewMemoryCacheManager())
+
+	// reading a missing lockout cache it as zero attempts and not locked, the attempts can still be incremented
+	lockout, err := repo.FindByScopeAndKey(ctx, model.LoginLockoutScopeIP, "203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), lockout.FailedAttempts)
+	assert.Nil(t, lockout.LockedUntil)
+
+	for i := int64(1); i <= 2; i++ {
+		attempts, err := repo.IncrementFailedAttempts(ctx, model.LoginLockoutScopeIP, "203.0.113.7", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, i, attempts)
+	}
+
+	until := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	require.NoError(t, repo.Lock(ctx, model.LoginLockoutScopeIP, "203.0.113.7", until))
+
+	lockout, err = repo.FindByScopeAndKey(ctx, model.LoginLockoutScopeIP, "203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), lockout.FailedAttempts)
+	require.NotNil(t, lockout.LockedUntil)
+	assert.True(t, until.Equal(*lockout.LockedUntil))
+
+	require.NoError(t, repo.DeleteByScopeAndKey(ctx, model.LoginLockoutScopeIP, "203.0.113.7"))
+	lockout, err = repo.FindByScopeAndKey(ctx, model.LoginLockoutScopeIP, "203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), lockout.FailedAttempts)
+	assert.Nil(t, lockout.LockedUntil)
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+// outboxRelayLockKey key of the postgres advisory lock held by the active relay
+const outboxRelayLockKey = 7460341
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(
+	db *gorm.DB,
+) model.OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (o *outboxRepository) CreateWithTransaction(ctx context.Context, tx *gorm.DB, messages []*model.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	err := tx.WithContext(ctx).Create(&messages).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"messages": utils.Dump(messages),
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (o *outboxRepository) TryLockRelayWithTransaction(ctx context.Context, tx *gorm.DB) (bool, error) {
+	var locked bool
+	err := tx.WithContext(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockKey).Scan(&locked).Error
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return false, err
+	}
+
+	return locked, nil
+}
+
+func (o *outboxRepository) FindPendingWithTransaction(ctx context.Context, tx *gorm.DB, limit int) ([]*model.OutboxMessage, error) {
+	var messages []*model.OutboxMessage
+	err := tx.WithContext(ctx).
+		Where("status = ?", model.OutboxStatusPending).
+		Order("id asc").
+		Limit(limit).
+		Find(&messages).Error
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (o *outboxRepository) ClaimWithTransaction(ctx context.Context, tx *gorm.DB, ids []int64, claimedUntil time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := tx.WithContext(ctx).Model(&model.OutboxMessage{}).Where("id IN ?", ids).Update("claimed_until", claimedUntil).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"ids": ids,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (o *outboxRepository) ReleaseClaimWithTransaction(ctx context.Context, tx *gorm.DB, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := tx.WithContext(ctx).Model(&model.OutboxMessage{}).Where("id IN ?", ids).Update("claimed_until", nil).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"ids": ids,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (o *outboxRepository) MarkPublishedWithTransaction(ctx context.Context, tx *gorm.DB, ids []int64, publishedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := tx.WithContext(ctx).Model(&model.OutboxMessage{}).Where("id IN ?", ids).
+		Updates(map[string]any{"status": model.OutboxStatusPublished, "published_at": publishedAt, "claimed_until": nil}).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"ids": ids,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (o *outboxRepository) MarkFailedWithTransaction(ctx context.Context, tx *gorm.DB, message *model.OutboxMessage) error {
+	err := tx.WithContext(ctx).Model(&model.OutboxMessage{}).Where("id = ?", message.ID).
+		Updates(map[string]any{
+			"status":        message.Status,
+			"attempts":      message.Attempts,
+			"last_error":    message.LastError,
+			"claimed_until": nil,
+		}).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"id": message.ID,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/go-redsync/redsync/v4"
 	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
@@ -35,8 +34,7 @@ func NewSessionRepository(
 
 // Create :nodoc:
 func (s sessionRepo) Create(ctx context.Context, sess *model.Session) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":    utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"userID": sess.UserID,
 	})
 	err := s.db.WithContext(ctx).Create(sess).Error
@@ -45,7 +43,7 @@ func (s sessionRepo) Create(ctx context.Context, sess *model.Session) error {
 		return err
 	}
 
-	if err = s.cacheToken(sess); err != nil {
+	if err = s.cacheToken(ctx, sess); err != nil {
 		logger.Error(err)
 	}
 	return nil
@@ -53,122 +51,131 @@ func (s sessionRepo) Create(ctx context.Context, sess *model.Session) error {
 
 // FindByToken find a session by it's token
 func (s *sessionRepo) FindByToken(ctx context.Context, tokenType model.TokenType, token string) (*model.Session, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":       utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"tokenType": tokenType,
 	})
 
-	cacheKey := model.NewSessionTokenCacheKey(token)
-	if !config.DisableCaching() {
-		reply, mu, err := s.findFromCacheByKey(cacheKey)
-		if err != nil {
-			logger.Error(err)
+	// the session is cached until the token expires, like cacheToken, the TTL is only known once it's loaded
+	var ttl time.Duration
+	load := func(ctx context.Context) (*model.Session, error) {
+		column := "access_token"
+		if tokenType == model.RefreshToken {
+			column = "refresh_token"
+		}
+
+		sess := &model.Session{}
+		err := s.db.WithContext(ctx).Take(sess, column+" = ?", token).Error
+		switch err {
+		case nil:
+		case gorm.ErrRecordNotFound:
+			return nil, nil
+		default:
 			return nil, err
 		}
-		defer cacher.SafeUnlock(mu)
 
-		if mu == nil {
-			return reply, nil
+		ttl = time.Until(sess.AccessTokenExpiredAt)
+		if tokenType == model.RefreshToken {
+			ttl = time.Until(sess.RefreshTokenExpiredAt)
 		}
+		return s.withExistingUser(ctx, sess)
 	}
 
-	sess := &model.Session{}
-	var err error
-	switch tokenType {
-	case model.AccessToken:
-		err = s.db.Take(sess, "access_token = ?", token).Error
-	case model.RefreshToken:
-		err = s.db.Take(sess, "refresh_token = ?", token).Error
-	}
-	switch err {
-	case nil:
-	case gorm.ErrRecordNotFound:
-		storeNil(s.cacheManager, cacheKey)
-		return nil, nil
-	default:
-		logger.Error(err)
-		return nil, err
+	var (
+		sess *model.Session
+		err  error
+	)
+	if config.DisableCaching() {
+		sess, err = load(ctx)
+	} else {
+		sess, err = cacher.GetOrSet(ctx, s.cacheManager, model.NewSessionTokenCacheKey(token), load, func(item cacher.Item) {
+			item.SetTTL(ttl)
+		})
 	}
-
-	user, err := s.userRepo.FindByID(ctx, sess.UserID)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
 
-	if user == nil {
-		return nil, nil
-	}
-
-	if err = s.cacheToken(sess); err != nil {
-		logger.Error(err)
-	}
-
 	return sess, nil
 }
 
 // FindByID find session by id
 func (s *sessionRepo) FindByID(ctx context.Context, id int) (*model.Session, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx": utils.DumpIncomingContext(ctx),
-		"id":  id,
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"id": id,
 	})
 
-	cacheKey := s.newCacheKeyByID(id)
-	reply, mu, err := s.findFromCacheByKey(cacheKey)
-	if err != nil {
-		return nil, err
-	}
-	defer cacher.SafeUnlock(mu)
-
-	if mu == nil {
-		return reply, nil
-	}
+	// the session is cached until its access token expires, like cacheToken
+	var ttl time.Duration
+	sess, err := cacher.GetOrSet(ctx, s.cacheManager, s.newCacheKeyByID(id), func(ctx context.Context) (*model.Session, error) {
+		sess := &model.Session{}
+		err := s.db.WithContext(ctx).Take(sess, "id = ?", id).Error
+		switch err {
+		case nil:
+		case gorm.ErrRecordNotFound:
+			return nil, nil
+		default:
+			return nil, err
+		}
 
-	sess := model.Session{}
-	err = s.db.WithContext(ctx).Take(&sess, "id = ?", id).Error
-	switch err {
-	case nil:
-	case gorm.ErrRecordNotFound:
-		storeNil(s.cacheManager, cacheKey)
-		return nil, nil
-	default:
+		ttl = time.Until(sess.AccessTokenExpiredAt)
+		return s.withExistingUser(ctx, sess)
+	}, func(item cacher.Item) {
+		item.SetTTL(ttl)
+	})
+	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
 
+	return sess, nil
+}
+
+// withExistingUser return the loaded session when its user still exist and cache its tokens, nil otherwise
+func (s *sessionRepo) withExistingUser(ctx context.Context, sess *model.Session) (*model.Session, error) {
 	user, err := s.userRepo.FindByID(ctx, sess.UserID)
 	if err != nil {
-		logger.Error(err)
 		return nil, err
 	}
-
 	if user == nil {
 		return nil, nil
 	}
 
-	if err = s.cacheToken(&sess); err != nil {
-		logger.Error(err)
+	if err = s.cacheToken(ctx, sess); err != nil {
+		logrus.WithContext(ctx).WithField("sessionID", sess.ID).Error(err)
+	}
+	return sess, nil
+}
+
+// FindAllByUserID find every session of the user ordered by the oldest
+func (s *sessionRepo) FindAllByUserID(ctx context.Context, userID int) ([]*model.Session, error) {
+	var sessions []*model.Session
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at asc, id asc").Find(&sessions).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return nil, err
 	}
 
-	return &sess, nil
+	return sessions, nil
 }
 
 // CheckToken check whether the token exists or not in the cache
 func (s *sessionRepo) CheckToken(ctx context.Context, token string) (exist bool, err error) {
-	reply, err := s.cacheManager.Get(model.NewSessionTokenCacheKey(token))
+	reply, err := s.cacheManager.Get(ctx, model.NewSessionTokenCacheKey(token))
 	if err != nil {
 		return false, err
 	}
 
+	// "null" is the miss cached by FindByToken, the token doesn't exist
 	bt, _ := reply.([]byte)
-	return string(bt) != "", nil
+	return len(bt) > 0 && string(bt) != "null", nil
 }
 
 // RefreshToken update access and refresh token string value and expired_at
 func (s *sessionRepo) RefreshToken(ctx context.Context, oldSess, sess *model.Session) (*model.Session, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":     utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"session": utils.Dump(sess),
 	})
 
@@ -187,10 +194,10 @@ func (s *sessionRepo) RefreshToken(ctx context.Context, oldSess, sess *model.Ses
 		return nil, err
 	}
 
-	if err = s.deleteCaches(oldSess); err != nil {
+	if err = s.deleteCaches(ctx, oldSess); err != nil {
 		logger.Error(err)
 	}
-	if err = s.deleteCaches(sess); err != nil {
+	if err = s.deleteCaches(ctx, sess); err != nil {
 		logger.Error(err)
 	}
 
@@ -199,8 +206,7 @@ func (s *sessionRepo) RefreshToken(ctx context.Context, oldSess, sess *model.Ses
 
 // Delete deletes existing session by id.
 func (s *sessionRepo) Delete(ctx context.Context, session *model.Session) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":     utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"session": utils.Dump(session),
 	})
 
@@ -210,29 +216,29 @@ func (s *sessionRepo) Delete(ctx context.Context, session *model.Session) error
 		return err
 	}
 
-	if err := s.deleteCaches(session); err != nil {
+	if err := s.deleteCaches(ctx, session); err != nil {
 		logger.Error(err)
 	}
 
 	return nil
 }
 
-func (s *sessionRepo) cacheToken(session *model.Session) error {
+func (s *sessionRepo) cacheToken(ctx context.Context, session *model.Session) error {
 	sess, err := json.Marshal(session)
 	if err != nil {
 		return err
 	}
 
 	now := time.Now()
-	return s.cacheManager.StoreMultiWithoutBlocking([]cacher.Item{
+	return s.cacheManager.StoreMultiWithoutBlocking(ctx, []cacher.Item{
 		cacher.NewItemWithCustomTTL(model.NewSessionTokenCacheKey(session.AccessToken), sess, session.AccessTokenExpiredAt.Sub(now)),
 		cacher.NewItemWithCustomTTL(s.newCacheKeyByID(session.ID), sess, session.AccessTokenExpiredAt.Sub(now)),
 		cacher.NewItemWithCustomTTL(model.NewSessionTokenCacheKey(session.RefreshToken), sess, session.RefreshTokenExpiredAt.Sub(now)),
 	})
 }
 
-func (s *sessionRepo) deleteCaches(session *model.Session) error {
-	return s.cacheManager.DeleteByKeys([]string{
+func (s *sessionRepo) deleteCaches(ctx context.Context, session *model.Session) error {
+	return s.cacheManager.DeleteByKeys(ctx, []string{
 		model.NewSessionTokenCacheKey(session.AccessToken),
 		model.NewSessionTokenCacheKey(session.RefreshToken),
 		s.newCacheKeyByID(session.ID),
@@ -242,14 +248,3 @@ func (s *sessionRepo) deleteCaches(session *model.Session) error {
 func (s *sessionRepo) newCacheKeyByID(id int) string {
 	return fmt.Sprintf("cache:object:session:id:%d", id)
 }
-
-func (s *sessionRepo) findFromCacheByKey(key string) (reply *model.Session, mu *redsync.Mutex, err error) {
-	var rep interface{}
-	rep, mu, err = s.cacheManager.GetOrLock(key)
-	if err != nil || rep == nil {
-		return
-	}
-
-	reply = utils.InterfaceBytesToType[*model.Session](rep)
-	return
-}
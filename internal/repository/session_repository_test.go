@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+// fakeUserRepository a user repository where every user exists
+type fakeUserRepository struct {
+	model.UserRepository
+}
+
+func (fakeUserRepository) FindByID(_ context.Context, id int) (*model.User, error) {
+	return &model.User{ID: id}, nil
+}
+
+func newTestSessionRepository(t *testing.T) (*sessionRepo, sqlmock.Sqlmock, cacher.CacheManager) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+
+	cacheManager := cacher.NewMemoryCacheManager()
+	return NewSessionRepository(db, cacheManager, fakeUserRepository{}).(*sessionRepo), mock, cacheManager
+}
+
+func TestSessionRepository_FindByToken(t *testing.T) {
+	ctx := context.Background()
+	repo, mock, cacheManager := newTestSessionRepository(t)
+
+	now := time.Now()
+	sess := &model.Session{
+		ID:                    1,
+		UserID:                1,
+		AccessToken:           "1_access",
+		RefreshToken:          "1_refresh",
+		AccessTokenExpiredAt:  now.Add(time.Hour),
+		RefreshTokenExpiredAt: now.Add(24 * time.Hour),
+	}
+	mock.ExpectQuery(`SELECT \* FROM "sessions" WHERE refresh_token = \$1`).
+		WithArgs(sess.RefreshToken).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "access_token", "refresh_token", "access_token_expired_at", "refresh_token_expired_at"}).
+			AddRow(sess.ID, sess.UserID, sess.AccessToken, sess.RefreshToken, sess.AccessTokenExpiredAt, sess.RefreshTokenExpiredAt))
+
+	found, err := repo.FindByToken(ctx, model.RefreshToken, sess.RefreshToken)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, sess.ID, found.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// every key is cached until its token expires, the refresh token isn't cut to the default TTL
+	for key, expected := range map[string]time.Duration{
+		model.NewSessionTokenCacheKey(sess.AccessToken):  time.Hour,
+		model.NewSessionTokenCacheKey(sess.RefreshToken): 24 * time.Hour,
+		repo.newCacheKeyByID(sess.ID):                    time.Hour,
+	} {
+		ttl, err := cacheManager.GetTTL(ctx, key)
+		require.NoError(t, err)
+		assert.InDelta(t, expected.Seconds(), ttl, 2, key)
+	}
+
+	// the next call is a hit
+	found, err = repo.FindByToken(ctx, model.RefreshToken, sess.RefreshToken)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, sess.ID, found.ID)
+}
+
+func TestSessionRepository_CheckToken(t *testing.T) {
+	ctx := context.Background()
+	repo, mock, cacheManager := newTestSessionRepository(t)
+
+	exist, err := repo.CheckToken(ctx, "1_unknown")
+	require.NoError(t, err)
+	assert.False(t, exist)
+
+	// a token looked up and not found is cached as nil, it still doesn't exist
+	mock.ExpectQuery(`SELECT \* FROM "sessions" WHERE access_token = \$1`).
+		WithArgs("1_unknown").
+		WillReturnError(gorm.ErrRecordNotFound)
+	found, err := repo.FindByToken(ctx, model.AccessToken, "1_unknown")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	exist, err = repo.CheckToken(ctx, "1_unknown")
+	require.NoError(t, err)
+	assert.False(t, exist)
+
+	bt, err := json.Marshal(&model.Session{ID: 1, AccessToken: "1_access"})
+	require.NoError(t, err)
+	require.NoError(t, cacheManager.StoreWithoutBlocking(ctx, cacher.NewItem(model.NewSessionTokenCacheKey("1_access"), bt)))
+
+	exist, err = repo.CheckToken(ctx, "1_access")
+	require.NoError(t, err)
+	assert.True(t, exist)
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+type transferLimitRepository struct {
+	db *gorm.DB
+}
+
+// NewTransferLimitRepository :nodoc:
+func NewTransferLimitRepository(db *gorm.DB) model.TransferLimitRepository {
+	return &transferLimitRepository{db: db}
+}
+
+func (t *transferLimitRepository) FindByUserID(ctx context.Context, userID int) (*model.UserTransferLimit, error) {
+	userLimit := &model.UserTransferLimit{}
+	err := t.db.WithContext(ctx).Take(userLimit, "user_id = ?", userID).Error
+	switch err {
+	case nil:
+		return userLimit, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return nil, err
+	}
+}
+
+func (t *transferLimitRepository) GetOutgoingUsageWithTransaction(ctx context.Context, tx *gorm.DB, userID int, now time.Time) (*model.TransferUsage, error) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	hourStart := now.Truncate(time.Hour)
+
+	usage := &model.TransferUsage{}
+	err := tx.WithContext(ctx).
+		Table("user_balance_histories AS h").
+		Select(
+			"COALESCE(SUM(h.balance_before - h.balance_after) FILTER (WHERE h.created_at >= ?), 0) AS daily_amount, "+
+				"COALESCE(SUM(h.balance_before - h.balance_after), 0) AS monthly_amount, "+
+				"COUNT(*) FILTER (WHERE h.created_at >= ?) AS hourly_count",
+			dayStart, hourStart,
+		).
+		Joins("JOIN user_balances AS b ON b.id = h.user_balance_id").
+		Where("b.user_id = ? AND h.type = ? AND h.created_at >= ?", userID, model.DEBIT, monthStart).
+		Scan(usage).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return nil, err
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type transferRepository struct {
+	db *gorm.DB
+}
+
+// NewTransferRepository :nodoc:
+func NewTransferRepository(
+	db *gorm.DB,
+) model.TransferRepository {
+	return &transferRepository{
+		db: db,
+	}
+}
+
+func (t *transferRepository) Create(ctx context.Context, transfer *model.Transfer) error {
+	return t.UpsertWithTransaction(ctx, t.db, transfer)
+}
+
+func (t *transferRepository) UpsertWithTransaction(ctx context.Context, tx *gorm.DB, transfer *model.Transfer) error {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"transfer": utils.Dump(transfer),
+	})
+
+	switch {
+	case transfer.ID > 0:
+		transfer.UpdatedAt = time.Now()
+		err := tx.WithContext(ctx).Select("status", "screening_reasons", "reviewed_by", "reviewed_at", "updated_at").Updates(transfer).Error
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+	default:
+		err := tx.WithContext(ctx).Create(transfer).Error
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *transferRepository) FindByID(ctx context.Context, id int) (*model.Transfer, error) {
+	transfer := &model.Transfer{}
+	err := t.db.WithContext(ctx).Take(transfer, "id = ?", id).Error
+	switch err {
+	case nil:
+		return transfer, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"id": id,
+		}).Error(err)
+		return nil, err
+	}
+}
+
+func (t *transferRepository) FindByIDForUpdateWithTransaction(ctx context.Context, tx *gorm.DB, id int) (*model.Transfer, error) {
+	transfer := &model.Transfer{}
+	err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Take(transfer, "id = ?", id).Error
+	switch err {
+	case nil:
+		return transfer, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"id": id,
+		}).Error(err)
+		return nil, err
+	}
+}
+
+func (t *transferRepository) FindByStatus(ctx context.Context, status model.TransferStatus) ([]*model.Transfer, error) {
+	var transfers []*model.Transfer
+	err := t.db.WithContext(ctx).Where("status = ?", status).Order("created_at asc, id asc").Find(&transfers).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"status": status,
+		}).Error(err)
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (t *transferRepository) CountCompletedByUserIDs(ctx context.Context, fromUserID, toUserID int) (int64, error) {
+	var count int64
+	err := t.db.WithContext(ctx).Model(model.Transfer{}).
+		Where("from_user_id = ? AND to_user_id = ? AND status = ?", fromUserID, toUserID, model.TransferStatusCompleted).
+		Count(&count).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"fromUserID": fromUserID,
+			"toUserID":   toUserID,
+		}).Error(err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (t *transferRepository) FindRecipientIDsSince(ctx context.Context, fromUserID int, since time.Time) ([]int, error) {
+	var recipientIDs []int
+	err := t.db.WithContext(ctx).Model(model.Transfer{}).
+		Distinct("to_user_id").
+		Where("from_user_id = ? AND created_at >= ? AND status <> ?", fromUserID, since, model.TransferStatusRejected).
+		Pluck("to_user_id", &recipientIDs).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"fromUserID": fromUserID,
+			"since":      since,
+		}).Error(err)
+		return nil, err
+	}
+
+	return recipientIDs, nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type twoFactorRepository struct {
+	db           *gorm.DB
+	cacheManager cacher.CacheManager
+}
+
+func NewTwoFactorRepository(
+	db *gorm.DB,
+	cacheManager cacher.CacheManager,
+) model.TwoFactorRepository {
+	return &twoFactorRepository{
+		db:           db,
+		cacheManager: cacheManager,
+	}
+}
+
+// FindByUserID is read from the db only, the secret must not be copied to the cache
+func (t *twoFactorRepository) FindByUserID(ctx context.Context, userID int) (*model.UserTwoFactor, error) {
+	twoFactor := &model.UserTwoFactor{}
+	err := t.db.WithContext(ctx).Take(twoFactor, "user_id = ?", userID).Error
+	switch err {
+	case nil:
+		return twoFactor, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return nil, err
+	}
+}
+
+// Upsert create or replace the not yet enabled secret of the user
+func (t *twoFactorRepository) Upsert(ctx context.Context, twoFactor *model.UserTwoFactor) error {
+	err := t.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "last_used_step", "enabled_at", "updated_at"}),
+	}).Create(twoFactor).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": twoFactor.UserID,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Enable enable the 2FA and replace the recovery codes of the user
+func (t *twoFactorRepository) Enable(ctx context.Context, twoFactor *model.UserTwoFactor, codeHashes []string) error {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID": twoFactor.UserID,
+	})
+
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(twoFactor).Select("enabled_at", "last_used_step", "updated_at").Updates(twoFactor).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(&model.UserRecoveryCode{}, "user_id = ?", twoFactor.UserID).Error
+		if err != nil {
+			return err
+		}
+
+		recoveryCodes := make([]*model.UserRecoveryCode, len(codeHashes))
+		for i, codeHash := range codeHashes {
+			recoveryCodes[i] = &model.UserRecoveryCode{UserID: twoFactor.UserID, CodeHash: codeHash}
+		}
+		return tx.Create(&recoveryCodes).Error
+	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) DeleteByUserID(ctx context.Context, userID int) error {
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.UserRecoveryCode{}, "user_id = ?", userID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.UserTwoFactor{}, "user_id = ?", userID).Error
+	})
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// UseTimeStep mark the time step as used, return false when the step or a later one was already used
+func (t *twoFactorRepository) UseTimeStep(ctx context.Context, userID int, step int64) (bool, error) {
+	res := t.db.WithContext(ctx).Model(&model.UserTwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Updates(map[string]any{"last_used_step": step, "updated_at": time.Now()})
+	if res.Error != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+			"step":   step,
+		}).Error(res.Error)
+		return false, res.Error
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+// UseRecoveryCode mark the recovery code as used, return false when the code is not found or already used
+func (t *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string, usedAt time.Time) (bool, error) {
+	res := t.db.WithContext(ctx).Model(&model.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", usedAt)
+	if res.Error != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(res.Error)
+		return false, res.Error
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+func (t *twoFactorRepository) CreateLoginChallenge(ctx context.Context, challenge *model.LoginChallenge) error {
+	ttl := time.Until(challenge.ExpiredAt)
+	item := cacher.NewItemWithCustomTTL(t.newLoginChallengeCacheKeyByToken(challenge.Token), utils.ToByte(challenge), ttl)
+	if err := t.cacheManager.StoreWithoutBlocking(ctx, item); err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": challenge.UserID,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) FindLoginChallengeByToken(ctx context.Context, token string) (*model.LoginChallenge, error) {
+	// the challenges only live in the cache, there is nothing to load on a miss
+	challenge, err := cacher.GetOrSet(ctx, t.cacheManager, t.newLoginChallengeCacheKeyByToken(token), func(context.Context) (*model.LoginChallenge, error) {
+		return nil, nil
+	})
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (t *twoFactorRepository) DeleteLoginChallengeByToken(ctx context.Context, token string) error {
+	err := t.cacheManager.DeleteByKeys(ctx, []string{t.newLoginChallengeCacheKeyByToken(token)})
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) newLoginChallengeCacheKeyByToken(token string) string {
+	return fmt.Sprintf("cache:login_challenge:token:%s", token)
+}
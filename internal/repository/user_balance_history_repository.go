@@ -6,6 +6,7 @@ import (
 	"github.com/irvankadhafi/user-balance-transfer-service/utils"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"time"
 )
 
 type userBalanceHistoryRepository struct {
@@ -21,8 +22,7 @@ func NewUserBalanceHistoryRepository(
 }
 
 func (u userBalanceHistoryRepository) CreateWithTransaction(ctx context.Context, tx *gorm.DB, input *model.UserBalanceHistory) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":                utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"userBalanceHistory": utils.Dump(input),
 	})
 
@@ -34,3 +34,41 @@ func (u userBalanceHistoryRepository) CreateWithTransaction(ctx context.Context,
 
 	return nil
 }
+
+func (u userBalanceHistoryRepository) FindByUserBalanceIDAndPeriod(ctx context.Context, userBalanceID int, from, to time.Time) ([]*model.UserBalanceHistory, error) {
+	var histories []*model.UserBalanceHistory
+	err := u.db.WithContext(ctx).
+		Where("user_balance_id = ? AND created_at >= ? AND created_at < ?", userBalanceID, from, to).
+		Order("created_at asc, id asc").
+		Find(&histories).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userBalanceID": userBalanceID,
+			"from":          from,
+			"to":            to,
+		}).Error(err)
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+func (u userBalanceHistoryRepository) FindLastByUserBalanceIDBefore(ctx context.Context, userBalanceID int, before time.Time) (*model.UserBalanceHistory, error) {
+	history := &model.UserBalanceHistory{}
+	err := u.db.WithContext(ctx).
+		Where("user_balance_id = ? AND created_at < ?", userBalanceID, before).
+		Order("created_at desc, id desc").
+		Take(history).Error
+	switch err {
+	case nil:
+		return history, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userBalanceID": userBalanceID,
+			"before":        before,
+		}).Error(err)
+		return nil, err
+	}
+}
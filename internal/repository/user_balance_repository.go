@@ -6,6 +6,7 @@ import (
 	"github.com/irvankadhafi/user-balance-transfer-service/utils"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userBalanceRepository struct {
@@ -21,8 +22,7 @@ func NewUserBalanceRepository(
 }
 
 func (u userBalanceRepository) CreateWithTransaction(ctx context.Context, tx *gorm.DB, userBalance *model.UserBalance) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":         utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"userBalance": utils.Dump(userBalance),
 	})
 
@@ -40,13 +40,13 @@ func (u userBalanceRepository) CreateWithTransaction(ctx context.Context, tx *go
 }
 
 func (u userBalanceRepository) UpsertWithTransaction(ctx context.Context, tx *gorm.DB, userBalance *model.UserBalance) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":         utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"userBalance": utils.Dump(userBalance),
 	})
 	switch {
 	case userBalance.ID > 0:
-		err := tx.WithContext(ctx).Updates(userBalance).Error
+		// select the columns explicitly, so a balance that drops to zero is still updated
+		err := tx.WithContext(ctx).Select("balance", "balance_achieve").Updates(userBalance).Error
 		if err != nil {
 			logger.Error(err)
 			return err
@@ -71,10 +71,33 @@ func (u userBalanceRepository) GetCurrentUserBalanceByUserID(ctx context.Context
 	case gorm.ErrRecordNotFound:
 		return &userBalance, nil
 	default:
-		logrus.WithFields(logrus.Fields{
-			"ctx":    utils.DumpIncomingContext(ctx),
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"userID": userID,
 		}).Error(err)
 		return nil, err
 	}
 }
+
+// FindByUserIDsForUpdateWithTransaction lock the balances of the given users until the transaction end.
+// The rows are always locked in ascending user id order, so concurrent transactions locking an overlapping
+// set of users wait for each other instead of deadlocking.
+func (u userBalanceRepository) FindByUserIDsForUpdateWithTransaction(ctx context.Context, tx *gorm.DB, userIDs []int) ([]*model.UserBalance, error) {
+	var userBalances []*model.UserBalance
+	if len(userIDs) == 0 {
+		return userBalances, nil
+	}
+
+	err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id IN ?", userIDs).
+		Order("user_id asc").
+		Find(&userBalances).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userIDs": userIDs,
+		}).Error(err)
+		return nil, err
+	}
+
+	return userBalances, nil
+}
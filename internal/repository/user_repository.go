@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"fmt"
-	"github.com/go-redsync/redsync/v4"
 	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
@@ -28,8 +27,7 @@ func NewUserRepository(
 }
 
 func (u *userRepository) Create(ctx context.Context, user *model.User) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":  utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"user": utils.Dump(user),
 	})
 
@@ -39,133 +37,156 @@ func (u *userRepository) Create(ctx context.Context, user *model.User) error {
 		return err
 	}
 
+	// the ID, email and username may be cached as not found, the IDs are sequential so the next one can be looked up before it exists
+	err = u.cacheManager.DeleteByKeys(ctx, []string{
+		u.newCacheKeyByID(user.ID),
+		u.newUserCacheKeyByEmail(user.Email),
+		u.newUserCacheKeyByUsername(user.Username),
+	})
+	if err != nil {
+		logger.Error(err)
+	}
+
 	return nil
 }
 
 func (u *userRepository) FindByID(ctx context.Context, id int) (*model.User, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx": utils.DumpIncomingContext(ctx),
-		"id":  id,
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"id": id,
 	})
 
 	if id <= 0 {
 		return nil, nil
 	}
 
-	cacheKey := u.newCacheKeyByID(id)
-
-	user := &model.User{}
-	err := u.db.WithContext(ctx).Take(user, "id = ?", id).Error
-	switch err {
-	case nil:
-		return user, nil
-	case gorm.ErrRecordNotFound:
-		storeNil(u.cacheManager, cacheKey)
-		return nil, nil
-	default:
+	user, err := cacher.GetOrSet(ctx, u.cacheManager, u.newCacheKeyByID(id), func(ctx context.Context) (*model.User, error) {
+		user := &model.User{}
+		err := u.db.WithContext(ctx).Take(user, "id = ?", id).Error
+		switch err {
+		case nil:
+			return user, nil
+		case gorm.ErrRecordNotFound:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	})
+	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
+
+	return user, nil
 }
 
 func (u *userRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":      utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"username": username,
 	})
-	cacheKey := u.newUserCacheKeyByUsername(username)
-	var id int
-	err := u.db.Model(model.User{}).Select("id").Take(&id, "username = ?", username).Error
-	switch err {
-	case nil:
-		err := u.cacheManager.StoreWithoutBlocking(cacher.NewItem(cacheKey, utils.ToByte(id)))
-		if err != nil {
-			logger.Error(err)
-		}
-		return u.FindByID(ctx, id)
-	case gorm.ErrRecordNotFound:
-		storeNil(u.cacheManager, cacheKey)
-		return nil, nil
-	default:
+
+	id, err := cacher.GetOrSet(ctx, u.cacheManager, u.newUserCacheKeyByUsername(username), func(ctx context.Context) (*int, error) {
+		return u.findIDByColumn(ctx, "username", username)
+	})
+	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
+	if id == nil {
+		return nil, nil
+	}
+
+	return u.FindByID(ctx, *id)
 }
 
 func (u *userRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":   utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"email": email,
 	})
-	cacheKey := u.newUserCacheKeyByEmail(email)
-	var id int
-	err := u.db.Model(model.User{}).Select("id").Take(&id, "email = ?", email).Error
-	switch err {
-	case nil:
-		err := u.cacheManager.StoreWithoutBlocking(cacher.NewItem(cacheKey, utils.ToByte(id)))
-		if err != nil {
-			logger.Error(err)
-		}
-		return u.FindByID(ctx, id)
-	case gorm.ErrRecordNotFound:
-		storeNil(u.cacheManager, cacheKey)
-		return nil, nil
-	default:
+
+	id, err := cacher.GetOrSet(ctx, u.cacheManager, u.newUserCacheKeyByEmail(email), func(ctx context.Context) (*int, error) {
+		return u.findIDByColumn(ctx, "email", email)
+	})
+	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
+	if id == nil {
+		return nil, nil
+	}
+
+	return u.FindByID(ctx, *id)
 }
 
 func (u *userRepository) FindPasswordByID(ctx context.Context, id int) ([]byte, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx": utils.DumpIncomingContext(ctx),
-		"id":  id,
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"id": id,
 	})
 
-	cacheKey := u.newPasswordCacheKeyByID(id)
-	reply, mu, err := u.findStringValueFromCacheByKey(cacheKey)
-	defer cacher.SafeUnlock(mu)
+	pass, err := cacher.GetOrSet(ctx, u.cacheManager, u.newPasswordCacheKeyByID(id), func(ctx context.Context) (*string, error) {
+		return u.findStringColumnByID(ctx, "password", id)
+	})
 	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
-	if mu == nil {
-		return []byte(reply), nil
-	}
-
-	var pass string
-	err = u.db.WithContext(ctx).Model(model.User{}).Select("password").Take(&pass, "id = ?", id).Error
-	if err == gorm.ErrRecordNotFound {
+	if pass == nil {
 		return nil, nil
 	}
+
+	return []byte(*pass), nil
+}
+
+func (u *userRepository) FindTransactionPinByID(ctx context.Context, id int) ([]byte, error) {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"id": id,
+	})
+
+	pin, err := cacher.GetOrSet(ctx, u.cacheManager, u.newTransactionPinCacheKeyByID(id), func(ctx context.Context) (*string, error) {
+		return u.findStringColumnByID(ctx, "transaction_pin", id)
+	})
 	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
+	if pin == nil {
+		return nil, nil
+	}
 
-	err = u.cacheManager.StoreWithoutBlocking(cacher.NewItem(cacheKey, utils.ToByte(pass)))
+	return []byte(*pin), nil
+}
+
+func (u *userRepository) UpdateTransactionPinByID(ctx context.Context, id int, cipherPin string) error {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"id": id,
+	})
+
+	err := u.db.WithContext(ctx).Model(model.User{}).Where("id = ?", id).Update("transaction_pin", cipherPin).Error
 	if err != nil {
 		logger.Error(err)
+		return err
+	}
+
+	if err = u.cacheManager.DeleteByKeys(ctx, []string{u.newTransactionPinCacheKeyByID(id)}); err != nil {
+		logger.Error(err)
 	}
 
-	return []byte(pass), err
+	return nil
 }
 
-// IncrementLoginByEmailPasswordRetryAttempts increment login by email and password retry attempts by one
-func (u *userRepository) IncrementLoginByEmailPasswordRetryAttempts(ctx context.Context, email string) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":   utils.DumpIncomingContext(ctx),
-		"email": email,
+// IncrementTransactionPinRetryAttempts increment transaction pin retry attempts by one
+func (u *userRepository) IncrementTransactionPinRetryAttempts(ctx context.Context, id int) error {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"id": id,
 	})
 
-	key := u.newLoginByEmailPasswordAttemptsCacheKeyByEmail(email)
-	if err := u.cacheManager.IncreaseCachedValueByOne(key); err != nil {
+	key := u.newTransactionPinAttemptsCacheKeyByID(id)
+	if err := u.cacheManager.IncreaseCachedValueByOne(ctx, key); err != nil {
 		logger.Error(err)
 		return err
 	}
 
 	// resets the ttl duration everytime the attempts is incremented
-	if err := u.cacheManager.Expire(key, config.LoginByUsernamePasswordLockTTL()); err != nil {
+	if err := u.cacheManager.Expire(ctx, key, config.TransactionPinLockTTL()); err != nil {
 		logger.Error(err)
 		return err
 	}
@@ -173,67 +194,74 @@ func (u *userRepository) IncrementLoginByEmailPasswordRetryAttempts(ctx context.
 	return nil
 }
 
-func (u *userRepository) IsLoginByEmailPasswordLocked(ctx context.Context, email string) (bool, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":   utils.DumpIncomingContext(ctx),
-		"email": email,
+func (u *userRepository) IsTransactionPinLocked(ctx context.Context, id int) (bool, error) {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"id": id,
 	})
 
-	key := u.newLoginByEmailPasswordAttemptsCacheKeyByEmail(email)
-	ttl, err := u.cacheManager.GetTTL(key)
+	key := u.newTransactionPinAttemptsCacheKeyByID(id)
+	ttl, err := u.cacheManager.GetTTL(ctx, key)
 	if err != nil {
 		logger.Error(err)
 		return false, err
 	}
 
-	loginAttempts, mu, err := u.findIntValueFromCacheByKey(key)
-	defer cacher.SafeUnlock(mu)
+	reply, err := u.cacheManager.Get(ctx, key)
 	if err != nil {
 		logger.Error(err)
 		return false, err
 	}
+	attempts := utils.InterfaceBytesToType[int](reply)
 
-	if ttl > int64(0) && loginAttempts >= config.LoginByUsernamePasswordRetryAttempts() {
+	if ttl > int64(0) && attempts >= config.TransactionPinRetryAttempts() {
 		return true, nil
 	}
 
 	return false, nil
 }
-func (u *userRepository) findFromCacheByKey(key string) (reply *model.User, mu *redsync.Mutex, err error) {
-	var rep interface{}
-	rep, mu, err = u.cacheManager.GetOrLock(key)
-	if err != nil || rep == nil {
-		return
+
+// ResetTransactionPinRetryAttempts clear the retry attempts, also unlock a locked pin
+func (u *userRepository) ResetTransactionPinRetryAttempts(ctx context.Context, id int) error {
+	err := u.cacheManager.DeleteByKeys(ctx, []string{u.newTransactionPinAttemptsCacheKeyByID(id)})
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"id": id,
+		}).Error(err)
 	}
 
-	reply = utils.InterfaceBytesToType[*model.User](rep)
-	return
+	return err
 }
 
-func (u *userRepository) findIntValueFromCacheByKey(key string) (reply int, mu *redsync.Mutex, err error) {
-	var rep interface{}
-	rep, mu, err = u.cacheManager.GetOrLock(key)
-	if err != nil || rep == nil {
-		return
+// findIDByColumn the id of the user by an unique column, nil when not found
+func (u *userRepository) findIDByColumn(ctx context.Context, column, value string) (*int, error) {
+	var id int
+	err := u.db.WithContext(ctx).Model(model.User{}).Select("id").Take(&id, column+" = ?", value).Error
+	switch err {
+	case nil:
+		return &id, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		return nil, err
 	}
-
-	reply = utils.InterfaceBytesToType[int](rep)
-	return
 }
 
-func (u *userRepository) findStringValueFromCacheByKey(key string) (reply string, mu *redsync.Mutex, err error) {
-	var rep interface{}
-	rep, mu, err = u.cacheManager.GetOrLock(key)
-	if err != nil || rep == nil {
-		return
+// findStringColumnByID the value of a column of the user, nil when not found
+func (u *userRepository) findStringColumnByID(ctx context.Context, column string, id int) (*string, error) {
+	var value string
+	err := u.db.WithContext(ctx).Model(model.User{}).Select(column).Take(&value, "id = ?", id).Error
+	switch err {
+	case nil:
+		return &value, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		return nil, err
 	}
-
-	reply = utils.InterfaceBytesToType[string](rep)
-	return
 }
 
-func (u *userRepository) newLoginByEmailPasswordAttemptsCacheKeyByEmail(email string) string {
-	return fmt.Sprintf("cache:login_attempts:email_password:user_email:%s", email)
+func (u *userRepository) newTransactionPinAttemptsCacheKeyByID(id int) string {
+	return fmt.Sprintf("cache:transaction_pin_attempts:user_id:%d", id)
 }
 
 func (u *userRepository) newCacheKeyByID(id int) string {
@@ -250,3 +278,7 @@ func (u *userRepository) newUserCacheKeyByUsername(username string) string {
 func (u *userRepository) newPasswordCacheKeyByID(id int) string {
 	return fmt.Sprintf("cache:password:id:%d", id)
 }
+
+func (u *userRepository) newTransactionPinCacheKeyByID(id int) string {
+	return fmt.Sprintf("cache:transaction_pin:id:%d", id)
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(
+	db *gorm.DB,
+) model.WebhookRepository {
+	return &webhookRepository{
+		db: db,
+	}
+}
+
+func (w *webhookRepository) CreateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	err := w.db.WithContext(ctx).Create(subscription).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"subscription": utils.Dump(subscription),
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (w *webhookRepository) FindSubscriptionByID(ctx context.Context, id int) (*model.WebhookSubscription, error) {
+	subscription := &model.WebhookSubscription{}
+	err := w.db.WithContext(ctx).Take(subscription, "id = ?", id).Error
+	switch err {
+	case nil:
+		return subscription, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"id": id,
+		}).Error(err)
+		return nil, err
+	}
+}
+
+func (w *webhookRepository) FindSubscriptionsByUserID(ctx context.Context, userID int) ([]*model.WebhookSubscription, error) {
+	var subscriptions []*model.WebhookSubscription
+	err := w.db.WithContext(ctx).Where("user_id = ?", userID).Order("id asc").Find(&subscriptions).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (w *webhookRepository) FindAppSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	var subscriptions []*model.WebhookSubscription
+	err := w.db.WithContext(ctx).Where("user_id IS NULL").Order("id asc").Find(&subscriptions).Error
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// FindActiveSubscriptionsForUser find the active subscriptions of the user and the app subscriptions
+func (w *webhookRepository) FindActiveSubscriptionsForUser(ctx context.Context, userID int) ([]*model.WebhookSubscription, error) {
+	var subscriptions []*model.WebhookSubscription
+	err := w.db.WithContext(ctx).
+		Where("active = ? AND (user_id = ? OR user_id IS NULL)", true, userID).
+		Order("id asc").
+		Find(&subscriptions).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// DeactivateSubscription deactivate the subscription, it is kept for the delivery log
+func (w *webhookRepository) DeactivateSubscription(ctx context.Context, id int) error {
+	err := w.db.WithContext(ctx).Model(&model.WebhookSubscription{}).Where("id = ?", id).
+		Updates(map[string]any{"active": false, "updated_at": time.Now()}).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"id": id,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (w *webhookRepository) CreateDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	// an event published again by the outbox relay is ignored
+	err := w.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(&deliveries).Error
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (w *webhookRepository) FindDeliveryByID(ctx context.Context, id int) (*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{}
+	err := w.db.WithContext(ctx).Take(delivery, "id = ?", id).Error
+	switch err {
+	case nil:
+		return delivery, nil
+	case gorm.ErrRecordNotFound:
+		return nil, nil
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"id": id,
+		}).Error(err)
+		return nil, err
+	}
+}
+
+func (w *webhookRepository) FindDeliveriesBySubscriptionID(ctx context.Context, subscriptionID, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := w.db.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("id desc").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"subscriptionID": subscriptionID,
+		}).Error(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries lock the pending deliveries which are due and push their next attempt by the lease,
+// so another worker skip them while they are being sent
+func (w *webhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryStatusPending, now).
+			Order("next_attempt_at asc").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]int, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		return tx.Model(&model.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (w *webhookRepository) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	err := w.db.WithContext(ctx).Model(delivery).
+		Select("status", "attempts", "response_status", "last_error", "next_attempt_at", "delivered_at", "updated_at").
+		Updates(delivery).Error
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"id": delivery.ID,
+		}).Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package requestid
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// UnaryServerInterceptor like HTTPMiddleware, accept the `x-request-id` metadata of the caller or generate a new one,
+// set it to the context and send it back in the header metadata.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	key := strings.ToLower(Header)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(key); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		id = resolve(id)
+
+		ctx = NewContext(ctx, id)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(key, id)); err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+
+		return handler(ctx, req)
+	}
+}
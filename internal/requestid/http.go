@@ -0,0 +1,22 @@
+package requestid
+
+import (
+	"github.com/labstack/echo"
+)
+
+// HTTPMiddleware accept the X-Request-ID of the caller or generate a new one,
+// set it to the request context and the response header. Register it with Pre so every response has it.
+func HTTPMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := resolve(req.Header.Get(Header))
+
+			req.Header.Set(Header, id)
+			c.Response().Header().Set(Header, id)
+			c.SetRequest(req.WithContext(NewContext(req.Context(), id)))
+
+			return next(c)
+		}
+	}
+}
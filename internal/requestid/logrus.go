@@ -0,0 +1,27 @@
+package requestid
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// LogrusHook add the request_id of the entry context to the fields.
+// The entry must be created with logrus.WithContext(ctx).
+type LogrusHook struct{}
+
+// Levels :nodoc:
+func (LogrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire :nodoc:
+func (LogrusHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	if id := FromContext(entry.Context); id != "" {
+		entry.Data["request_id"] = id
+	}
+
+	return nil
+}
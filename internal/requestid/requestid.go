@@ -0,0 +1,52 @@
+package requestid
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/labstack/echo"
+)
+
+// Header the http header and grpc metadata key of the request ID
+const Header = echo.HeaderXRequestID
+
+const (
+	generatedLength = 32
+	maxLength       = 128
+)
+
+type contextKey string
+
+// use module path to make it unique
+const requestIDCtxKey contextKey = "github.com/irvankadhafi/user-balance-transfer-service/internal/requestid.RequestID"
+
+// NewContext set the request ID to context
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey, id)
+}
+
+// FromContext get the request ID from context, empty when the context isn't from a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey).(string)
+	return id
+}
+
+// resolve keep the request ID given by the caller, or generate a new one when it's empty or invalid
+func resolve(id string) string {
+	if isValid(id) {
+		return id
+	}
+	return utils.GenerateRandomAlphanumeric(generatedLength)
+}
+
+// isValid only accept a printable ASCII ID, so it can be written back to the headers and the logs as is
+func isValid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
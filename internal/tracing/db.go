@@ -0,0 +1,33 @@
+package tracing
+
+import (
+	"context"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"time"
+)
+
+// RecordDBQuery record the finished query as a client span of the span in the context, a query without a traced caller
+// is not recorded. Only the operation is recorded, the statement has its values inlined.
+func RecordDBQuery(ctx context.Context, begin time.Time, sql string, err error) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return
+	}
+
+	operation := "query"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	_, span := StartSpan(ctx, "db."+strings.ToLower(operation),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(begin),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(operation),
+		),
+	)
+	RecordError(span, err)
+	span.End()
+}
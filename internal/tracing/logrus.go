@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogrusHook add the trace_id and span_id of the entry context to the fields, an error entry is also recorded on the span.
+// The entry must be created with logrus.WithContext(ctx).
+type LogrusHook struct{}
+
+// Levels :nodoc:
+func (LogrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire :nodoc:
+func (LogrusHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	span := trace.SpanFromContext(entry.Context)
+	spanContext := span.SpanContext()
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	entry.Data["trace_id"] = spanContext.TraceID().String()
+	entry.Data["span_id"] = spanContext.SpanID().String()
+
+	if entry.Level <= logrus.ErrorLevel && span.IsRecording() {
+		span.AddEvent(entry.Message, trace.WithAttributes(attribute.String("log.severity", entry.Level.String())))
+		span.SetStatus(codes.Error, entry.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+)
+
+// tracing exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+const instrumentationName = "github.com/irvankadhafi/user-balance-transfer-service"
+
+// Init set the global tracer provider with the configured exporter and the W3C trace context propagator.
+// The propagator is always set, so the trace context of the callers is kept even when the spans are not exported.
+// The returned func flush the pending spans and must be called before exiting.
+func Init() (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var opts []stdouttrace.Option
+	closeFile := func() error { return nil }
+	switch config.TracingExporter() {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		opts = append(opts, stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		file, err := os.OpenFile(config.TracingFile(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, stdouttrace.WithWriter(file))
+		closeFile = file.Close
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter: %s", config.TracingExporter())
+	}
+
+	exporter, err := stdouttrace.New(opts...)
+	if err != nil {
+		_ = closeFile()
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(config.TracingServiceName()),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		if err := provider.Shutdown(ctx); err != nil {
+			_ = closeFile()
+			return err
+		}
+		return closeFile()
+	}, nil
+}
+
+// StartSpan start a span as the child of the span in the context, e.g. StartSpan(ctx, "userBalanceUsecase.TransferUserBalance")
+func StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError mark the span as failed with the error
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// authEventLogLimit maximum events returned by the security events of a user
+const authEventLogLimit = 100
+
+// auth event reasons
+const (
+	authEventReasonLocked              = "too many failed logins"
+	authEventReasonUnknownEmail        = "unknown email"
+	authEventReasonWrongPassword       = "wrong password"
+	authEventReasonTwoFactorRequired   = "two factor required"
+	authEventReasonInvalidCode         = "invalid two factor code"
+	authEventReasonRefreshTokenExpired = "refresh token expired"
+	authEventReasonInvalidRefreshToken = "invalid refresh token"
+)
+
+// recordAuthEvent write the event to the audit log, userID zero means the event has no user.
+// A failure is only logged, the audit log never block the authentication.
+func (a *authUsecase) recordAuthEvent(ctx context.Context, userID int, event model.AuthEvent) {
+	if userID > 0 {
+		event.UserID = &userID
+	}
+
+	if err := a.authEventRepo.Create(ctx, &event); err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID":    userID,
+			"eventType": event.EventType,
+		}).Error(err)
+	}
+}
+
+// recordLoginFailure record the failed login, and the lockout when the failure locked the account or the IP
+func (a *authUsecase) recordLoginFailure(ctx context.Context, userID int, event model.AuthEvent, locked bool) {
+	event.Result = model.AuthEventResultFailure
+	a.recordAuthEvent(ctx, userID, event)
+
+	if locked {
+		event.EventType = model.AuthEventLockout
+		event.Reason = authEventReasonLocked
+		a.recordAuthEvent(ctx, userID, event)
+	}
+}
+
+// FindSecurityEvents return the latest auth events of the user
+func (a *authUsecase) FindSecurityEvents(ctx context.Context, userID int) ([]*model.AuthEvent, error) {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.FindSecurityEvents")
+	defer span.End()
+
+	return a.authEventRepo.FindByUserID(ctx, userID, authEventLogLimit)
+}
+
+// PruneAuthEvents delete one batch of the auth events older than the retention and return how many were deleted
+func (a *authUsecase) PruneAuthEvents(ctx context.Context) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.PruneAuthEvents")
+	defer span.End()
+
+	before := a.now().Add(-config.AuthEventRetention())
+	count, err := a.authEventRepo.DeleteCreatedBefore(ctx, before, config.AuthEventPruneBatchSize())
+	if err != nil {
+		logrus.WithContext(ctx).WithField("before", before).Error(err)
+		return 0, err
+	}
+
+	return count, nil
+}
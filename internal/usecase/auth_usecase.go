@@ -6,15 +6,22 @@ import (
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
 	"github.com/irvankadhafi/user-balance-transfer-service/utils"
 	"github.com/sirupsen/logrus"
 	"time"
 )
 
 type authUsecase struct {
-	userUsecase model.UserUsecase
-	userRepo    model.UserRepository
-	sessionRepo model.SessionRepository
+	userUsecase   model.UserUsecase
+	userRepo      model.UserRepository
+	sessionRepo   model.SessionRepository
+	twoFactorRepo model.TwoFactorRepository
+	authEventRepo model.AuthEventRepository
+	loginLockout  *loginLockout
+
+	// now is the clock used to validate the TOTP code, replaced with a fixed clock in tests
+	now func() time.Time
 }
 
 // NewAuthUsecase :nodoc:
@@ -22,88 +29,165 @@ func NewAuthUsecase(
 	userRepo model.UserRepository,
 	sessionRepo model.SessionRepository,
 	userUsecase model.UserUsecase,
+	twoFactorRepo model.TwoFactorRepository,
+	loginLockoutRepo model.LoginLockoutRepository,
+	authEventRepo model.AuthEventRepository,
 ) model.AuthUsecase {
 	return &authUsecase{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
-		userUsecase: userUsecase,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		userUsecase:   userUsecase,
+		twoFactorRepo: twoFactorRepo,
+		authEventRepo: authEventRepo,
+		loginLockout:  &loginLockout{repo: loginLockoutRepo, now: time.Now},
+		now:           time.Now,
 	}
 }
 
-// LoginByEmailPassword login the user by email & password
-func (a *authUsecase) LoginByEmailPassword(ctx context.Context, req model.LoginRequest) (*model.Session, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":       utils.DumpIncomingContext(ctx),
+// LoginByEmailPassword login the user by email & password.
+// When the user enabled 2FA, a challenge is returned instead of the session.
+func (a *authUsecase) LoginByEmailPassword(ctx context.Context, req model.LoginRequest) (*model.Session, *model.LoginChallenge, error) {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.LoginByEmailPassword")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"email":     req.Email,
 		"ip":        req.IPAddress,
 		"userAgent": req.UserAgent,
 	})
 
-	isLocked, err := a.userRepo.IsLoginByEmailPasswordLocked(ctx, req.Email)
-	if err != nil {
-		logger.Error(err)
-		return nil, err
+	event := model.AuthEvent{
+		EventType: model.AuthEventLogin,
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
 	}
 
-	if isLocked {
-		return nil, ErrLoginMaxAttempts
+	// the IP is checked first, so an IP spraying many accounts can't learn which ones exist
+	if err := a.loginLockout.check(ctx, "", req.IPAddress); err != nil {
+		logger.Error(err)
+		if err == ErrLoginMaxAttempts {
+			event.Reason = authEventReasonLocked
+			a.recordLoginFailure(ctx, 0, event, false)
+		}
+		return nil, nil, err
 	}
 
 	user, err := a.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		logger.Error(err)
-		return nil, err
+		return nil, nil, err
 	}
 	if user == nil {
-		return nil, ErrNotFound
+		locked, err := a.loginLockout.recordFailure(ctx, "", req.IPAddress)
+		if err != nil {
+			logger.Error(err)
+			return nil, nil, err
+		}
+		event.Reason = authEventReasonUnknownEmail
+		a.recordLoginFailure(ctx, 0, event, locked)
+		return nil, nil, ErrEmailPasswordNotMatch
+	}
+
+	if err := a.loginLockout.check(ctx, user.Email, ""); err != nil {
+		logger.Error(err)
+		if err == ErrLoginMaxAttempts {
+			event.Reason = authEventReasonLocked
+			a.recordLoginFailure(ctx, user.ID, event, false)
+		}
+		return nil, nil, err
 	}
 
 	cipherPass, err := a.userRepo.FindPasswordByID(ctx, user.ID)
 	if err != nil {
 		logger.Error(err)
-		return nil, err
+		return nil, nil, err
 	}
 	if cipherPass == nil {
 		logger.Error(err)
-		return nil, errors.New("unexpected: no password found")
+		return nil, nil, errors.New("unexpected: no password found")
 	}
 
 	if !helper.IsHashedStringMatch([]byte(req.PlainPassword), cipherPass) {
 		// obscure the error if the password does not match
-		if err := a.userRepo.IncrementLoginByEmailPasswordRetryAttempts(ctx, req.Email); err != nil {
+		locked, err := a.loginLockout.recordFailure(ctx, user.Email, req.IPAddress)
+		if err != nil {
 			logger.Error(err)
-			return nil, err
+			return nil, nil, err
 		}
+		event.Reason = authEventReasonWrongPassword
+		a.recordLoginFailure(ctx, user.ID, event, locked)
+
+		return nil, nil, ErrEmailPasswordNotMatch
+	}
 
-		return nil, ErrUnauthorized
+	if err := a.loginLockout.reset(ctx, user.Email); err != nil {
+		logger.Error(err)
+		return nil, nil, err
 	}
 
 	logger = logger.WithField("userID", user.ID)
-	accessToken, err := generateToken(a.sessionRepo, user.ID)
+	twoFactor, err := a.twoFactorRepo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		logger.Error(err)
+		return nil, nil, err
+	}
+	if twoFactor.IsEnabled() {
+		challenge := &model.LoginChallenge{
+			Token:     utils.GenerateRandomAlphanumeric(config.DefaultSessionTokenLength),
+			UserID:    user.ID,
+			Email:     user.Email,
+			IPAddress: req.IPAddress,
+			UserAgent: req.UserAgent,
+			ExpiredAt: time.Now().Add(config.LoginChallengeTTL()),
+		}
+		if err = a.twoFactorRepo.CreateLoginChallenge(ctx, challenge); err != nil {
+			logger.Error(err)
+			return nil, nil, err
+		}
+
+		// the login is completed by a TWO_FACTOR_LOGIN event
+		event.Result = model.AuthEventResultSuccess
+		event.Reason = authEventReasonTwoFactorRequired
+		a.recordAuthEvent(ctx, user.ID, event)
+		return nil, challenge, nil
+	}
+
+	session, err := a.createSession(ctx, user.ID, req.IPAddress, req.UserAgent)
 	if err != nil {
 		logger.Error(err)
+		return nil, nil, err
+	}
+
+	event.Result = model.AuthEventResultSuccess
+	a.recordAuthEvent(ctx, user.ID, event)
+	return session, nil, nil
+}
+
+// createSession create a new session with a fresh access and refresh token
+func (a *authUsecase) createSession(ctx context.Context, userID int, ipAddress, userAgent string) (*model.Session, error) {
+	accessToken, err := generateToken(a.sessionRepo, userID)
+	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := generateToken(a.sessionRepo, user.ID)
+	refreshToken, err := generateToken(a.sessionRepo, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	now := time.Now()
 	session := &model.Session{
-		UserID:                user.ID,
+		UserID:                userID,
 		AccessToken:           accessToken,
 		RefreshToken:          refreshToken,
 		AccessTokenExpiredAt:  now.Add(config.AccessTokenDuration()),
 		RefreshTokenExpiredAt: now.Add(config.RefreshTokenDuration()),
-		IPAddress:             req.IPAddress,
-		UserAgent:             req.UserAgent,
+		IPAddress:             ipAddress,
+		UserAgent:             userAgent,
 		Location:              "-",
 	}
 
 	if err = a.sessionRepo.Create(ctx, session); err != nil {
-		logger.Error(err)
 		return nil, err
 	}
 	return session, nil
@@ -111,6 +195,9 @@ func (a *authUsecase) LoginByEmailPassword(ctx context.Context, req model.LoginR
 
 // AuthenticateToken authenticate the given access token and return the corresponding user
 func (a *authUsecase) AuthenticateToken(ctx context.Context, accessToken string) (*model.User, error) {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.AuthenticateToken")
+	defer span.End()
+
 	session, err := a.sessionRepo.FindByToken(ctx, model.AccessToken, accessToken)
 	if err != nil {
 		logrus.Error(err)
@@ -140,19 +227,31 @@ func (a *authUsecase) AuthenticateToken(ctx context.Context, accessToken string)
 
 // RefreshToken refresh the user's access and refresh token
 func (a *authUsecase) RefreshToken(ctx context.Context, req model.RefreshTokenRequest) (*model.Session, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":                 utils.DumpIncomingContext(ctx),
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.RefreshToken")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"refreshTokenRequest": utils.Dump(req),
 	})
 
+	event := model.AuthEvent{
+		EventType: model.AuthEventTokenRefresh,
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+		Result:    model.AuthEventResultSuccess,
+	}
+
 	session, err := a.sessionRepo.FindByToken(ctx, model.RefreshToken, req.RefreshToken)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
 	if session == nil {
-		logger.Error(ErrNotFound)
-		return nil, ErrNotFound
+		logger.Error(ErrInvalidRefreshToken)
+		event.Result = model.AuthEventResultFailure
+		event.Reason = authEventReasonInvalidRefreshToken
+		a.recordAuthEvent(ctx, 0, event)
+		return nil, ErrInvalidRefreshToken
 	}
 
 	user, err := a.userRepo.FindByID(ctx, session.UserID)
@@ -161,8 +260,12 @@ func (a *authUsecase) RefreshToken(ctx context.Context, req model.RefreshTokenRe
 		logger.WithField("userID", session.UserID).Error(err)
 		return nil, err
 	case user == nil:
-		logger.WithField("userID", session.UserID).Error(ErrNotFound)
-		return nil, ErrNotFound
+		// the session of a deleted user can't be refreshed
+		logger.WithField("userID", session.UserID).Error(ErrInvalidRefreshToken)
+		event.Result = model.AuthEventResultFailure
+		event.Reason = authEventReasonInvalidRefreshToken
+		a.recordAuthEvent(ctx, 0, event)
+		return nil, ErrInvalidRefreshToken
 	}
 
 	// old session is used to delete the old session cache
@@ -170,6 +273,9 @@ func (a *authUsecase) RefreshToken(ctx context.Context, req model.RefreshTokenRe
 
 	if session.RefreshTokenExpiredAt.Before(time.Now()) {
 		logger.Error(ErrRefreshTokenExpired)
+		event.Result = model.AuthEventResultFailure
+		event.Reason = authEventReasonRefreshTokenExpired
+		a.recordAuthEvent(ctx, session.UserID, event)
 		return nil, ErrRefreshTokenExpired
 	}
 
@@ -200,13 +306,16 @@ func (a *authUsecase) RefreshToken(ctx context.Context, req model.RefreshTokenRe
 		return nil, err
 	}
 
+	a.recordAuthEvent(ctx, session.UserID, event)
 	return session, nil
 }
 
 // DeleteSessionByID deletes session by id.
 func (a *authUsecase) DeleteSessionByID(ctx context.Context, sessionID int) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":       utils.DumpIncomingContext(ctx),
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.DeleteSessionByID")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"sessionID": utils.Dump(sessionID),
 	})
 
@@ -223,7 +332,15 @@ func (a *authUsecase) DeleteSessionByID(ctx context.Context, sessionID int) erro
 	err = a.sessionRepo.Delete(ctx, session)
 	if err != nil {
 		logger.Error(err)
+		return err
 	}
 
-	return err
+	// the logout is recorded with the device of the deleted session
+	a.recordAuthEvent(ctx, session.UserID, model.AuthEvent{
+		EventType: model.AuthEventLogout,
+		IPAddress: session.IPAddress,
+		UserAgent: session.UserAgent,
+		Result:    model.AuthEventResultSuccess,
+	})
+	return nil
 }
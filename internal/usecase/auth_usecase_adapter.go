@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/irvankadhafi/user-balance-transfer-service/auth"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -24,19 +25,22 @@ func NewUserAutherAdapter(authUsecase model.AuthUsecase) *UserAutherAdapter {
 // AuthenticateToken authenticate access token
 func (a *UserAutherAdapter) AuthenticateToken(ctx context.Context, accessToken string) (*auth.User, error) {
 	user, err := a.authUsecase.AuthenticateToken(ctx, accessToken)
-	if errors.Is(err, ErrNotFound) {
-		return nil, status.Error(codes.NotFound, err.Error())
+	if err != nil {
+		return nil, toGRPCError(err)
 	}
 
-	if errors.Is(err, ErrAccessTokenExpired) {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
-	}
+	return newAuthUser(user), nil
+}
 
-	if err != nil {
-		return nil, err
+// toGRPCError convert the domain error to a grpc status error with its code, so the caller can switch on status.Code.
+// An error which is not a domain error is returned as internal.
+func toGRPCError(err error) error {
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		return status.Error(appErr.Code.GRPCCode(), appErr.Message)
 	}
 
-	return newAuthUser(user), nil
+	return status.Error(codes.Internal, err.Error())
 }
 
 func newAuthUser(user *model.User) *auth.User {
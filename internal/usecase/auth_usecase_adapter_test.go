@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestToGRPCError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{name: "invalid argument", err: apperr.New(apperr.CodeInvalidArgument, "invalid argument"), code: codes.InvalidArgument, message: "invalid argument"},
+		{name: "not found", err: ErrNotFound, code: codes.NotFound, message: ErrNotFound.Message},
+		{name: "already exists", err: apperr.New(apperr.CodeAlreadyExists, "email already registered"), code: codes.AlreadyExists, message: "email already registered"},
+		{name: "unauthenticated", err: ErrAccessTokenExpired, code: codes.Unauthenticated, message: ErrAccessTokenExpired.Message},
+		{name: "permission denied", err: ErrPermissionDenied, code: codes.PermissionDenied, message: ErrPermissionDenied.Message},
+		{name: "failed precondition", err: ErrFailedPrecondition, code: codes.FailedPrecondition, message: ErrFailedPrecondition.Message},
+		{name: "conflict", err: apperr.New(apperr.CodeConflict, "transfer is not pending review"), code: codes.Aborted, message: "transfer is not pending review"},
+		{name: "unprocessable", err: ErrBalanceNotEnough, code: codes.FailedPrecondition, message: ErrBalanceNotEnough.Message},
+		{name: "locked", err: apperr.New(apperr.CodeLocked, "login locked"), code: codes.ResourceExhausted, message: "login locked"},
+		{name: "too many requests", err: apperr.New(apperr.CodeTooManyRequests, "too many requests"), code: codes.ResourceExhausted, message: "too many requests"},
+		{name: "internal", err: apperr.New(apperr.CodeInternal, "internal system error"), code: codes.Internal, message: "internal system error"},
+		{name: "wrapped domain error", err: fmt.Errorf("authenticate: %w", ErrAccessTokenExpired), code: codes.Unauthenticated, message: ErrAccessTokenExpired.Message},
+		{name: "unknown error", err: errors.New("redis: connection refused"), code: codes.Internal, message: "redis: connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := status.FromError(toGRPCError(tt.err))
+			assert.True(t, ok)
+			assert.Equal(t, tt.code, st.Code())
+			assert.Equal(t, tt.message, st.Message())
+		})
+	}
+}
+
+// TestUserAutherAdapter_AuthenticateToken the codes the auth middlewares map to unauthenticated
+func TestUserAutherAdapter_AuthenticateToken(t *testing.T) {
+	usecase, _ := newRefreshTokenUsecase(t, time.Now().Add(time.Hour))
+	adapter := NewUserAutherAdapter(usecase)
+
+	tests := []struct {
+		name  string
+		token string
+		code  codes.Code
+	}{
+		{name: "unknown", token: "unknown", code: codes.NotFound},
+		{name: "expired", token: "access-token", code: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := adapter.AuthenticateToken(context.Background(), tt.token)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	session, err := usecase.RefreshToken(context.Background(), model.RefreshTokenRequest{RefreshToken: "refresh-token"})
+	require.NoError(t, err)
+	user, err := adapter.AuthenticateToken(context.Background(), session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// newRefreshTokenUsecase an auth usecase with one session of the user, its refresh token expire at refreshTokenExpiredAt
+func newRefreshTokenUsecase(t *testing.T, refreshTokenExpiredAt time.Time) (*authUsecase, *fakeAuthEventRepository) {
+	t.Helper()
+
+	sessionRepo := &fakeSessionRepository{}
+	require.NoError(t, sessionRepo.Create(context.Background(), &model.Session{
+		UserID:                1,
+		AccessToken:           "access-token",
+		RefreshToken:          "refresh-token",
+		AccessTokenExpiredAt:  time.Now(),
+		RefreshTokenExpiredAt: refreshTokenExpiredAt,
+	}))
+
+	authEventRepo := &fakeAuthEventRepository{}
+	usecase := NewAuthUsecase(
+		newFakeUserRepository(&model.User{ID: 1, Email: "johndoe@mail.com"}),
+		sessionRepo,
+		nil,
+		newFakeTwoFactorRepository(),
+		repository.NewLoginLockoutRepository(cacher.NewMemoryCacheManager()),
+		authEventRepo,
+	).(*authUsecase)
+	return usecase, authEventRepo
+}
+
+func TestAuthUsecase_RefreshToken(t *testing.T) {
+	usecase, authEventRepo := newRefreshTokenUsecase(t, time.Now().Add(time.Hour))
+
+	session, err := usecase.RefreshToken(context.Background(), model.RefreshTokenRequest{RefreshToken: "refresh-token", IPAddress: "203.0.113.7"})
+	require.NoError(t, err)
+	assert.NotEqual(t, "access-token", session.AccessToken)
+	assert.NotEqual(t, "refresh-token", session.RefreshToken)
+	assert.Equal(t, "203.0.113.7", session.IPAddress)
+	assert.Equal(t, []string{"TOKEN_REFRESH:SUCCESS"}, authEventRepo.eventTypes())
+
+	// the old refresh token is rotated
+	_, err = usecase.RefreshToken(context.Background(), model.RefreshTokenRequest{RefreshToken: "refresh-token"})
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func TestAuthUsecase_RefreshToken_Invalid(t *testing.T) {
+	usecase, authEventRepo := newRefreshTokenUsecase(t, time.Now().Add(time.Hour))
+
+	_, err := usecase.RefreshToken(context.Background(), model.RefreshTokenRequest{RefreshToken: "unknown"})
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+	assert.Equal(t, []string{"TOKEN_REFRESH:FAILURE"}, authEventRepo.eventTypes())
+	assert.Equal(t, authEventReasonInvalidRefreshToken, authEventRepo.events[0].Reason)
+	assert.Nil(t, authEventRepo.events[0].UserID)
+}
+
+func TestAuthUsecase_RefreshToken_Expired(t *testing.T) {
+	usecase, authEventRepo := newRefreshTokenUsecase(t, time.Now().Add(-time.Minute))
+
+	_, err := usecase.RefreshToken(context.Background(), model.RefreshTokenRequest{RefreshToken: "refresh-token"})
+	assert.Equal(t, ErrRefreshTokenExpired, err)
+	assert.Equal(t, []string{"TOKEN_REFRESH:FAILURE"}, authEventRepo.eventTypes())
+	assert.Equal(t, authEventReasonRefreshTokenExpired, authEventRepo.events[0].Reason)
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// SubscribeBalanceUpdates return the balance updates of the user until the context is done
+func (u *userBalanceUsecase) SubscribeBalanceUpdates(ctx context.Context, userID int) (<-chan *model.BalanceUpdate, error) {
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.SubscribeBalanceUpdates")
+	defer span.End()
+
+	if userID <= 0 {
+		return nil, ErrFailedPrecondition
+	}
+
+	updates, err := u.balanceBroker.Subscribe(ctx, userID)
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": userID,
+		}).Error(err)
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+// publishBalanceUpdates publish the updates of a committed change. The stream is only a notification,
+// a failure is logged and the client resync the balance when it reconnect.
+func (u *userBalanceUsecase) publishBalanceUpdates(ctx context.Context, updates ...*model.BalanceUpdate) {
+	if err := u.balanceBroker.Publish(ctx, updates...); err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"updates": utils.Dump(updates),
+		}).Error(err)
+	}
+}
+
+// newBalanceUpdate copy the balance, a balance of a batch transfer keep changing after the update is built
+func newBalanceUpdate(userID int, balance *model.UserBalance, history *model.UserBalanceHistory) *model.BalanceUpdate {
+	snapshot := *balance
+	return &model.BalanceUpdate{
+		UserID:  userID,
+		Balance: &snapshot,
+		History: history,
+	}
+}
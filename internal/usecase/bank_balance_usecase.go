@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/repository"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
 	"github.com/irvankadhafi/user-balance-transfer-service/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -30,14 +31,20 @@ func NewBankBalanceUsecase(
 	}
 }
 
-func (b *bankBalanceUsecase) CreateBankAccount(ctx context.Context, sessionID int, code string) error {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":  utils.DumpIncomingContext(ctx),
-		"code": code,
+func (b *bankBalanceUsecase) CreateBankAccount(ctx context.Context, input model.CreateBankAccountInput) error {
+	ctx, span := tracing.StartSpan(ctx, "bankBalanceUsecase.CreateBankAccount")
+	defer span.End()
+
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"input": utils.Dump(input),
 	})
 
 	// Get IP, UserAgent, etc..
-	session, err := b.sessionRepo.FindByID(ctx, sessionID)
+	session, err := b.sessionRepo.FindByID(ctx, input.SessionID)
 	if err != nil {
 		logger.Error(err)
 		return err
@@ -47,7 +54,7 @@ func (b *bankBalanceUsecase) CreateBankAccount(ctx context.Context, sessionID in
 	bankBalance := &model.BankBalance{
 		Balance:        0,
 		BalanceAchieve: 0,
-		Code:           code,
+		Code:           input.Code,
 		Enable:         true,
 	}
 	err = b.bankBalanceRepo.CreateWithTransaction(ctx, tx, bankBalance)
@@ -84,12 +91,14 @@ func (b *bankBalanceUsecase) CreateBankAccount(ctx context.Context, sessionID in
 }
 
 func (b *bankBalanceUsecase) AddBankBalance(ctx context.Context, input model.AddBankBalanceInput) error {
-	if input.Code == "" || input.Balance <= 0 {
-		return ErrFailedPrecondition
+	ctx, span := tracing.StartSpan(ctx, "bankBalanceUsecase.AddBankBalance")
+	defer span.End()
+
+	if err := input.Validate(); err != nil {
+		return err
 	}
 
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":   utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"input": utils.Dump(input),
 	})
 
@@ -146,11 +155,17 @@ func (b *bankBalanceUsecase) AddBankBalance(ctx context.Context, input model.Add
 }
 
 func (b *bankBalanceUsecase) GetBankBalanceByID(ctx context.Context, bankBalanceID int) (*model.BankBalance, error) {
+	ctx, span := tracing.StartSpan(ctx, "bankBalanceUsecase.GetBankBalanceByID")
+	defer span.End()
+
 	//TODO implement me
 	panic("implement me")
 }
 
 func (b *bankBalanceUsecase) TransferUserBalance(ctx context.Context, userIDFrom, userIDTo int, balance float64, code string) error {
+	ctx, span := tracing.StartSpan(ctx, "bankBalanceUsecase.TransferUserBalance")
+	defer span.End()
+
 	//TODO implement me
 	panic("implement me")
 }
@@ -1,15 +1,47 @@
 package usecase
 
-import "errors"
+import "github.com/irvankadhafi/user-balance-transfer-service/internal/apperr"
 
 // errors ..
 var (
-	ErrNotFound            = errors.New("not found")
-	ErrAccessTokenExpired  = errors.New("access token expired")
-	ErrFailedPrecondition  = errors.New("precondition failed")
-	ErrDuplicateUser       = errors.New("user already exist")
-	ErrLoginMaxAttempts    = errors.New("user is locked from logging")
-	ErrUnauthorized        = errors.New("unauthorized")
-	ErrRefreshTokenExpired = errors.New("refresh token expired")
-	ErrBalanceNotEnough    = errors.New("balance not enough")
+	ErrNotFound            = apperr.New(apperr.CodeNotFound, "not found")
+	ErrAccessTokenExpired  = apperr.New(apperr.CodeUnauthenticated, "access token expired")
+	ErrFailedPrecondition  = apperr.New(apperr.CodeFailedPrecondition, "precondition failed")
+	ErrDuplicateUser       = apperr.New(apperr.CodeAlreadyExists, "user already exist")
+	ErrLoginMaxAttempts    = apperr.New(apperr.CodeLocked, "user is locked from logging in, try again later")
+	ErrUnauthorized        = apperr.New(apperr.CodeUnauthenticated, "unauthorized")
+	ErrRefreshTokenExpired = apperr.New(apperr.CodeUnauthenticated, "refresh token expired")
+	ErrInvalidRefreshToken = apperr.New(apperr.CodeUnauthenticated, "refresh token is invalid")
+	ErrBalanceNotEnough    = apperr.New(apperr.CodeUnprocessable, "balance not enough")
+	ErrTransferBatchLimit  = apperr.New(apperr.CodeInvalidArgument, "transfer batch exceed the maximum items")
+	ErrTransferBatchFailed = apperr.New(apperr.CodeUnprocessable, "transfer batch aborted")
+	ErrDuplicateReference  = apperr.New(apperr.CodeAlreadyExists, "reference already imported")
+
+	// ErrEmailPasswordNotMatch an unknown email and a wrong password are the same error, so the emails can't be enumerated
+	ErrEmailPasswordNotMatch  = apperr.New(apperr.CodeUnauthenticated, "email or password not match")
+	ErrInvalidStatementPeriod = apperr.New(apperr.CodeInvalidArgument, "statement period must end after it starts")
+
+	ErrTransferAmountBelowMinimum   = apperr.New(apperr.CodeUnprocessable, "transfer amount is below the minimum")
+	ErrTransferAmountAboveMaximum   = apperr.New(apperr.CodeUnprocessable, "transfer amount is above the maximum")
+	ErrTransferDailyLimitExceeded   = apperr.New(apperr.CodeUnprocessable, "transfer daily limit exceeded")
+	ErrTransferMonthlyLimitExceeded = apperr.New(apperr.CodeUnprocessable, "transfer monthly limit exceeded")
+	ErrTransferHourlyCountExceeded  = apperr.New(apperr.CodeUnprocessable, "transfer hourly count exceeded")
+
+	ErrTransferDenied     = apperr.New(apperr.CodePermissionDenied, "transfer denied by screening")
+	ErrPermissionDenied   = apperr.New(apperr.CodePermissionDenied, "permission denied")
+	ErrTransferNotPending = apperr.New(apperr.CodeConflict, "transfer is not pending review")
+
+	ErrTransactionPinRequired    = apperr.New(apperr.CodePermissionDenied, "transaction pin required")
+	ErrTransactionPinNotSet      = apperr.New(apperr.CodeFailedPrecondition, "transaction pin is not set")
+	ErrTransactionPinAlreadySet  = apperr.New(apperr.CodeConflict, "transaction pin already set")
+	ErrInvalidTransactionPin     = apperr.New(apperr.CodePermissionDenied, "invalid transaction pin")
+	ErrTransactionPinMaxAttempts = apperr.New(apperr.CodeLocked, "transaction pin is locked")
+
+	ErrTwoFactorAlreadyEnabled = apperr.New(apperr.CodeConflict, "two factor authentication already enabled")
+	ErrTwoFactorNotEnrolled    = apperr.New(apperr.CodeFailedPrecondition, "two factor authentication is not enrolled")
+	ErrInvalidTwoFactorCode    = apperr.New(apperr.CodeUnauthenticated, "invalid two factor code")
+	ErrLoginChallengeExpired   = apperr.New(apperr.CodeUnauthenticated, "login challenge is expired, please login again")
+
+	ErrInvalidWebhookEventType = apperr.New(apperr.CodeInvalidArgument, "invalid webhook event type")
+	ErrInvalidWebhookURL       = apperr.New(apperr.CodeInvalidArgument, "webhook url must be https and resolve to a public address")
 )
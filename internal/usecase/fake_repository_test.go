@@ -0,0 +1,769 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// the fakes embed their interface, a method which is not faked panic when it's called
+
+type fakeUserRepository struct {
+	model.UserRepository
+	users map[int]*model.User
+}
+
+func newFakeUserRepository(users ...*model.User) *fakeUserRepository {
+	r := &fakeUserRepository{users: make(map[int]*model.User)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *fakeUserRepository) FindByID(_ context.Context, id int) (*model.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepository) FindByEmail(_ context.Context, email string) (*model.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+type fakeSessionRepository struct {
+	model.SessionRepository
+	mu       sync.Mutex
+	sessions []*model.Session
+}
+
+func (r *fakeSessionRepository) Create(_ context.Context, sess *model.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sess.ID = len(r.sessions) + 1
+	r.sessions = append(r.sessions, sess)
+	return nil
+}
+
+func (r *fakeSessionRepository) FindByID(_ context.Context, id int) (*model.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sess := range r.sessions {
+		if sess.ID == id {
+			copied := *sess
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSessionRepository) CheckToken(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func (r *fakeSessionRepository) FindByToken(_ context.Context, tokenType model.TokenType, token string) (*model.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sess := range r.sessions {
+		if (tokenType == model.RefreshToken && sess.RefreshToken == token) || (tokenType != model.RefreshToken && sess.AccessToken == token) {
+			copied := *sess
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSessionRepository) RefreshToken(_ context.Context, oldSess, sess *model.Session) (*model.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, stored := range r.sessions {
+		if stored.ID == oldSess.ID {
+			r.sessions[i] = sess
+		}
+	}
+	return sess, nil
+}
+
+type fakeTwoFactorRepository struct {
+	model.TwoFactorRepository
+	twoFactors    map[int]*model.UserTwoFactor
+	recoveryCodes map[string]*model.UserRecoveryCode
+	challenges    map[string]*model.LoginChallenge
+}
+
+func newFakeTwoFactorRepository() *fakeTwoFactorRepository {
+	return &fakeTwoFactorRepository{
+		twoFactors:    make(map[int]*model.UserTwoFactor),
+		recoveryCodes: make(map[string]*model.UserRecoveryCode),
+		challenges:    make(map[string]*model.LoginChallenge),
+	}
+}
+
+func (r *fakeTwoFactorRepository) FindByUserID(_ context.Context, userID int) (*model.UserTwoFactor, error) {
+	twoFactor, ok := r.twoFactors[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *twoFactor
+	return &copied, nil
+}
+
+func (r *fakeTwoFactorRepository) Upsert(_ context.Context, twoFactor *model.UserTwoFactor) error {
+	copied := *twoFactor
+	r.twoFactors[twoFactor.UserID] = &copied
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) Enable(_ context.Context, twoFactor *model.UserTwoFactor, codeHashes []string) error {
+	copied := *twoFactor
+	r.twoFactors[twoFactor.UserID] = &copied
+	for _, hash := range codeHashes {
+		r.recoveryCodes[hash] = &model.UserRecoveryCode{UserID: twoFactor.UserID, CodeHash: hash}
+	}
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) DeleteByUserID(_ context.Context, userID int) error {
+	delete(r.twoFactors, userID)
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) UseTimeStep(_ context.Context, userID int, step int64) (bool, error) {
+	twoFactor, ok := r.twoFactors[userID]
+	if !ok || twoFactor.LastUsedStep >= step {
+		return false, nil
+	}
+	twoFactor.LastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepository) UseRecoveryCode(_ context.Context, userID int, codeHash string, usedAt time.Time) (bool, error) {
+	code, ok := r.recoveryCodes[codeHash]
+	if !ok || code.UserID != userID || code.UsedAt != nil {
+		return false, nil
+	}
+	code.UsedAt = &usedAt
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepository) CreateLoginChallenge(_ context.Context, challenge *model.LoginChallenge) error {
+	copied := *challenge
+	r.challenges[challenge.Token] = &copied
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) FindLoginChallengeByToken(_ context.Context, token string) (*model.LoginChallenge, error) {
+	challenge, ok := r.challenges[token]
+	if !ok {
+		return nil, nil
+	}
+	copied := *challenge
+	return &copied, nil
+}
+
+func (r *fakeTwoFactorRepository) DeleteLoginChallengeByToken(_ context.Context, token string) error {
+	delete(r.challenges, token)
+	return nil
+}
+
+type fakeAuthEventRepository struct {
+	model.AuthEventRepository
+	mu     sync.Mutex
+	events []*model.AuthEvent
+}
+
+func (r *fakeAuthEventRepository) Create(_ context.Context, event *model.AuthEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+	return nil
+}
+
+// eventTypes the type and result of every recorded event, in order
+func (r *fakeAuthEventRepository) eventTypes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var types []string
+	for _, event := range r.events {
+		types = append(types, string(event.EventType)+":"+string(event.Result))
+	}
+	return types
+}
+
+type fakeWebhookRepository struct {
+	model.WebhookRepository
+	subscriptions map[int]*model.WebhookSubscription
+	deliveries    map[int]*model.WebhookDelivery
+}
+
+func newFakeWebhookRepository(subscriptions ...*model.WebhookSubscription) *fakeWebhookRepository {
+	r := &fakeWebhookRepository{
+		subscriptions: make(map[int]*model.WebhookSubscription),
+		deliveries:    make(map[int]*model.WebhookDelivery),
+	}
+	for _, subscription := range subscriptions {
+		r.subscriptions[subscription.ID] = subscription
+	}
+	return r
+}
+
+func (r *fakeWebhookRepository) CreateSubscription(_ context.Context, subscription *model.WebhookSubscription) error {
+	subscription.ID = len(r.subscriptions) + 1
+	copied := *subscription
+	r.subscriptions[subscription.ID] = &copied
+	return nil
+}
+
+func (r *fakeWebhookRepository) FindSubscriptionByID(_ context.Context, id int) (*model.WebhookSubscription, error) {
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *subscription
+	return &copied, nil
+}
+
+// FindActiveSubscriptionsForUser the active subscriptions of the user and the active app subscriptions
+func (r *fakeWebhookRepository) FindActiveSubscriptionsForUser(_ context.Context, userID int) ([]*model.WebhookSubscription, error) {
+	var subscriptions []*model.WebhookSubscription
+	for id := 1; id <= len(r.subscriptions); id++ {
+		subscription, ok := r.subscriptions[id]
+		if !ok || !subscription.Active || (subscription.UserID != nil && *subscription.UserID != userID) {
+			continue
+		}
+		copied := *subscription
+		subscriptions = append(subscriptions, &copied)
+	}
+	return subscriptions, nil
+}
+
+func (r *fakeWebhookRepository) CreateDeliveries(_ context.Context, deliveries []*model.WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		delivery.ID = len(r.deliveries) + 1
+		copied := *delivery
+		r.deliveries[delivery.ID] = &copied
+	}
+	return nil
+}
+
+func (r *fakeWebhookRepository) FindDeliveryByID(_ context.Context, id int) (*model.WebhookDelivery, error) {
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *delivery
+	return &copied, nil
+}
+
+// ClaimDueDeliveries claim the due pending deliveries, the lease is not faked
+func (r *fakeWebhookRepository) ClaimDueDeliveries(_ context.Context, now time.Time, _ time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	for id := 1; id <= len(r.deliveries) && len(deliveries) < limit; id++ {
+		delivery, ok := r.deliveries[id]
+		if !ok || delivery.Status != model.WebhookDeliveryStatusPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		copied := *delivery
+		deliveries = append(deliveries, &copied)
+	}
+	return deliveries, nil
+}
+
+func (r *fakeWebhookRepository) UpdateDelivery(_ context.Context, delivery *model.WebhookDelivery) error {
+	copied := *delivery
+	r.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+// fakeLedger keep the balances, histories, transfers and outbox messages of the money fakes.
+// The writes of a transaction are visible to others only after its commit, and a locked balance row
+// stay locked until the transaction end, like in postgres.
+type fakeLedger struct {
+	mu        sync.Mutex
+	nextID    int
+	rowLocks  map[int]*sync.Mutex
+	lockCalls [][]int
+	txs       map[*gorm.DB]*fakeLedgerTx
+	committed fakeLedgerWrites
+}
+
+type fakeLedgerTx struct {
+	locked     []int
+	writes     fakeLedgerWrites
+	savePoints map[string]fakeLedgerWrites
+}
+
+type fakeLedgerWrites struct {
+	balances  map[int]model.UserBalance
+	histories []model.UserBalanceHistory
+	transfers map[int]model.Transfer
+	outbox    []model.OutboxMessage
+}
+
+func newFakeLedgerWrites() fakeLedgerWrites {
+	return fakeLedgerWrites{
+		balances:  make(map[int]model.UserBalance),
+		transfers: make(map[int]model.Transfer),
+	}
+}
+
+func (w fakeLedgerWrites) clone() fakeLedgerWrites {
+	cloned := newFakeLedgerWrites()
+	for userID, balance := range w.balances {
+		cloned.balances[userID] = balance
+	}
+	for id, transfer := range w.transfers {
+		cloned.transfers[id] = transfer
+	}
+	cloned.histories = append(cloned.histories, w.histories...)
+	cloned.outbox = append(cloned.outbox, w.outbox...)
+	return cloned
+}
+
+// apply the writes of a transaction on top of w
+func (w *fakeLedgerWrites) apply(writes fakeLedgerWrites) {
+	for userID, balance := range writes.balances {
+		w.balances[userID] = balance
+	}
+	for id, transfer := range writes.transfers {
+		w.transfers[id] = transfer
+	}
+	w.histories = append(w.histories, writes.histories...)
+	w.outbox = append(w.outbox, writes.outbox...)
+}
+
+func newFakeLedger(balances ...*model.UserBalance) *fakeLedger {
+	l := &fakeLedger{
+		rowLocks:  make(map[int]*sync.Mutex),
+		txs:       make(map[*gorm.DB]*fakeLedgerTx),
+		committed: newFakeLedgerWrites(),
+	}
+	for _, balance := range balances {
+		l.nextID++
+		balance.ID = l.nextID
+		l.committed.balances[balance.UserID] = *balance
+	}
+	return l
+}
+
+// balanceOf the committed balance of the user
+func (l *fakeLedger) balanceOf(userID int) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.committed.balances[userID].Balance
+}
+
+// committedHistories the committed histories, in order
+func (l *fakeLedger) committedHistories() []model.UserBalanceHistory {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]model.UserBalanceHistory(nil), l.committed.histories...)
+}
+
+// committedTransfers the committed transfers ordered by id
+func (l *fakeLedger) committedTransfers() []model.Transfer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var transfers []model.Transfer
+	for id := 1; id <= l.nextID; id++ {
+		if transfer, ok := l.committed.transfers[id]; ok {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers
+}
+
+// committedOutbox the committed outbox messages, in order
+func (l *fakeLedger) committedOutbox() []model.OutboxMessage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]model.OutboxMessage(nil), l.committed.outbox...)
+}
+
+// openTransactions the number of transactions which are not committed nor rolled back
+func (l *fakeLedger) openTransactions() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.txs)
+}
+
+// tx the state of an open transaction, l.mu must be held
+func (l *fakeLedger) tx(tx *gorm.DB) *fakeLedgerTx {
+	state, ok := l.txs[tx]
+	if !ok {
+		panic("the transaction is not open")
+	}
+	return state
+}
+
+func (l *fakeLedger) end(tx *gorm.DB, commit bool) {
+	l.mu.Lock()
+	state := l.tx(tx)
+	if commit {
+		l.committed.apply(state.writes)
+	}
+	delete(l.txs, tx)
+	var locks []*sync.Mutex
+	for _, userID := range state.locked {
+		locks = append(locks, l.rowLocks[userID])
+	}
+	l.mu.Unlock()
+
+	for _, lock := range locks {
+		lock.Unlock()
+	}
+}
+
+type fakeGormTransactioner struct {
+	ledger *fakeLedger
+}
+
+func (t *fakeGormTransactioner) Begin(context.Context) *gorm.DB {
+	t.ledger.mu.Lock()
+	defer t.ledger.mu.Unlock()
+
+	tx := &gorm.DB{}
+	t.ledger.txs[tx] = &fakeLedgerTx{
+		writes:     newFakeLedgerWrites(),
+		savePoints: make(map[string]fakeLedgerWrites),
+	}
+	return tx
+}
+
+func (t *fakeGormTransactioner) Commit(tx *gorm.DB) error {
+	t.ledger.end(tx, true)
+	return nil
+}
+
+func (t *fakeGormTransactioner) Rollback(tx *gorm.DB) {
+	t.ledger.end(tx, false)
+}
+
+func (t *fakeGormTransactioner) SavePoint(tx *gorm.DB, name string) error {
+	t.ledger.mu.Lock()
+	defer t.ledger.mu.Unlock()
+
+	state := t.ledger.tx(tx)
+	state.savePoints[name] = state.writes.clone()
+	return nil
+}
+
+func (t *fakeGormTransactioner) RollbackTo(tx *gorm.DB, name string) error {
+	t.ledger.mu.Lock()
+	defer t.ledger.mu.Unlock()
+
+	state := t.ledger.tx(tx)
+	writes, ok := state.savePoints[name]
+	if !ok {
+		return errors.New("save point not found")
+	}
+	state.writes = writes.clone()
+	return nil
+}
+
+type fakeUserBalanceRepository struct {
+	model.UserBalanceRepository
+	ledger *fakeLedger
+}
+
+func (r *fakeUserBalanceRepository) GetCurrentUserBalanceByUserID(_ context.Context, userID int) (*model.UserBalance, error) {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	balance, ok := r.ledger.committed.balances[userID]
+	if !ok {
+		return &model.UserBalance{}, nil
+	}
+	return &balance, nil
+}
+
+// FindByUserIDsForUpdateWithTransaction lock the rows one by one in the given order, a missing row is locked too
+func (r *fakeUserBalanceRepository) FindByUserIDsForUpdateWithTransaction(_ context.Context, tx *gorm.DB, userIDs []int) ([]*model.UserBalance, error) {
+	r.ledger.mu.Lock()
+	r.ledger.tx(tx)
+	r.ledger.lockCalls = append(r.ledger.lockCalls, append([]int(nil), userIDs...))
+	r.ledger.mu.Unlock()
+
+	for _, userID := range userIDs {
+		r.ledger.mu.Lock()
+		lock, ok := r.ledger.rowLocks[userID]
+		if !ok {
+			lock = &sync.Mutex{}
+			r.ledger.rowLocks[userID] = lock
+		}
+		r.ledger.mu.Unlock()
+
+		lock.Lock()
+
+		r.ledger.mu.Lock()
+		state := r.ledger.tx(tx)
+		state.locked = append(state.locked, userID)
+		r.ledger.mu.Unlock()
+	}
+
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	state := r.ledger.tx(tx)
+	var balances []*model.UserBalance
+	for _, userID := range userIDs {
+		balance, ok := state.writes.balances[userID]
+		if !ok {
+			balance, ok = r.ledger.committed.balances[userID]
+		}
+		if ok {
+			balances = append(balances, &balance)
+		}
+	}
+	return balances, nil
+}
+
+func (r *fakeUserBalanceRepository) UpsertWithTransaction(_ context.Context, tx *gorm.DB, userBalance *model.UserBalance) error {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	state := r.ledger.tx(tx)
+	if userBalance.ID == 0 {
+		r.ledger.nextID++
+		userBalance.ID = r.ledger.nextID
+	}
+	state.writes.balances[userBalance.UserID] = *userBalance
+	return nil
+}
+
+type fakeUserBalanceHistoryRepository struct {
+	model.UserBalanceHistoryRepository
+	ledger *fakeLedger
+}
+
+func (r *fakeUserBalanceHistoryRepository) CreateWithTransaction(_ context.Context, tx *gorm.DB, history *model.UserBalanceHistory) error {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	state := r.ledger.tx(tx)
+	r.ledger.nextID++
+	history.ID = r.ledger.nextID
+	history.CreatedAt = time.Now()
+	state.writes.histories = append(state.writes.histories, *history)
+	return nil
+}
+
+type fakeTransferRepository struct {
+	model.TransferRepository
+	ledger *fakeLedger
+	// failToUserID make the upsert of a transfer to the user fail
+	failToUserID int
+}
+
+func (r *fakeTransferRepository) UpsertWithTransaction(_ context.Context, tx *gorm.DB, transfer *model.Transfer) error {
+	if transfer.ToUserID == r.failToUserID {
+		return errors.New("upsert failed")
+	}
+
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	state := r.ledger.tx(tx)
+	if transfer.ID == 0 {
+		r.ledger.nextID++
+		transfer.ID = r.ledger.nextID
+	}
+	state.writes.transfers[transfer.ID] = *transfer
+	return nil
+}
+
+func (r *fakeTransferRepository) FindByID(_ context.Context, id int) (*model.Transfer, error) {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	transfer, ok := r.ledger.committed.transfers[id]
+	if !ok {
+		return nil, nil
+	}
+	return &transfer, nil
+}
+
+// FindByIDForUpdateWithTransaction the transfer seen by the transaction, the row lock is not faked
+func (r *fakeTransferRepository) FindByIDForUpdateWithTransaction(_ context.Context, tx *gorm.DB, id int) (*model.Transfer, error) {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	transfer, ok := r.ledger.tx(tx).writes.transfers[id]
+	if !ok {
+		transfer, ok = r.ledger.committed.transfers[id]
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &transfer, nil
+}
+
+type fakeOutboxRepository struct {
+	model.OutboxRepository
+	ledger *fakeLedger
+}
+
+func (r *fakeOutboxRepository) CreateWithTransaction(_ context.Context, tx *gorm.DB, messages []*model.OutboxMessage) error {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	state := r.ledger.tx(tx)
+	for _, message := range messages {
+		r.ledger.nextID++
+		message.ID = int64(r.ledger.nextID)
+		if message.Status == "" {
+			message.Status = model.OutboxStatusPending
+		}
+		state.writes.outbox = append(state.writes.outbox, *message)
+	}
+	return nil
+}
+
+func (r *fakeOutboxRepository) TryLockRelayWithTransaction(context.Context, *gorm.DB) (bool, error) {
+	return true, nil
+}
+
+// FindPendingWithTransaction the committed pending messages, the updates of the relay are committed at once
+func (r *fakeOutboxRepository) FindPendingWithTransaction(_ context.Context, tx *gorm.DB, limit int) ([]*model.OutboxMessage, error) {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	r.ledger.tx(tx)
+	var messages []*model.OutboxMessage
+	for _, message := range r.ledger.committed.outbox {
+		if message.Status == model.OutboxStatusPending && len(messages) < limit {
+			copied := message
+			messages = append(messages, &copied)
+		}
+	}
+	return messages, nil
+}
+
+func (r *fakeOutboxRepository) ClaimWithTransaction(_ context.Context, tx *gorm.DB, ids []int64, claimedUntil time.Time) error {
+	return r.update(tx, ids, func(message *model.OutboxMessage) {
+		message.ClaimedUntil = &claimedUntil
+	})
+}
+
+func (r *fakeOutboxRepository) ReleaseClaimWithTransaction(_ context.Context, tx *gorm.DB, ids []int64) error {
+	return r.update(tx, ids, func(message *model.OutboxMessage) {
+		message.ClaimedUntil = nil
+	})
+}
+
+func (r *fakeOutboxRepository) MarkPublishedWithTransaction(_ context.Context, tx *gorm.DB, ids []int64, publishedAt time.Time) error {
+	return r.update(tx, ids, func(message *model.OutboxMessage) {
+		message.Status = model.OutboxStatusPublished
+		message.PublishedAt = &publishedAt
+		message.ClaimedUntil = nil
+	})
+}
+
+func (r *fakeOutboxRepository) MarkFailedWithTransaction(_ context.Context, tx *gorm.DB, failed *model.OutboxMessage) error {
+	return r.update(tx, []int64{failed.ID}, func(message *model.OutboxMessage) {
+		message.Status = failed.Status
+		message.Attempts = failed.Attempts
+		message.LastError = failed.LastError
+		message.ClaimedUntil = nil
+	})
+}
+
+func (r *fakeOutboxRepository) update(tx *gorm.DB, ids []int64, update func(message *model.OutboxMessage)) error {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	r.ledger.tx(tx)
+	for _, id := range ids {
+		for i := range r.ledger.committed.outbox {
+			if r.ledger.committed.outbox[i].ID == id {
+				update(&r.ledger.committed.outbox[i])
+			}
+		}
+	}
+	return nil
+}
+
+type fakeTransferLimitRepository struct {
+	model.TransferLimitRepository
+	ledger *fakeLedger
+	limits map[int]*model.UserTransferLimit
+}
+
+func (r *fakeTransferLimitRepository) FindByUserID(_ context.Context, userID int) (*model.UserTransferLimit, error) {
+	return r.limits[userID], nil
+}
+
+// GetOutgoingUsageWithTransaction sum the debits of the user seen by the transaction, in the windows of the real query
+func (r *fakeTransferLimitRepository) GetOutgoingUsageWithTransaction(_ context.Context, tx *gorm.DB, userID int, now time.Time) (*model.TransferUsage, error) {
+	r.ledger.mu.Lock()
+	defer r.ledger.mu.Unlock()
+
+	state := r.ledger.tx(tx)
+	balance, ok := state.writes.balances[userID]
+	if !ok {
+		balance = r.ledger.committed.balances[userID]
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	hourStart := now.Truncate(time.Hour)
+
+	usage := &model.TransferUsage{}
+	for _, history := range append(append([]model.UserBalanceHistory(nil), r.ledger.committed.histories...), state.writes.histories...) {
+		if balance.ID == 0 || history.UserBalanceID != balance.ID || history.Type != model.DEBIT || history.CreatedAt.Before(monthStart) {
+			continue
+		}
+		usage.MonthlyAmount += history.BalanceBefore - history.BalanceAfter
+		if !history.CreatedAt.Before(dayStart) {
+			usage.DailyAmount += history.BalanceBefore - history.BalanceAfter
+		}
+		if !history.CreatedAt.Before(hourStart) {
+			usage.HourlyCount++
+		}
+	}
+	return usage, nil
+}
+
+// fakeTransferScreener allow every transfer, except to the recipients with a decision
+type fakeTransferScreener struct {
+	decisions map[int]model.ScreeningDecision
+}
+
+func (s *fakeTransferScreener) Screen(_ context.Context, req model.TransferScreeningRequest) (*model.TransferScreeningResult, error) {
+	decision, ok := s.decisions[req.ToUserID]
+	if !ok {
+		decision = model.ScreeningDecisionAllow
+	}
+	return &model.TransferScreeningResult{Decision: decision}, nil
+}
+
+type fakeBalanceBroker struct {
+	model.BalanceUpdateBroker
+}
+
+func (b *fakeBalanceBroker) Publish(context.Context, ...*model.BalanceUpdate) error {
+	return nil
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// loginLockout delay the logins of an account or an IP after too many failed logins,
+// the delay is doubled on every next failure instead of locking for a fixed time
+type loginLockout struct {
+	repo model.LoginLockoutRepository
+	now  func() time.Time
+}
+
+type loginLockoutTarget struct {
+	scope model.LoginLockoutScope
+	key   string
+}
+
+// loginLockoutTargets the account and the IP, an empty one is skipped
+func loginLockoutTargets(email, ipAddress string) []loginLockoutTarget {
+	var targets []loginLockoutTarget
+	if email != "" {
+		targets = append(targets, loginLockoutTarget{scope: model.LoginLockoutScopeAccount, key: email})
+	}
+	if ipAddress != "" {
+		targets = append(targets, loginLockoutTarget{scope: model.LoginLockoutScopeIP, key: ipAddress})
+	}
+	return targets
+}
+
+// check return ErrLoginMaxAttempts while the account or the IP is locked
+func (l *loginLockout) check(ctx context.Context, email, ipAddress string) error {
+	for _, target := range loginLockoutTargets(email, ipAddress) {
+		lockout, err := l.repo.FindByScopeAndKey(ctx, target.scope, target.key)
+		if err != nil {
+			return err
+		}
+		if lockout.IsLocked(l.now()) {
+			return ErrLoginMaxAttempts
+		}
+	}
+	return nil
+}
+
+// recordFailure count the failed login of the account and the IP, and lock the ones above their attempts.
+// locked is true when the account or the IP is locked by this failure.
+func (l *loginLockout) recordFailure(ctx context.Context, email, ipAddress string) (locked bool, err error) {
+	for _, target := range loginLockoutTargets(email, ipAddress) {
+		attempts, err := l.repo.IncrementFailedAttempts(ctx, target.scope, target.key, config.LoginLockoutWindow())
+		if err != nil {
+			return false, err
+		}
+
+		delay := loginLockoutDelay(attempts, loginLockoutAttempts(target.scope))
+		if delay <= 0 {
+			continue
+		}
+
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"scope":    target.scope,
+			"key":      target.key,
+			"attempts": attempts,
+			"delay":    delay,
+		}).Warn("login is delayed after too many failures")
+		if err = l.repo.Lock(ctx, target.scope, target.key, l.now().Add(delay)); err != nil {
+			return false, err
+		}
+		locked = true
+	}
+	return locked, nil
+}
+
+// reset forget the failed logins of the account after a successful login.
+// The IP keep its failures, so an attacker can't reset them by logging in to its own account.
+func (l *loginLockout) reset(ctx context.Context, email string) error {
+	return l.repo.DeleteByScopeAndKey(ctx, model.LoginLockoutScopeAccount, email)
+}
+
+func loginLockoutAttempts(scope model.LoginLockoutScope) int64 {
+	if scope == model.LoginLockoutScopeIP {
+		return config.LoginLockoutIPAttempts()
+	}
+	return config.LoginLockoutAccountAttempts()
+}
+
+// loginLockoutDelay the base delay on the first failure above the allowed attempts, doubled on every next failure up to the max delay
+func loginLockoutDelay(failedAttempts, allowedAttempts int64) time.Duration {
+	if failedAttempts <= allowedAttempts {
+		return 0
+	}
+
+	delay, maxDelay := config.LoginLockoutBaseDelay(), config.LoginLockoutMaxDelay()
+	for i := allowedAttempts + 1; i < failedAttempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// FindLoginLockouts return the lockout of the account and/or the IP, a key without failure is returned with zero attempts
+func (a *authUsecase) FindLoginLockouts(ctx context.Context, adminID int, query model.LoginLockoutQuery) ([]*model.LoginLockout, error) {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.FindLoginLockouts")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"adminID": adminID,
+		"email":   query.Email,
+		"ip":      query.IPAddress,
+	})
+
+	query.Email = helper.FormatEmail(query.Email)
+	if err := query.Validate(); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	if err := a.mustBeAdmin(ctx, adminID); err != nil {
+		return nil, err
+	}
+
+	var lockouts []*model.LoginLockout
+	for _, target := range loginLockoutTargets(query.Email, query.IPAddress) {
+		lockout, err := a.loginLockout.repo.FindByScopeAndKey(ctx, target.scope, target.key)
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		lockouts = append(lockouts, lockout)
+	}
+
+	return lockouts, nil
+}
+
+// UnlockLogin forget the failed logins of the account and/or the IP, so they can login immediately
+func (a *authUsecase) UnlockLogin(ctx context.Context, adminID int, query model.LoginLockoutQuery) error {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.UnlockLogin")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"adminID": adminID,
+		"email":   query.Email,
+		"ip":      query.IPAddress,
+	})
+
+	query.Email = helper.FormatEmail(query.Email)
+	if err := query.Validate(); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if err := a.mustBeAdmin(ctx, adminID); err != nil {
+		return err
+	}
+
+	for _, target := range loginLockoutTargets(query.Email, query.IPAddress) {
+		if err := a.loginLockout.repo.DeleteByScopeAndKey(ctx, target.scope, target.key); err != nil {
+			logger.Error(err)
+			return err
+		}
+	}
+
+	logger.Info("login is unlocked by admin")
+	return nil
+}
+
+func (a *authUsecase) mustBeAdmin(ctx context.Context, userID int) error {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		logrus.WithContext(ctx).WithField("userID", userID).Error(err)
+		return err
+	}
+	if user == nil || !user.IsAdmin() {
+		return ErrPermissionDenied
+	}
+	return nil
+}
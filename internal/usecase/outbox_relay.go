@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/repository"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type outboxRelay struct {
+	outboxRepo        model.OutboxRepository
+	gormTransactioner repository.GormTransactioner
+	publisher         model.Publisher
+}
+
+// NewOutboxRelay :nodoc:
+func NewOutboxRelay(
+	outboxRepo model.OutboxRepository,
+	gormTransactioner repository.GormTransactioner,
+	publisher model.Publisher,
+) model.OutboxRelay {
+	return &outboxRelay{
+		outboxRepo:        outboxRepo,
+		gormTransactioner: gormTransactioner,
+		publisher:         publisher,
+	}
+}
+
+// Relay claim a batch of pending messages, publish them outside of any transaction and then mark them.
+// When a message failed the next messages of the same account wait for the next round, so the messages of an account
+// are published in order and at least once. A message which failed OutboxMaxAttempts times is failed for good,
+// it's not published again and the later messages of its account are published without it.
+func (o *outboxRelay) Relay(ctx context.Context) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "outboxRelay.Relay")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx)
+
+	messages, err := o.claim(ctx)
+	if err != nil {
+		logger.Error(err)
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	var (
+		published []int64
+		released  []int64
+		failed    []*model.OutboxMessage
+	)
+	blocked := make(map[int]bool)
+	for _, message := range messages {
+		if blocked[message.AggregateID] {
+			released = append(released, message.ID)
+			continue
+		}
+
+		if err = o.publisher.Publish(ctx, message); err != nil {
+			logger.WithField("messageID", message.ID).Error(err)
+			blocked[message.AggregateID] = true
+
+			message.Attempts++
+			message.LastError = err.Error()
+			if message.Attempts >= config.OutboxMaxAttempts() {
+				message.Status = model.OutboxStatusFailed
+				logger.WithFields(logrus.Fields{
+					"messageID":   message.ID,
+					"aggregateID": message.AggregateID,
+					"eventID":     message.EventID,
+					"attempts":    message.Attempts,
+				}).Error("outbox message failed for good, the later messages of its account are published without it")
+			}
+			failed = append(failed, message)
+			continue
+		}
+		published = append(published, message.ID)
+	}
+
+	// a message published but not marked because the commit failed is published again once its claim expired,
+	// which is fine for at least once
+	tx := o.gormTransactioner.Begin(ctx)
+	if err = o.outboxRepo.MarkPublishedWithTransaction(ctx, tx, published, time.Now()); err != nil {
+		o.gormTransactioner.Rollback(tx)
+		return 0, err
+	}
+	for _, message := range failed {
+		if err = o.outboxRepo.MarkFailedWithTransaction(ctx, tx, message); err != nil {
+			o.gormTransactioner.Rollback(tx)
+			return 0, err
+		}
+	}
+	if err = o.outboxRepo.ReleaseClaimWithTransaction(ctx, tx, released); err != nil {
+		o.gormTransactioner.Rollback(tx)
+		return 0, err
+	}
+
+	if err = o.gormTransactioner.Commit(tx); err != nil {
+		logger.Error(err)
+		return 0, err
+	}
+
+	return len(published), nil
+}
+
+// claim the pending messages of a batch for the claim lease. Only one relay claim at a time, and the relay lock is
+// released once the batch is claimed, so the messages are published without holding a transaction.
+func (o *outboxRelay) claim(ctx context.Context) ([]*model.OutboxMessage, error) {
+	tx := o.gormTransactioner.Begin(ctx)
+	locked, err := o.outboxRepo.TryLockRelayWithTransaction(ctx, tx)
+	if err != nil {
+		o.gormTransactioner.Rollback(tx)
+		return nil, err
+	}
+	if !locked {
+		o.gormTransactioner.Rollback(tx)
+		return nil, nil
+	}
+
+	messages, err := o.outboxRepo.FindPendingWithTransaction(ctx, tx, config.OutboxBatchSize())
+	if err != nil {
+		o.gormTransactioner.Rollback(tx)
+		return nil, err
+	}
+
+	// a message claimed by another relay is still being published, the later messages of its account wait for it
+	now := time.Now()
+	var (
+		claimed []*model.OutboxMessage
+		ids     []int64
+	)
+	blocked := make(map[int]bool)
+	for _, message := range messages {
+		if blocked[message.AggregateID] || (message.ClaimedUntil != nil && message.ClaimedUntil.After(now)) {
+			blocked[message.AggregateID] = true
+			continue
+		}
+		claimed = append(claimed, message)
+		ids = append(ids, message.ID)
+	}
+
+	if err = o.outboxRepo.ClaimWithTransaction(ctx, tx, ids, now.Add(config.OutboxClaimLease())); err != nil {
+		o.gormTransactioner.Rollback(tx)
+		return nil, err
+	}
+
+	if err = o.gormTransactioner.Commit(tx); err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePublisher record the published events, the events in failEventIDs fail
+type fakePublisher struct {
+	ledger       *fakeLedger
+	mu           sync.Mutex
+	failEventIDs map[string]bool
+	published    []string
+	openTxs      []int
+}
+
+func (p *fakePublisher) Publish(_ context.Context, message *model.OutboxMessage) error {
+	openTxs := p.ledger.openTransactions()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.openTxs = append(p.openTxs, openTxs)
+	if p.failEventIDs[message.EventID] {
+		return errors.New("publish failed")
+	}
+	p.published = append(p.published, message.EventID)
+	return nil
+}
+
+// newOutboxRelayTestSuite a relay over the fake ledger with the committed messages,
+// the event id of a message is its account followed by its position in the account, e.g. A1
+func newOutboxRelayTestSuite(t *testing.T, eventIDs ...string) (model.OutboxRelay, *fakeLedger, *fakePublisher) {
+	t.Helper()
+
+	ledger := newFakeLedger()
+	transactioner := &fakeGormTransactioner{ledger: ledger}
+	outboxRepo := &fakeOutboxRepository{ledger: ledger}
+
+	var messages []*model.OutboxMessage
+	for _, eventID := range eventIDs {
+		messages = append(messages, &model.OutboxMessage{AggregateID: int(eventID[0]), EventID: eventID})
+	}
+	tx := transactioner.Begin(context.Background())
+	require.NoError(t, outboxRepo.CreateWithTransaction(context.Background(), tx, messages))
+	require.NoError(t, transactioner.Commit(tx))
+
+	publisher := &fakePublisher{ledger: ledger, failEventIDs: make(map[string]bool)}
+	return NewOutboxRelay(outboxRepo, transactioner, publisher), ledger, publisher
+}
+
+func outboxStatuses(ledger *fakeLedger) map[string]model.OutboxStatus {
+	statuses := make(map[string]model.OutboxStatus)
+	for _, message := range ledger.committedOutbox() {
+		statuses[message.EventID] = message.Status
+	}
+	return statuses
+}
+
+func TestOutboxRelay_Relay(t *testing.T) {
+	relay, ledger, publisher := newOutboxRelayTestSuite(t, "A1", "B1", "A2", "B2")
+	publisher.failEventIDs["A1"] = true
+
+	// the failed message hold back the later messages of its account only
+	count, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"B1", "B2"}, publisher.published)
+
+	for _, message := range ledger.committedOutbox() {
+		if message.EventID == "A1" {
+			assert.Equal(t, 1, message.Attempts)
+			assert.Equal(t, "publish failed", message.LastError)
+		}
+		if message.Status == model.OutboxStatusPending {
+			assert.Nil(t, message.ClaimedUntil, message.EventID)
+		}
+	}
+
+	publisher.failEventIDs["A1"] = false
+	count, err = relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"B1", "B2", "A1", "A2"}, publisher.published)
+	assert.Equal(t, map[string]model.OutboxStatus{
+		"A1": model.OutboxStatusPublished,
+		"A2": model.OutboxStatusPublished,
+		"B1": model.OutboxStatusPublished,
+		"B2": model.OutboxStatusPublished,
+	}, outboxStatuses(ledger))
+
+	// the messages are published outside of any transaction
+	for _, openTxs := range publisher.openTxs {
+		assert.Equal(t, 0, openTxs)
+	}
+}
+
+func TestOutboxRelay_MaxAttempts(t *testing.T) {
+	viper.Set("outbox.max_attempts", 2)
+	defer viper.Set("outbox.max_attempts", nil)
+
+	relay, ledger, publisher := newOutboxRelayTestSuite(t, "A1", "A2")
+	publisher.failEventIDs["A1"] = true
+
+	for i := 0; i < 2; i++ {
+		count, err := relay.Relay(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	}
+	assert.Equal(t, model.OutboxStatusFailed, outboxStatuses(ledger)["A1"])
+
+	// the failed message is not published again and no longer block its account
+	count, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"A2"}, publisher.published)
+	assert.Equal(t, map[string]model.OutboxStatus{
+		"A1": model.OutboxStatusFailed,
+		"A2": model.OutboxStatusPublished,
+	}, outboxStatuses(ledger))
+}
+
+func TestOutboxRelay_Claimed(t *testing.T) {
+	relay, ledger, publisher := newOutboxRelayTestSuite(t, "A1", "A2", "B1")
+
+	// another relay is still publishing A1
+	claimedUntil := time.Now().Add(time.Minute)
+	ledger.mu.Lock()
+	ledger.committed.outbox[0].ClaimedUntil = &claimedUntil
+	ledger.mu.Unlock()
+
+	count, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"B1"}, publisher.published)
+	assert.Equal(t, model.OutboxStatusPending, outboxStatuses(ledger)["A2"])
+
+	// the claim expired, the relay which claimed it is gone
+	expired := time.Now().Add(-time.Second)
+	ledger.mu.Lock()
+	ledger.committed.outbox[0].ClaimedUntil = &expired
+	ledger.mu.Unlock()
+
+	count, err = relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"B1", "A1", "A2"}, publisher.published)
+}
@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// SetTransactionPin set the first transaction pin of the user
+func (u *userUsecase) SetTransactionPin(ctx context.Context, input model.SetTransactionPinInput) error {
+	ctx, span := tracing.StartSpan(ctx, "userUsecase.SetTransactionPin")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"input": utils.Dump(input),
+	})
+
+	if err := input.Validate(); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	cipherPin, err := u.userRepo.FindTransactionPinByID(ctx, input.UserID)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if cipherPin == nil {
+		return ErrNotFound
+	}
+	if len(cipherPin) > 0 {
+		return ErrTransactionPinAlreadySet
+	}
+
+	return u.updateTransactionPin(ctx, input.UserID, input.Pin)
+}
+
+// ChangeTransactionPin change the transaction pin, the old pin is verified like on a transfer
+func (u *userUsecase) ChangeTransactionPin(ctx context.Context, input model.ChangeTransactionPinInput) error {
+	ctx, span := tracing.StartSpan(ctx, "userUsecase.ChangeTransactionPin")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"input": utils.Dump(input),
+	})
+
+	if err := input.Validate(); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if err := verifyTransactionPin(ctx, u.userRepo, input.UserID, input.OldPin); err != nil {
+		return err
+	}
+
+	return u.updateTransactionPin(ctx, input.UserID, input.NewPin)
+}
+
+// ResetTransactionPin replace a forgotten transaction pin by confirming the account password.
+// The password attempts share the login lockout, and a successful reset unlock the pin.
+func (u *userUsecase) ResetTransactionPin(ctx context.Context, input model.ResetTransactionPinInput) error {
+	ctx, span := tracing.StartSpan(ctx, "userUsecase.ResetTransactionPin")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"input": utils.Dump(input),
+	})
+
+	if err := input.Validate(); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	user, err := u.userRepo.FindByID(ctx, input.UserID)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if user == nil {
+		return ErrNotFound
+	}
+
+	if err = u.loginLockout.check(ctx, user.Email, ""); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	cipherPass, err := u.userRepo.FindPasswordByID(ctx, user.ID)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if !helper.IsHashedStringMatch([]byte(input.PlainPassword), cipherPass) {
+		if _, err := u.loginLockout.recordFailure(ctx, user.Email, ""); err != nil {
+			logger.Error(err)
+			return err
+		}
+
+		return ErrUnauthorized
+	}
+
+	if err = u.updateTransactionPin(ctx, user.ID, input.NewPin); err != nil {
+		return err
+	}
+
+	return u.userRepo.ResetTransactionPinRetryAttempts(ctx, user.ID)
+}
+
+func (u *userUsecase) updateTransactionPin(ctx context.Context, userID int, pin string) error {
+	cipherPin, err := helper.HashString(pin)
+	if err != nil {
+		logrus.WithField("userID", userID).Error(err)
+		return err
+	}
+
+	return u.userRepo.UpdateTransactionPinByID(ctx, userID, cipherPin)
+}
+
+// requireTransactionPin verify the transaction pin when the amount is above the configured threshold
+func requireTransactionPin(ctx context.Context, userRepo model.UserRepository, userID int, amount int64, pin string) error {
+	if amount <= config.TransactionPinRequiredAmount() {
+		return nil
+	}
+	if pin == "" {
+		return ErrTransactionPinRequired
+	}
+
+	return verifyTransactionPin(ctx, userRepo, userID, pin)
+}
+
+// verifyTransactionPin check the pin against the stored one, a mismatch count toward the pin lockout
+func verifyTransactionPin(ctx context.Context, userRepo model.UserRepository, userID int, pin string) error {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID": userID,
+	})
+
+	isLocked, err := userRepo.IsTransactionPinLocked(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if isLocked {
+		return ErrTransactionPinMaxAttempts
+	}
+
+	cipherPin, err := userRepo.FindTransactionPinByID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if len(cipherPin) == 0 {
+		return ErrTransactionPinNotSet
+	}
+
+	if !helper.IsHashedStringMatch([]byte(pin), cipherPin) {
+		if err := userRepo.IncrementTransactionPinRetryAttempts(ctx, userID); err != nil {
+			logger.Error(err)
+			return err
+		}
+
+		return ErrInvalidTransactionPin
+	}
+
+	if err = userRepo.ResetTransactionPinRetryAttempts(ctx, userID); err != nil {
+		logger.Error(err)
+	}
+
+	return nil
+}
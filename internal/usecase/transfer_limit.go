@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+)
+
+// findTransferLimit return the limit of the user, that is the config limit overridden by the user limit
+func (u *userBalanceUsecase) findTransferLimit(ctx context.Context, userID int) (model.TransferLimit, error) {
+	limit := model.TransferLimit{
+		MinAmount:     config.TransferMinAmount(),
+		MaxAmount:     config.TransferMaxAmount(),
+		DailyAmount:   config.TransferDailyAmount(),
+		MonthlyAmount: config.TransferMonthlyAmount(),
+		HourlyCount:   config.TransferHourlyCount(),
+	}
+
+	userLimit, err := u.transferLimitRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return limit, err
+	}
+
+	return limit.Override(userLimit), nil
+}
+
+// checkTransferAmount check the amount of a single transfer, it doesn't depend on the usage so it can be checked early
+func checkTransferAmount(limit model.TransferLimit, amount int64) error {
+	switch {
+	case amount < limit.MinAmount:
+		return ErrTransferAmountBelowMinimum
+	case limit.MaxAmount > 0 && amount > limit.MaxAmount:
+		return ErrTransferAmountAboveMaximum
+	}
+	return nil
+}
+
+// checkTransferLimit check a new outgoing transfer of the amount against the limit and the current usage.
+// The usage must be read after the payer balance is locked, so concurrent transfers can't pass the limit together.
+func checkTransferLimit(limit model.TransferLimit, usage *model.TransferUsage, amount int64) error {
+	if err := checkTransferAmount(limit, amount); err != nil {
+		return err
+	}
+
+	switch {
+	case limit.HourlyCount > 0 && usage.HourlyCount+1 > limit.HourlyCount:
+		return ErrTransferHourlyCountExceeded
+	case limit.DailyAmount > 0 && usage.DailyAmount+amount > limit.DailyAmount:
+		return ErrTransferDailyLimitExceeded
+	case limit.MonthlyAmount > 0 && usage.MonthlyAmount+amount > limit.MonthlyAmount:
+		return ErrTransferMonthlyLimitExceeded
+	}
+	return nil
+}
+
+// addTransferUsage add a new outgoing transfer of the amount to the usage
+func addTransferUsage(usage *model.TransferUsage, amount int64) {
+	usage.DailyAmount += amount
+	usage.MonthlyAmount += amount
+	usage.HourlyCount++
+}
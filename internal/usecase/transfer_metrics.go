@@ -0,0 +1,24 @@
+package usecase
+
+import "github.com/irvankadhafi/user-balance-transfer-service/internal/metrics"
+
+// transferRejectReasons reason label of the errors that reject a transfer by a business rule
+var transferRejectReasons = map[error]string{
+	ErrBalanceNotEnough:             "balance_not_enough",
+	ErrTransferDenied:               "screening_denied",
+	ErrTransferAmountBelowMinimum:   "amount_below_minimum",
+	ErrTransferAmountAboveMaximum:   "amount_above_maximum",
+	ErrTransferDailyLimitExceeded:   "daily_limit_exceeded",
+	ErrTransferMonthlyLimitExceeded: "monthly_limit_exceeded",
+	ErrTransferHourlyCountExceeded:  "hourly_count_exceeded",
+}
+
+// transferReviewRejectedReason reason label of a pending transfer rejected by an admin
+const transferReviewRejectedReason = "review_rejected"
+
+// observeTransferRejected count the rejected transfer when the error is a business rule, other errors are not counted
+func observeTransferRejected(err error) {
+	if reason, ok := transferRejectReasons[err]; ok {
+		metrics.ObserveTransferRejected(reason)
+	}
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// TransferScreeningRule a single rule of the rule based transfer screener,
+// the reason is only meaningful when the decision is not allow
+type TransferScreeningRule interface {
+	Evaluate(ctx context.Context, req model.TransferScreeningRequest) (decision model.ScreeningDecision, reason string, err error)
+}
+
+type ruleTransferScreener struct {
+	rules []TransferScreeningRule
+}
+
+// NewRuleTransferScreener return a screener that evaluate every rule and decide by the most severe decision
+func NewRuleTransferScreener(rules ...TransferScreeningRule) model.TransferScreener {
+	return &ruleTransferScreener{rules: rules}
+}
+
+// NewDefaultTransferScreeningRules return the built-in rules configured from the config
+func NewDefaultTransferScreeningRules(sessionRepo model.SessionRepository, transferRepo model.TransferRepository) []TransferScreeningRule {
+	return []TransferScreeningRule{
+		&newDeviceRule{
+			sessionRepo: sessionRepo,
+			action:      parseScreeningDecision(config.ScreeningNewDeviceAction()),
+		},
+		&newRecipientRule{
+			transferRepo: transferRepo,
+			action:       parseScreeningDecision(config.ScreeningNewRecipientAction()),
+			threshold:    config.ScreeningNewRecipientThreshold(),
+		},
+		&fanOutRule{
+			transferRepo:  transferRepo,
+			action:        parseScreeningDecision(config.ScreeningFanOutAction()),
+			window:        config.ScreeningFanOutWindow(),
+			maxRecipients: config.ScreeningFanOutMaxRecipients(),
+		},
+	}
+}
+
+// Screen :nodoc:
+func (r *ruleTransferScreener) Screen(ctx context.Context, req model.TransferScreeningRequest) (*model.TransferScreeningResult, error) {
+	ctx, span := tracing.StartSpan(ctx, "ruleTransferScreener.Screen")
+	defer span.End()
+
+	result := &model.TransferScreeningResult{Decision: model.ScreeningDecisionAllow}
+	for _, rule := range r.rules {
+		decision, reason, err := rule.Evaluate(ctx, req)
+		if err != nil {
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
+				"req": utils.Dump(req),
+			}).Error(err)
+			return nil, err
+		}
+		if decision == model.ScreeningDecisionAllow {
+			continue
+		}
+
+		result.Reasons = append(result.Reasons, reason)
+		if decision.Severity() > result.Decision.Severity() {
+			result.Decision = decision
+		}
+	}
+
+	return result, nil
+}
+
+// newDeviceRule screen a transfer made from a session whose ip address or user agent
+// never used by the previous sessions of the user
+type newDeviceRule struct {
+	sessionRepo model.SessionRepository
+	action      model.ScreeningDecision
+}
+
+func (r *newDeviceRule) Evaluate(ctx context.Context, req model.TransferScreeningRequest) (model.ScreeningDecision, string, error) {
+	if req.Session == nil {
+		return model.ScreeningDecisionAllow, "", nil
+	}
+
+	sessions, err := r.sessionRepo.FindAllByUserID(ctx, req.FromUserID)
+	if err != nil {
+		return "", "", err
+	}
+
+	var hasPrevious, knownIP, knownDevice bool
+	for _, sess := range sessions {
+		if sess.ID == req.Session.ID || !sess.CreatedAt.Before(req.Session.CreatedAt) {
+			continue
+		}
+		hasPrevious = true
+		knownIP = knownIP || sess.IPAddress == req.Session.IPAddress
+		knownDevice = knownDevice || sess.UserAgent == req.Session.UserAgent
+	}
+
+	// the first session of the user has nothing to compare with
+	switch {
+	case !hasPrevious, knownIP && knownDevice:
+		return model.ScreeningDecisionAllow, "", nil
+	case !knownIP && !knownDevice:
+		return r.action, "transfer from a new device and ip address", nil
+	case !knownIP:
+		return r.action, "transfer from a new ip address", nil
+	default:
+		return r.action, "transfer from a new device", nil
+	}
+}
+
+// newRecipientRule screen the first transfer to a recipient with an amount above the threshold
+type newRecipientRule struct {
+	transferRepo model.TransferRepository
+	action       model.ScreeningDecision
+	threshold    int64
+}
+
+func (r *newRecipientRule) Evaluate(ctx context.Context, req model.TransferScreeningRequest) (model.ScreeningDecision, string, error) {
+	if req.Amount < r.threshold {
+		return model.ScreeningDecisionAllow, "", nil
+	}
+
+	count, err := r.transferRepo.CountCompletedByUserIDs(ctx, req.FromUserID, req.ToUserID)
+	if err != nil {
+		return "", "", err
+	}
+	if count > 0 {
+		return model.ScreeningDecisionAllow, "", nil
+	}
+
+	return r.action, fmt.Sprintf("first transfer to the recipient is above %d", r.threshold), nil
+}
+
+// fanOutRule screen a transfer that make the user send to too many recipients in the window
+type fanOutRule struct {
+	transferRepo  model.TransferRepository
+	action        model.ScreeningDecision
+	window        time.Duration
+	maxRecipients int
+}
+
+func (r *fanOutRule) Evaluate(ctx context.Context, req model.TransferScreeningRequest) (model.ScreeningDecision, string, error) {
+	recipientIDs, err := r.transferRepo.FindRecipientIDsSince(ctx, req.FromUserID, time.Now().Add(-r.window))
+	if err != nil {
+		return "", "", err
+	}
+
+	recipients := map[int]bool{req.ToUserID: true}
+	for _, id := range recipientIDs {
+		recipients[id] = true
+	}
+	for _, id := range req.BatchRecipientIDs {
+		recipients[id] = true
+	}
+	if len(recipients) <= r.maxRecipients {
+		return model.ScreeningDecisionAllow, "", nil
+	}
+
+	return r.action, fmt.Sprintf("transfer to %d recipients in %s", len(recipients), r.window), nil
+}
+
+// parseScreeningDecision parse the configured action, an unknown action is treated as review
+func parseScreeningDecision(action string) model.ScreeningDecision {
+	switch decision := model.ScreeningDecision(strings.ToUpper(strings.TrimSpace(action))); decision {
+	case model.ScreeningDecisionAllow, model.ScreeningDecisionReview, model.ScreeningDecisionDeny:
+		return decision
+	default:
+		return model.ScreeningDecisionReview
+	}
+}
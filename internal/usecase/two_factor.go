@@ -0,0 +1,257 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// VerifyLoginChallenge complete the 2FA login with a TOTP or a recovery code.
+// A wrong code count toward the login lockout of the user.
+func (a *authUsecase) VerifyLoginChallenge(ctx context.Context, req model.VerifyLoginChallengeRequest) (*model.Session, error) {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.VerifyLoginChallenge")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"ip":        req.IPAddress,
+		"userAgent": req.UserAgent,
+	})
+
+	challenge, err := a.twoFactorRepo.FindLoginChallengeByToken(ctx, req.ChallengeToken)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	// the challenge expire with its cache, the expiry is checked too in case the cache outlive it
+	if challenge == nil || !challenge.ExpiredAt.After(a.now()) {
+		return nil, ErrLoginChallengeExpired
+	}
+
+	logger = logger.WithField("userID", challenge.UserID)
+	event := model.AuthEvent{
+		EventType: model.AuthEventTwoFactorLogin,
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+	}
+
+	if err = a.loginLockout.check(ctx, challenge.Email, req.IPAddress); err != nil {
+		logger.Error(err)
+		if err == ErrLoginMaxAttempts {
+			event.Reason = authEventReasonLocked
+			a.recordLoginFailure(ctx, challenge.UserID, event, false)
+		}
+		return nil, err
+	}
+
+	twoFactor, err := a.twoFactorRepo.FindByUserID(ctx, challenge.UserID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if !twoFactor.IsEnabled() {
+		return nil, ErrLoginChallengeExpired
+	}
+
+	locked, err := a.verifyTwoFactorCode(ctx, twoFactor, challenge.Email, req.IPAddress, req.Code)
+	if err != nil {
+		if err == ErrInvalidTwoFactorCode {
+			event.Reason = authEventReasonInvalidCode
+			a.recordLoginFailure(ctx, challenge.UserID, event, locked)
+		}
+		return nil, err
+	}
+
+	if err = a.loginLockout.reset(ctx, challenge.Email); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	// the challenge is single use
+	if err = a.twoFactorRepo.DeleteLoginChallengeByToken(ctx, req.ChallengeToken); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	session, err := a.createSession(ctx, challenge.UserID, req.IPAddress, req.UserAgent)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	event.Result = model.AuthEventResultSuccess
+	a.recordAuthEvent(ctx, challenge.UserID, event)
+	return session, nil
+}
+
+// EnrollTwoFactor generate a new secret, the 2FA is enabled after the first code is verified
+func (a *authUsecase) EnrollTwoFactor(ctx context.Context, userID int) (*model.TwoFactorEnrollment, error) {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.EnrollTwoFactor")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID": userID,
+	})
+
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+
+	twoFactor, err := a.twoFactorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if twoFactor.IsEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := helper.GenerateTOTPSecret()
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	err = a.twoFactorRepo.Upsert(ctx, &model.UserTwoFactor{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	return &model.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: helper.TOTPProvisioningURI(config.TwoFactorIssuer(), user.Email, secret),
+	}, nil
+}
+
+// EnableTwoFactor verify the first code of the enrolled secret and enable the 2FA.
+// The returned recovery codes are only shown once, only their hash is stored.
+func (a *authUsecase) EnableTwoFactor(ctx context.Context, userID int, code string) ([]string, error) {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.EnableTwoFactor")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID": userID,
+	})
+
+	twoFactor, err := a.twoFactorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	switch {
+	case twoFactor == nil:
+		return nil, ErrTwoFactorNotEnrolled
+	case twoFactor.IsEnabled():
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	now := a.now()
+	step, ok := helper.ValidateTOTPCode(twoFactor.Secret, code, now)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	recoveryCodes := make([]string, config.DefaultTwoFactorRecoveryCodes)
+	codeHashes := make([]string, len(recoveryCodes))
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = helper.GenerateRecoveryCode()
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		codeHashes[i] = helper.HashRecoveryCode(recoveryCodes[i])
+	}
+
+	twoFactor.EnabledAt = &now
+	twoFactor.LastUsedStep = step
+	if err = a.twoFactorRepo.Enable(ctx, twoFactor, codeHashes); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableTwoFactor disable the 2FA, confirmed by a TOTP or a recovery code
+func (a *authUsecase) DisableTwoFactor(ctx context.Context, userID int, code string) error {
+	ctx, span := tracing.StartSpan(ctx, "authUsecase.DisableTwoFactor")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID": userID,
+	})
+
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if user == nil {
+		return ErrNotFound
+	}
+
+	twoFactor, err := a.twoFactorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if !twoFactor.IsEnabled() {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if err = a.loginLockout.check(ctx, user.Email, ""); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if _, err = a.verifyTwoFactorCode(ctx, twoFactor, user.Email, "", code); err != nil {
+		return err
+	}
+
+	return a.twoFactorRepo.DeleteByUserID(ctx, userID)
+}
+
+// verifyTwoFactorCode accept a TOTP code, which can't be replayed, or an unused recovery code.
+// locked is true when a wrong code locked the account or the IP.
+func (a *authUsecase) verifyTwoFactorCode(ctx context.Context, twoFactor *model.UserTwoFactor, email, ipAddress, code string) (locked bool, err error) {
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID": twoFactor.UserID,
+	})
+
+	var ok bool
+	code = strings.TrimSpace(code)
+	if len(code) == helper.TOTPDigits {
+		var step int64
+		if step, ok = helper.ValidateTOTPCode(twoFactor.Secret, code, a.now()); ok {
+			ok, err = a.twoFactorRepo.UseTimeStep(ctx, twoFactor.UserID, step)
+		}
+	} else if code != "" {
+		ok, err = a.twoFactorRepo.UseRecoveryCode(ctx, twoFactor.UserID, helper.HashRecoveryCode(code), a.now())
+	}
+	if err != nil {
+		logger.Error(err)
+		return false, err
+	}
+
+	if !ok {
+		if locked, err = a.loginLockout.recordFailure(ctx, email, ipAddress); err != nil {
+			logger.Error(err)
+			return false, err
+		}
+		return locked, ErrInvalidTwoFactorCode
+	}
+
+	return false, nil
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/cacher"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type twoFactorTestSuite struct {
+	usecase       *authUsecase
+	twoFactorRepo *fakeTwoFactorRepository
+	authEventRepo *fakeAuthEventRepository
+	user          *model.User
+	now           time.Time
+}
+
+// newTwoFactorTestSuite an auth usecase with a fixed clock and a user who enabled the 2FA
+func newTwoFactorTestSuite(t *testing.T) *twoFactorTestSuite {
+	t.Helper()
+
+	s := &twoFactorTestSuite{
+		twoFactorRepo: newFakeTwoFactorRepository(),
+		authEventRepo: &fakeAuthEventRepository{},
+		user:          &model.User{ID: 1, Email: "johndoe@mail.com", Username: "johndoe"},
+		now:           time.Unix(1234567890, 0),
+	}
+	s.usecase = NewAuthUsecase(
+		newFakeUserRepository(s.user),
+		&fakeSessionRepository{},
+		nil,
+		s.twoFactorRepo,
+		repository.NewLoginLockoutRepository(cacher.NewMemoryCacheManager()),
+		s.authEventRepo,
+	).(*authUsecase)
+	s.usecase.now = func() time.Time { return s.now }
+	s.usecase.loginLockout.now = s.usecase.now
+
+	ctx := context.Background()
+	enrollment, err := s.usecase.EnrollTwoFactor(ctx, s.user.ID)
+	require.NoError(t, err)
+
+	// enabling use the current step, the login tests start two steps later so the previous step is unused
+	code := s.code(t, enrollment.Secret, 0)
+	_, err = s.usecase.EnableTwoFactor(ctx, s.user.ID, code)
+	require.NoError(t, err)
+	s.now = s.now.Add(2 * helper.TOTPPeriod * time.Second)
+
+	return s
+}
+
+// code the TOTP code of the step at the given offset from the current one
+func (s *twoFactorTestSuite) code(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := helper.GenerateTOTPCode(secret, helper.TOTPTimeStep(s.now)+offset)
+	require.NoError(t, err)
+	return code
+}
+
+func (s *twoFactorTestSuite) secret() string {
+	return s.twoFactorRepo.twoFactors[s.user.ID].Secret
+}
+
+// newChallenge create a login challenge like the first login step, it expire after the challenge TTL
+func (s *twoFactorTestSuite) newChallenge(t *testing.T, token string) {
+	t.Helper()
+
+	err := s.twoFactorRepo.CreateLoginChallenge(context.Background(), &model.LoginChallenge{
+		Token:     token,
+		UserID:    s.user.ID,
+		Email:     s.user.Email,
+		ExpiredAt: s.now.Add(5 * time.Minute),
+	})
+	require.NoError(t, err)
+}
+
+func (s *twoFactorTestSuite) verify(token, code string) (*model.Session, error) {
+	return s.usecase.VerifyLoginChallenge(context.Background(), model.VerifyLoginChallengeRequest{
+		ChallengeToken: token,
+		Code:           code,
+		IPAddress:      "10.0.0.1",
+		UserAgent:      "test",
+	})
+}
+
+func TestVerifyLoginChallenge_TOTPWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int64
+		err    error
+	}{
+		{name: "previous step", offset: -1},
+		{name: "current step", offset: 0},
+		{name: "next step", offset: 1},
+		{name: "two steps before", offset: -2, err: ErrInvalidTwoFactorCode},
+		{name: "two steps after", offset: 2, err: ErrInvalidTwoFactorCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTwoFactorTestSuite(t)
+			s.newChallenge(t, "challenge")
+
+			session, err := s.verify("challenge", s.code(t, s.secret(), tt.offset))
+			if tt.err != nil {
+				assert.Equal(t, tt.err, err)
+				assert.Nil(t, session)
+				// a wrong code doesn't consume the challenge
+				assert.Contains(t, s.twoFactorRepo.challenges, "challenge")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, s.user.ID, session.UserID)
+			// the challenge is single use
+			assert.NotContains(t, s.twoFactorRepo.challenges, "challenge")
+		})
+	}
+}
+
+func TestVerifyLoginChallenge_ReplayedTOTPCode(t *testing.T) {
+	s := newTwoFactorTestSuite(t)
+	code := s.code(t, s.secret(), 0)
+
+	s.newChallenge(t, "first")
+	_, err := s.verify("first", code)
+	require.NoError(t, err)
+
+	s.newChallenge(t, "second")
+	_, err = s.verify("second", code)
+	assert.Equal(t, ErrInvalidTwoFactorCode, err)
+
+	// an older step is a replay too, once a later step is used
+	_, err = s.verify("second", s.code(t, s.secret(), -1))
+	assert.Equal(t, ErrInvalidTwoFactorCode, err)
+
+	_, err = s.verify("second", s.code(t, s.secret(), 1))
+	assert.NoError(t, err)
+}
+
+func TestVerifyLoginChallenge_RecoveryCodeIsSingleUse(t *testing.T) {
+	s := newTwoFactorTestSuite(t)
+
+	// the codes shown to the user are only kept as hashes, so enable the 2FA again to know them
+	require.NoError(t, s.twoFactorRepo.DeleteByUserID(context.Background(), s.user.ID))
+	enrollment, err := s.usecase.EnrollTwoFactor(context.Background(), s.user.ID)
+	require.NoError(t, err)
+	recoveryCodes, err := s.usecase.EnableTwoFactor(context.Background(), s.user.ID, s.code(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+	require.NotEmpty(t, recoveryCodes)
+
+	s.newChallenge(t, "first")
+	_, err = s.verify("first", recoveryCodes[0])
+	require.NoError(t, err)
+
+	s.newChallenge(t, "second")
+	_, err = s.verify("second", recoveryCodes[0])
+	assert.Equal(t, ErrInvalidTwoFactorCode, err)
+
+	// the other codes are still usable
+	_, err = s.verify("second", recoveryCodes[1])
+	assert.NoError(t, err)
+}
+
+func TestVerifyLoginChallenge_Expired(t *testing.T) {
+	s := newTwoFactorTestSuite(t)
+	s.newChallenge(t, "challenge")
+	code := s.code(t, s.secret(), 0)
+
+	// the cache outlive the challenge, its expiry is still enforced
+	s.now = s.now.Add(5 * time.Minute)
+	_, err := s.verify("challenge", code)
+	assert.Equal(t, ErrLoginChallengeExpired, err)
+
+	_, err = s.verify("unknown", code)
+	assert.Equal(t, ErrLoginChallengeExpired, err)
+}
+
+func TestVerifyLoginChallenge_WrongCodesLockTheAccount(t *testing.T) {
+	s := newTwoFactorTestSuite(t)
+	s.newChallenge(t, "challenge")
+
+	// the account is allowed 3 failures, the next one lock it
+	for i := 0; i < 4; i++ {
+		_, err := s.verify("challenge", "000000")
+		assert.Equal(t, ErrInvalidTwoFactorCode, err)
+	}
+
+	_, err := s.verify("challenge", s.code(t, s.secret(), 0))
+	assert.Equal(t, ErrLoginMaxAttempts, err)
+
+	assert.Equal(t, []string{
+		"TWO_FACTOR_LOGIN:FAILURE",
+		"TWO_FACTOR_LOGIN:FAILURE",
+		"TWO_FACTOR_LOGIN:FAILURE",
+		"TWO_FACTOR_LOGIN:FAILURE",
+		"LOCKOUT:FAILURE",
+		"TWO_FACTOR_LOGIN:FAILURE",
+	}, s.authEventRepo.eventTypes())
+}
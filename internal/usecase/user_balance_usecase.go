@@ -3,10 +3,17 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/metrics"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/repository"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
 	"github.com/irvankadhafi/user-balance-transfer-service/utils"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+	"time"
 )
 
 type userBalanceUsecase struct {
@@ -15,6 +22,12 @@ type userBalanceUsecase struct {
 	userBalanceHistoryRepo model.UserBalanceHistoryRepository
 	gormTransactioner      repository.GormTransactioner
 	sessionRepo            model.SessionRepository
+	creditImportRepo       model.CreditImportRepository
+	transferLimitRepo      model.TransferLimitRepository
+	transferRepo           model.TransferRepository
+	transferScreener       model.TransferScreener
+	outboxRepo             model.OutboxRepository
+	balanceBroker          model.BalanceUpdateBroker
 }
 
 func NewUserBalanceUsecase(
@@ -23,6 +36,12 @@ func NewUserBalanceUsecase(
 	userBalanceHistoryRepo model.UserBalanceHistoryRepository,
 	gormTransactioner repository.GormTransactioner,
 	sessionRepo model.SessionRepository,
+	creditImportRepo model.CreditImportRepository,
+	transferLimitRepo model.TransferLimitRepository,
+	transferRepo model.TransferRepository,
+	transferScreener model.TransferScreener,
+	outboxRepo model.OutboxRepository,
+	balanceBroker model.BalanceUpdateBroker,
 ) model.UserBalanceUsecase {
 	return &userBalanceUsecase{
 		userRepo:               userRepo,
@@ -30,15 +49,23 @@ func NewUserBalanceUsecase(
 		userBalanceHistoryRepo: userBalanceHistoryRepo,
 		gormTransactioner:      gormTransactioner,
 		sessionRepo:            sessionRepo,
+		creditImportRepo:       creditImportRepo,
+		transferLimitRepo:      transferLimitRepo,
+		transferRepo:           transferRepo,
+		transferScreener:       transferScreener,
+		outboxRepo:             outboxRepo,
+		balanceBroker:          balanceBroker,
 	}
 }
 
 func (u *userBalanceUsecase) AddUserBalance(ctx context.Context, input model.AddUserBalanceInput) error {
-	if input.UserID <= 0 || input.Balance <= 0 {
-		return ErrFailedPrecondition
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.AddUserBalance")
+	defer span.End()
+
+	if err := input.Validate(); err != nil {
+		return err
 	}
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":   utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"input": utils.Dump(input),
 	})
 
@@ -48,20 +75,29 @@ func (u *userBalanceUsecase) AddUserBalance(ctx context.Context, input model.Add
 		logger.Error(err)
 		return err
 	}
+	if session == nil {
+		return ErrNotFound
+	}
 
-	// Get the current user balance
-	balance, err := u.userBalanceRepo.GetCurrentUserBalanceByUserID(ctx, input.UserID)
+	activity := "Add Balance"
+
+	// the balance is read under the lock, so a concurrent transfer of the same user isn't overwritten
+	tx := u.gormTransactioner.Begin(ctx)
+	lockedBalances, err := u.userBalanceRepo.FindByUserIDsForUpdateWithTransaction(ctx, tx, []int{input.UserID})
 	if err != nil {
 		logger.Error(err)
+		u.gormTransactioner.Rollback(tx)
 		return err
 	}
 
-	activity := "Add Balance"
+	balance := &model.UserBalance{UserID: input.UserID}
+	if len(lockedBalances) > 0 {
+		balance = lockedBalances[0]
+	}
 
-	tx := u.gormTransactioner.Begin(ctx)
+	balanceBefore := balance.Balance
 	balance.Balance += input.Balance
 	balance.BalanceAchieve += input.Balance
-	balance.UserID = input.UserID
 	err = u.userBalanceRepo.UpsertWithTransaction(ctx, tx, balance)
 	if err != nil {
 		logger.Error(err)
@@ -71,12 +107,12 @@ func (u *userBalanceUsecase) AddUserBalance(ctx context.Context, input model.Add
 
 	userBalanceAudit := &model.UserBalanceHistory{
 		UserBalanceID: balance.ID,
-		BalanceBefore: balance.BalanceAchieve - input.Balance,
-		BalanceAfter:  balance.BalanceAchieve,
+		BalanceBefore: balanceBefore,
+		BalanceAfter:  balance.Balance,
 		Activity:      activity,
 		Type:          model.CREDIT,
-		IPAddress:     session.Location,
-		Location:      session.IPAddress,
+		IPAddress:     session.IPAddress,
+		Location:      session.Location,
 		UserAgent:     session.UserAgent,
 		Author:        input.Author,
 	}
@@ -87,116 +123,563 @@ func (u *userBalanceUsecase) AddUserBalance(ctx context.Context, input model.Add
 		return err
 	}
 
+	err = u.createOutboxMessagesWithTransaction(ctx, tx, newBalanceWebhookEvent(userBalanceAudit, input.UserID, model.WebhookEventBalanceCredited))
+	if err != nil {
+		logger.Error(err)
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
 	if err = u.gormTransactioner.Commit(tx); err != nil {
 		logger.Error(err)
 		return err
 	}
 
+	u.publishBalanceUpdates(ctx, newBalanceUpdate(input.UserID, balance, userBalanceAudit))
+
 	return nil
 }
 
-func (u *userBalanceUsecase) TransferUserBalance(ctx context.Context, input model.TransferUserBalanceInput) error {
-	if input.FromUserID <= 0 || input.ToUserID <= 0 || input.Balance <= 0 {
-		return ErrFailedPrecondition
+// TransferUserBalance transfer balance to another user. The transfer is screened before it is executed,
+// a denied transfer return ErrTransferDenied and a flagged transfer is saved as pending review.
+func (u *userBalanceUsecase) TransferUserBalance(ctx context.Context, input model.TransferUserBalanceInput) (*model.Transfer, error) {
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.TransferUserBalance")
+	defer span.End()
+
+	if err := input.Validate(); err != nil {
+		return nil, err
 	}
 
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":   utils.DumpIncomingContext(ctx),
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"input": utils.Dump(input),
 	})
 
 	session, err := u.sessionRepo.FindByID(ctx, input.SessionID)
 	if err != nil {
 		logger.Error(err)
-		return err
+		return nil, err
 	}
 
 	// Check user tujuan transfer
 	toUser, err := u.userRepo.FindByID(ctx, input.ToUserID)
 	if err != nil {
 		logger.Error(err)
-		return err
+		return nil, err
 	}
 	if toUser == nil {
-		return ErrNotFound
+		return nil, ErrNotFound
 	}
 
 	fromUser, err := u.userRepo.FindByID(ctx, input.FromUserID)
 	if err != nil {
 		logger.Error(err)
-		return err
+		return nil, err
 	}
 	if fromUser == nil {
-		return ErrNotFound
+		return nil, ErrNotFound
+	}
+
+	if err = requireTransactionPin(ctx, u.userRepo, fromUser.ID, input.Balance, input.Pin); err != nil {
+		return nil, err
+	}
+
+	limit, err := u.findTransferLimit(ctx, fromUser.ID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if err = checkTransferAmount(limit, input.Balance); err != nil {
+		observeTransferRejected(err)
+		return nil, err
 	}
 
 	// get the current balance for the from user
 	fromBalance, err := u.userBalanceRepo.GetCurrentUserBalanceByUserID(ctx, fromUser.ID)
 	if err != nil {
 		logger.Error(err)
-		return err
+		return nil, err
 	}
 
-	if fromBalance.BalanceAchieve < input.Balance {
-		return ErrBalanceNotEnough
+	if fromBalance.Balance < input.Balance {
+		observeTransferRejected(ErrBalanceNotEnough)
+		return nil, ErrBalanceNotEnough
 	}
 
-	// get the current balance for the to user
-	toBalance, err := u.userBalanceRepo.GetCurrentUserBalanceByUserID(ctx, toUser.ID)
+	screening, err := u.transferScreener.Screen(ctx, model.TransferScreeningRequest{
+		FromUserID: fromUser.ID,
+		ToUserID:   toUser.ID,
+		Amount:     input.Balance,
+		Session:    session,
+	})
 	if err != nil {
 		logger.Error(err)
-		return err
+		return nil, err
+	}
+
+	transfer := &model.Transfer{
+		FromUserID:       fromUser.ID,
+		ToUserID:         toUser.ID,
+		Amount:           input.Balance,
+		SessionID:        input.SessionID,
+		Author:           input.Author,
+		ScreeningReasons: strings.Join(screening.Reasons, "; "),
+	}
+	switch screening.Decision {
+	case model.ScreeningDecisionDeny:
+		logger.WithField("screening", utils.Dump(screening)).Warn(ErrTransferDenied)
+		observeTransferRejected(ErrTransferDenied)
+		return nil, ErrTransferDenied
+	case model.ScreeningDecisionReview:
+		transfer.Status = model.TransferStatusPendingReview
+		if err = u.createPendingTransfer(ctx, transfer); err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		metrics.ObserveTransferPendingReview()
+		return transfer, nil
+	}
+
+	if err = u.executeTransfer(ctx, session, transfer, limit, fromUser, toUser); err != nil {
+		logger.Error(err)
+		observeTransferRejected(err)
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// FindPendingTransfers find the transfers waiting for review, only an admin can see them
+func (u *userBalanceUsecase) FindPendingTransfers(ctx context.Context, reviewerID int) ([]*model.Transfer, error) {
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.FindPendingTransfers")
+	defer span.End()
+
+	if err := u.mustBeAdmin(ctx, reviewerID); err != nil {
+		return nil, err
+	}
+
+	transfers, err := u.transferRepo.FindByStatus(ctx, model.TransferStatusPendingReview)
+	if err != nil {
+		logrus.WithField("reviewerID", reviewerID).Error(err)
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// ReviewPendingTransfer approve or reject a transfer that is pending review, an approved transfer is executed immediately
+func (u *userBalanceUsecase) ReviewPendingTransfer(ctx context.Context, reviewerID, transferID int, approve bool) (*model.Transfer, error) {
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.ReviewPendingTransfer")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"reviewerID": reviewerID,
+		"transferID": transferID,
+		"approve":    approve,
+	})
+
+	if err := u.mustBeAdmin(ctx, reviewerID); err != nil {
+		return nil, err
+	}
+
+	transfer, err := u.transferRepo.FindByID(ctx, transferID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	switch {
+	case transfer == nil:
+		return nil, ErrNotFound
+	case transfer.Status != model.TransferStatusPendingReview:
+		return nil, ErrTransferNotPending
+	}
+
+	now := time.Now()
+	transfer.ReviewedBy = &reviewerID
+	transfer.ReviewedAt = &now
+
+	if !approve {
+		tx := u.gormTransactioner.Begin(ctx)
+		locked, err := u.transferRepo.FindByIDForUpdateWithTransaction(ctx, tx, transferID)
+		if err != nil {
+			logger.Error(err)
+			u.gormTransactioner.Rollback(tx)
+			return nil, err
+		}
+		if locked == nil || locked.Status != model.TransferStatusPendingReview {
+			u.gormTransactioner.Rollback(tx)
+			return nil, ErrTransferNotPending
+		}
+
+		transfer.Status = model.TransferStatusRejected
+		if err = u.transferRepo.UpsertWithTransaction(ctx, tx, transfer); err != nil {
+			logger.Error(err)
+			u.gormTransactioner.Rollback(tx)
+			return nil, err
+		}
+
+		err = u.createOutboxMessagesWithTransaction(ctx, tx, newTransferWebhookEvent(model.WebhookEventTransferRejected, transfer.FromUserID, transfer))
+		if err != nil {
+			logger.Error(err)
+			u.gormTransactioner.Rollback(tx)
+			return nil, err
+		}
+
+		if err = u.gormTransactioner.Commit(tx); err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		metrics.ObserveTransferRejected(transferReviewRejectedReason)
+		return transfer, nil
+	}
+
+	fromUser, err := u.userRepo.FindByID(ctx, transfer.FromUserID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	toUser, err := u.userRepo.FindByID(ctx, transfer.ToUserID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if fromUser == nil || toUser == nil {
+		return nil, ErrNotFound
+	}
+
+	session, err := u.sessionRepo.FindByID(ctx, transfer.SessionID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	limit, err := u.findTransferLimit(ctx, fromUser.ID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	// the payer limits are checked again, a flagged transfer is not counted in the usage until it's approved
+	if err = u.executeTransfer(ctx, session, transfer, limit, fromUser, toUser); err != nil {
+		logger.Error(err)
+		observeTransferRejected(err)
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// TransferUserBalanceBatch transfer balance from a single payer to many recipients in one transaction.
+// In atomic mode any failed item aborts the whole batch, while in best effort mode
+// only the failed item is rolled back and reported in its result.
+func (u *userBalanceUsecase) TransferUserBalanceBatch(ctx context.Context, input model.TransferUserBalanceBatchInput) ([]*model.TransferBatchItemResult, error) {
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.TransferUserBalanceBatch")
+	defer span.End()
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if len(input.Items) > config.TransferBatchMaxItems() {
+		return nil, ErrTransferBatchLimit
+	}
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"input": utils.Dump(input),
+	})
+
+	session, err := u.sessionRepo.FindByID(ctx, input.SessionID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrNotFound
+	}
+
+	fromUser, err := u.userRepo.FindByID(ctx, input.FromUserID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if fromUser == nil {
+		return nil, ErrNotFound
+	}
+
+	// the pin is required by the total amount, so a big transfer can't be split into small items
+	var totalAmount int64
+	for _, item := range input.Items {
+		if item.Balance > 0 {
+			totalAmount += item.Balance
+		}
+	}
+	if err = requireTransactionPin(ctx, u.userRepo, fromUser.ID, totalAmount, input.Pin); err != nil {
+		return nil, err
+	}
+
+	results := make([]*model.TransferBatchItemResult, len(input.Items))
+	recipients := make(map[int]*model.User)
+	userIDs := []int{fromUser.ID}
+	for i, item := range input.Items {
+		results[i] = &model.TransferBatchItemResult{ToUserID: item.ToUserID, Balance: item.Balance}
+		switch {
+		case item.Balance <= 0:
+			failTransferBatchItem(results[i], "balance must be greater than zero")
+			continue
+		case item.ToUserID == fromUser.ID:
+			failTransferBatchItem(results[i], "cannot transfer to yourself")
+			continue
+		}
+
+		if _, ok := recipients[item.ToUserID]; ok {
+			continue
+		}
+
+		toUser, err := u.userRepo.FindByID(ctx, item.ToUserID)
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		if toUser == nil {
+			failTransferBatchItem(results[i], ErrNotFound.Error())
+			continue
+		}
+
+		recipients[toUser.ID] = toUser
+		userIDs = append(userIDs, toUser.ID)
+	}
+
+	// every item is screened like a single transfer, with the other recipients of the batch counted by the fan-out rule
+	screenings, err := u.screenTransferBatch(ctx, session, fromUser.ID, results)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	if input.Mode == model.TransferBatchModeAtomic && hasFailedTransferBatchItem(results) {
+		return abortTransferBatch(results), ErrTransferBatchFailed
 	}
 
+	limit, err := u.findTransferLimit(ctx, fromUser.ID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	// lock every involved balance in a stable order before touching any of them
+	sort.Ints(userIDs)
 	tx := u.gormTransactioner.Begin(ctx)
-	fromBalance.Balance -= input.Balance
-	err = u.userBalanceRepo.UpsertWithTransaction(ctx, tx, fromBalance)
+	lockedBalances, err := u.userBalanceRepo.FindByUserIDsForUpdateWithTransaction(ctx, tx, userIDs)
+	if err != nil {
+		logger.Error(err)
+		u.gormTransactioner.Rollback(tx)
+		return nil, err
+	}
+
+	// the usage is read under the payer lock, a concurrent transfer of the payer wait until this batch end
+	usage, err := u.transferLimitRepo.GetOutgoingUsageWithTransaction(ctx, tx, fromUser.ID, time.Now())
 	if err != nil {
 		logger.Error(err)
 		u.gormTransactioner.Rollback(tx)
+		return nil, err
+	}
+
+	balances := make(map[int]*model.UserBalance, len(userIDs))
+	for _, balance := range lockedBalances {
+		balances[balance.UserID] = balance
+	}
+	for _, userID := range userIDs {
+		if _, ok := balances[userID]; !ok {
+			balances[userID] = &model.UserBalance{UserID: userID}
+		}
+	}
+
+	fromBalance := balances[fromUser.ID]
+	var (
+		events  []*model.WebhookEvent
+		updates []*model.BalanceUpdate
+	)
+	for i, item := range input.Items {
+		result := results[i]
+		if result.Status == model.TransferBatchItemStatusFailed {
+			continue
+		}
+
+		if err = checkTransferLimit(limit, usage, item.Balance); err != nil {
+			observeTransferRejected(err)
+			failTransferBatchItem(result, err.Error())
+			if input.Mode == model.TransferBatchModeAtomic {
+				u.gormTransactioner.Rollback(tx)
+				return abortTransferBatch(results), ErrTransferBatchFailed
+			}
+			continue
+		}
+
+		if fromBalance.Balance < item.Balance {
+			observeTransferRejected(ErrBalanceNotEnough)
+			failTransferBatchItem(result, ErrBalanceNotEnough.Error())
+			if input.Mode == model.TransferBatchModeAtomic {
+				u.gormTransactioner.Rollback(tx)
+				return abortTransferBatch(results), ErrTransferBatchFailed
+			}
+			continue
+		}
+
+		toUser := recipients[item.ToUserID]
+		toBalance := balances[item.ToUserID]
+		transfer := &model.Transfer{
+			FromUserID:       fromUser.ID,
+			ToUserID:         toUser.ID,
+			Amount:           item.Balance,
+			Status:           model.TransferStatusCompleted,
+			SessionID:        input.SessionID,
+			Author:           input.Author,
+			ScreeningReasons: strings.Join(screenings[i].Reasons, "; "),
+		}
+		if screenings[i].Decision == model.ScreeningDecisionReview {
+			transfer.Status = model.TransferStatusPendingReview
+		}
+
+		// in best effort mode a failed item is rolled back to its save point
+		savePoint := fmt.Sprintf("transfer_batch_item_%d", i)
+		if input.Mode == model.TransferBatchModeBestEffort {
+			if err = u.gormTransactioner.SavePoint(tx, savePoint); err != nil {
+				logger.Error(err)
+				u.gormTransactioner.Rollback(tx)
+				return nil, err
+			}
+		}
+
+		fromSnapshot, toSnapshot := *fromBalance, *toBalance
+		itemUpdates, itemEvents, err := u.transferBatchItemWithTransaction(ctx, tx, session, transfer, fromUser, fromBalance, toUser, toBalance)
+		if err != nil {
+			logger.Error(err)
+			if input.Mode == model.TransferBatchModeAtomic {
+				u.gormTransactioner.Rollback(tx)
+				return nil, err
+			}
+			if err = u.gormTransactioner.RollbackTo(tx, savePoint); err != nil {
+				logger.Error(err)
+				u.gormTransactioner.Rollback(tx)
+				return nil, err
+			}
+			*fromBalance, *toBalance = fromSnapshot, toSnapshot
+			failTransferBatchItem(result, "system error")
+			continue
+		}
+
+		result.TransferID = transfer.ID
+		result.Status = model.TransferBatchItemStatusSuccess
+		if transfer.Status == model.TransferStatusPendingReview {
+			result.Status = model.TransferBatchItemStatusPendingReview
+		} else {
+			addTransferUsage(usage, item.Balance)
+		}
+		events = append(events, itemEvents...)
+		updates = append(updates, itemUpdates...)
+	}
+
+	if err = u.createOutboxMessagesWithTransaction(ctx, tx, events...); err != nil {
+		logger.Error(err)
+		u.gormTransactioner.Rollback(tx)
+		return nil, err
+	}
+
+	if err = u.gormTransactioner.Commit(tx); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	u.publishBalanceUpdates(ctx, updates...)
+
+	for _, result := range results {
+		switch result.Status {
+		case model.TransferBatchItemStatusSuccess:
+			metrics.ObserveTransferExecuted(result.Balance)
+		case model.TransferBatchItemStatusPendingReview:
+			metrics.ObserveTransferPendingReview()
+		}
+	}
+
+	return results, nil
+}
+
+// ImportUserBalanceCredit add balance to the user like AddUserBalance, but without a session and only once per reference
+func (u *userBalanceUsecase) ImportUserBalanceCredit(ctx context.Context, input model.ImportUserBalanceCreditInput) error {
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.ImportUserBalanceCredit")
+	defer span.End()
+
+	if err := input.Validate(); err != nil {
 		return err
 	}
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"input": utils.Dump(input),
+	})
 
-	fromUserBalanceAudit := &model.UserBalanceHistory{
-		UserBalanceID: fromBalance.ID,
-		BalanceBefore: fromBalance.BalanceAchieve,
-		BalanceAfter:  fromBalance.Balance,
-		Activity:      fmt.Sprintf("Transfer to %s", toUser.Username),
-		Type:          model.DEBIT,
-		IPAddress:     session.Location,
-		Location:      session.IPAddress,
-		UserAgent:     session.UserAgent,
-		Author:        input.Author,
+	tx := u.gormTransactioner.Begin(ctx)
+	lockedBalances, err := u.userBalanceRepo.FindByUserIDsForUpdateWithTransaction(ctx, tx, []int{input.UserID})
+	if err != nil {
+		logger.Error(err)
+		u.gormTransactioner.Rollback(tx)
+		return err
 	}
-	err = u.userBalanceHistoryRepo.CreateWithTransaction(ctx, tx, fromUserBalanceAudit)
+
+	// the reference is checked after the lock, so a concurrent import of the same user can't pass it twice
+	existing, err := u.creditImportRepo.FindByReferenceWithTransaction(ctx, tx, input.Reference)
 	if err != nil {
 		logger.Error(err)
 		u.gormTransactioner.Rollback(tx)
 		return err
 	}
+	if existing != nil {
+		u.gormTransactioner.Rollback(tx)
+		return ErrDuplicateReference
+	}
+
+	balance := &model.UserBalance{UserID: input.UserID}
+	if len(lockedBalances) > 0 {
+		balance = lockedBalances[0]
+	}
 
-	toBalance.Balance += input.Balance
-	toBalance.BalanceAchieve += input.Balance
-	toBalance.UserID = toUser.ID
-	err = u.userBalanceRepo.UpsertWithTransaction(ctx, tx, toBalance)
+	balanceBefore := balance.Balance
+	balance.Balance += input.Balance
+	balance.BalanceAchieve += input.Balance
+	err = u.userBalanceRepo.UpsertWithTransaction(ctx, tx, balance)
 	if err != nil {
 		logger.Error(err)
 		u.gormTransactioner.Rollback(tx)
 		return err
 	}
 
-	toUserBalanceAudit := &model.UserBalanceHistory{
-		UserBalanceID: toBalance.ID,
-		BalanceBefore: toBalance.BalanceAchieve,
-		BalanceAfter:  toBalance.Balance,
-		Activity:      fmt.Sprintf("Transfer from %s", fromUser.Username),
+	userBalanceAudit := &model.UserBalanceHistory{
+		UserBalanceID: balance.ID,
+		BalanceBefore: balanceBefore,
+		BalanceAfter:  balance.Balance,
+		Activity:      fmt.Sprintf("Import Credit %s", input.Reference),
 		Type:          model.CREDIT,
-		IPAddress:     session.Location,
-		Location:      session.IPAddress,
-		UserAgent:     session.UserAgent,
+		IPAddress:     "-",
+		Location:      "-",
+		UserAgent:     "-",
 		Author:        input.Author,
 	}
-	err = u.userBalanceHistoryRepo.CreateWithTransaction(ctx, tx, toUserBalanceAudit)
+	err = u.userBalanceHistoryRepo.CreateWithTransaction(ctx, tx, userBalanceAudit)
+	if err != nil {
+		logger.Error(err)
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	err = u.creditImportRepo.CreateWithTransaction(ctx, tx, &model.CreditImport{
+		Reference:            input.Reference,
+		UserID:               input.UserID,
+		Amount:               input.Balance,
+		UserBalanceHistoryID: userBalanceAudit.ID,
+	})
+	if err != nil {
+		logger.Error(err)
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	err = u.createOutboxMessagesWithTransaction(ctx, tx, newBalanceWebhookEvent(userBalanceAudit, input.UserID, model.WebhookEventBalanceCredited))
 	if err != nil {
 		logger.Error(err)
 		u.gormTransactioner.Rollback(tx)
@@ -208,12 +691,74 @@ func (u *userBalanceUsecase) TransferUserBalance(ctx context.Context, input mode
 		return err
 	}
 
+	u.publishBalanceUpdates(ctx, newBalanceUpdate(input.UserID, balance, userBalanceAudit))
+
 	return nil
 }
 
+// GetUserBalanceStatement return the balance histories of the user in [from, to) with its opening and closing balance
+func (u *userBalanceUsecase) GetUserBalanceStatement(ctx context.Context, userID int, from, to time.Time) (*model.UserBalanceStatement, error) {
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.GetUserBalanceStatement")
+	defer span.End()
+
+	if userID <= 0 || !from.Before(to) {
+		return nil, ErrInvalidStatementPeriod
+	}
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID": userID,
+		"from":   from,
+		"to":     to,
+	})
+
+	statement := &model.UserBalanceStatement{
+		UserID: userID,
+		From:   from,
+		To:     to,
+		Totals: map[model.TransactionType]int64{
+			model.CREDIT: 0,
+			model.DEBIT:  0,
+		},
+	}
+
+	balance, err := u.userBalanceRepo.GetCurrentUserBalanceByUserID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if balance.ID <= 0 {
+		return statement, nil
+	}
+
+	lastHistory, err := u.userBalanceHistoryRepo.FindLastByUserBalanceIDBefore(ctx, balance.ID, from)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if lastHistory != nil {
+		statement.OpeningBalance = lastHistory.BalanceAfter
+	}
+
+	histories, err := u.userBalanceHistoryRepo.FindByUserBalanceIDAndPeriod(ctx, balance.ID, from, to)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	statement.Histories = histories
+	statement.ClosingBalance = statement.OpeningBalance
+	for _, history := range histories {
+		statement.Totals[history.Type] += history.Amount()
+		statement.ClosingBalance = history.BalanceAfter
+	}
+
+	return statement, nil
+}
+
 func (u *userBalanceUsecase) GetCurrentUserBalanceByUserID(ctx context.Context, userID int) (*model.UserBalance, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":    utils.DumpIncomingContext(ctx),
+	ctx, span := tracing.StartSpan(ctx, "userBalanceUsecase.GetCurrentUserBalanceByUserID")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"userID": userID,
 	})
 	// Get the current user balance
@@ -225,3 +770,271 @@ func (u *userBalanceUsecase) GetCurrentUserBalanceByUserID(ctx context.Context,
 
 	return balance, nil
 }
+
+// executeTransfer lock both balances, move the amount and save the transfer as completed in one transaction.
+// A transfer that is already saved must still be pending review when it is locked,
+// and the limit is checked against the usage read under the payer lock.
+func (u *userBalanceUsecase) executeTransfer(ctx context.Context, session *model.Session, transfer *model.Transfer, limit model.TransferLimit, fromUser, toUser *model.User) error {
+	if session == nil {
+		// the session may be deleted while the transfer is pending review
+		session = &model.Session{IPAddress: "-", Location: "-", UserAgent: "-"}
+	}
+
+	tx := u.gormTransactioner.Begin(ctx)
+	if transfer.ID > 0 {
+		locked, err := u.transferRepo.FindByIDForUpdateWithTransaction(ctx, tx, transfer.ID)
+		if err != nil {
+			u.gormTransactioner.Rollback(tx)
+			return err
+		}
+		if locked == nil || locked.Status != model.TransferStatusPendingReview {
+			u.gormTransactioner.Rollback(tx)
+			return ErrTransferNotPending
+		}
+	}
+
+	userIDs := []int{fromUser.ID, toUser.ID}
+	sort.Ints(userIDs)
+	lockedBalances, err := u.userBalanceRepo.FindByUserIDsForUpdateWithTransaction(ctx, tx, userIDs)
+	if err != nil {
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	fromBalance := &model.UserBalance{UserID: fromUser.ID}
+	toBalance := &model.UserBalance{UserID: toUser.ID}
+	for _, balance := range lockedBalances {
+		switch balance.UserID {
+		case fromUser.ID:
+			fromBalance = balance
+		case toUser.ID:
+			toBalance = balance
+		}
+	}
+
+	// the usage is read under the payer lock, a concurrent transfer of the payer wait until this one end
+	usage, err := u.transferLimitRepo.GetOutgoingUsageWithTransaction(ctx, tx, fromUser.ID, time.Now())
+	if err != nil {
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+	if err = checkTransferLimit(limit, usage, transfer.Amount); err != nil {
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	if fromBalance.Balance < transfer.Amount {
+		u.gormTransactioner.Rollback(tx)
+		return ErrBalanceNotEnough
+	}
+
+	updates, err := u.transferBalanceWithTransaction(ctx, tx, session, transfer.Author, fromUser, fromBalance, toUser, toBalance, transfer.Amount)
+	if err != nil {
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	transfer.Status = model.TransferStatusCompleted
+	if err = u.transferRepo.UpsertWithTransaction(ctx, tx, transfer); err != nil {
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	err = u.createOutboxMessagesWithTransaction(ctx, tx, newCompletedTransferWebhookEvents(transfer, fromUser, fromBalance, toUser, toBalance)...)
+	if err != nil {
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	if err = u.gormTransactioner.Commit(tx); err != nil {
+		return err
+	}
+
+	metrics.ObserveTransferExecuted(transfer.Amount)
+	u.publishBalanceUpdates(ctx, updates...)
+	return nil
+}
+
+// createPendingTransfer save the transfer held for review with its event
+func (u *userBalanceUsecase) createPendingTransfer(ctx context.Context, transfer *model.Transfer) error {
+	tx := u.gormTransactioner.Begin(ctx)
+	if err := u.transferRepo.UpsertWithTransaction(ctx, tx, transfer); err != nil {
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	err := u.createOutboxMessagesWithTransaction(ctx, tx, newTransferWebhookEvent(model.WebhookEventTransferPendingReview, transfer.FromUserID, transfer))
+	if err != nil {
+		u.gormTransactioner.Rollback(tx)
+		return err
+	}
+
+	return u.gormTransactioner.Commit(tx)
+}
+
+func (u *userBalanceUsecase) mustBeAdmin(ctx context.Context, userID int) error {
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		logrus.WithField("userID", userID).Error(err)
+		return err
+	}
+	if user == nil || !user.IsAdmin() {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// transferBalanceWithTransaction move the balance between two locked balances and write the audit of both sides,
+// the returned updates are published once the transaction is committed
+func (u *userBalanceUsecase) transferBalanceWithTransaction(
+	ctx context.Context,
+	tx *gorm.DB,
+	session *model.Session,
+	author string,
+	fromUser *model.User,
+	fromBalance *model.UserBalance,
+	toUser *model.User,
+	toBalance *model.UserBalance,
+	amount int64,
+) ([]*model.BalanceUpdate, error) {
+	fromBalanceBefore := fromBalance.Balance
+	fromBalance.Balance -= amount
+	if err := u.userBalanceRepo.UpsertWithTransaction(ctx, tx, fromBalance); err != nil {
+		return nil, err
+	}
+
+	fromHistory := &model.UserBalanceHistory{
+		UserBalanceID: fromBalance.ID,
+		BalanceBefore: fromBalanceBefore,
+		BalanceAfter:  fromBalance.Balance,
+		Activity:      fmt.Sprintf("Transfer to %s", toUser.Username),
+		Type:          model.DEBIT,
+		IPAddress:     session.IPAddress,
+		Location:      session.Location,
+		UserAgent:     session.UserAgent,
+		Author:        author,
+	}
+	if err := u.userBalanceHistoryRepo.CreateWithTransaction(ctx, tx, fromHistory); err != nil {
+		return nil, err
+	}
+
+	toBalanceBefore := toBalance.Balance
+	toBalance.Balance += amount
+	toBalance.BalanceAchieve += amount
+	if err := u.userBalanceRepo.UpsertWithTransaction(ctx, tx, toBalance); err != nil {
+		return nil, err
+	}
+
+	toHistory := &model.UserBalanceHistory{
+		UserBalanceID: toBalance.ID,
+		BalanceBefore: toBalanceBefore,
+		BalanceAfter:  toBalance.Balance,
+		Activity:      fmt.Sprintf("Transfer from %s", fromUser.Username),
+		Type:          model.CREDIT,
+		IPAddress:     session.IPAddress,
+		Location:      session.Location,
+		UserAgent:     session.UserAgent,
+		Author:        author,
+	}
+	if err := u.userBalanceHistoryRepo.CreateWithTransaction(ctx, tx, toHistory); err != nil {
+		return nil, err
+	}
+
+	return []*model.BalanceUpdate{
+		newBalanceUpdate(fromUser.ID, fromBalance, fromHistory),
+		newBalanceUpdate(toUser.ID, toBalance, toHistory),
+	}, nil
+}
+
+// screenTransferBatch screen the valid items of the batch, a denied item is failed.
+// The returned screenings are indexed like the results, nil for an invalid item.
+func (u *userBalanceUsecase) screenTransferBatch(ctx context.Context, session *model.Session, fromUserID int, results []*model.TransferBatchItemResult) ([]*model.TransferScreeningResult, error) {
+	var recipientIDs []int
+	for _, result := range results {
+		if result.Status != model.TransferBatchItemStatusFailed {
+			recipientIDs = append(recipientIDs, result.ToUserID)
+		}
+	}
+
+	screenings := make([]*model.TransferScreeningResult, len(results))
+	for i, result := range results {
+		if result.Status == model.TransferBatchItemStatusFailed {
+			continue
+		}
+
+		screening, err := u.transferScreener.Screen(ctx, model.TransferScreeningRequest{
+			FromUserID:        fromUserID,
+			ToUserID:          result.ToUserID,
+			Amount:            result.Balance,
+			Session:           session,
+			BatchRecipientIDs: recipientIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if screening.Decision == model.ScreeningDecisionDeny {
+			logrus.WithContext(ctx).WithField("screening", utils.Dump(screening)).Warn(ErrTransferDenied)
+			observeTransferRejected(ErrTransferDenied)
+			failTransferBatchItem(result, ErrTransferDenied.Error())
+		}
+		screenings[i] = screening
+	}
+
+	return screenings, nil
+}
+
+// transferBatchItemWithTransaction record the transfer of a batch item, a completed transfer also move the balance.
+// The returned updates and events are of this item only.
+func (u *userBalanceUsecase) transferBatchItemWithTransaction(
+	ctx context.Context,
+	tx *gorm.DB,
+	session *model.Session,
+	transfer *model.Transfer,
+	fromUser *model.User,
+	fromBalance *model.UserBalance,
+	toUser *model.User,
+	toBalance *model.UserBalance,
+) ([]*model.BalanceUpdate, []*model.WebhookEvent, error) {
+	if transfer.Status == model.TransferStatusPendingReview {
+		if err := u.transferRepo.UpsertWithTransaction(ctx, tx, transfer); err != nil {
+			return nil, nil, err
+		}
+		return nil, []*model.WebhookEvent{newTransferWebhookEvent(model.WebhookEventTransferPendingReview, transfer.FromUserID, transfer)}, nil
+	}
+
+	updates, err := u.transferBalanceWithTransaction(ctx, tx, session, transfer.Author, fromUser, fromBalance, toUser, toBalance, transfer.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = u.transferRepo.UpsertWithTransaction(ctx, tx, transfer); err != nil {
+		return nil, nil, err
+	}
+
+	return updates, newCompletedTransferWebhookEvents(transfer, fromUser, fromBalance, toUser, toBalance), nil
+}
+
+func failTransferBatchItem(result *model.TransferBatchItemResult, reason string) {
+	result.Status = model.TransferBatchItemStatusFailed
+	result.Reason = reason
+}
+
+func hasFailedTransferBatchItem(results []*model.TransferBatchItemResult) bool {
+	for _, result := range results {
+		if result.Status == model.TransferBatchItemStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// abortTransferBatch mark every item that is not failed as skipped
+func abortTransferBatch(results []*model.TransferBatchItemResult) []*model.TransferBatchItemResult {
+	for _, result := range results {
+		if result.Status != model.TransferBatchItemStatusFailed {
+			result.Status = model.TransferBatchItemStatusSkipped
+		}
+	}
+	return results
+}
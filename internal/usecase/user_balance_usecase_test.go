@@ -0,0 +1,354 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+type userBalanceTestSuite struct {
+	usecase           *userBalanceUsecase
+	ledger            *fakeLedger
+	transferRepo      *fakeTransferRepository
+	transferLimitRepo *fakeTransferLimitRepository
+	screener          *fakeTransferScreener
+	sessionID         int
+}
+
+// newUserBalanceTestSuite a user balance usecase over the fake ledger, the users 1 to 5 exist, user 6 is an admin
+// and user 1 has a session
+func newUserBalanceTestSuite(t *testing.T, balances ...*model.UserBalance) *userBalanceTestSuite {
+	t.Helper()
+
+	var users []*model.User
+	for id := 1; id <= 5; id++ {
+		users = append(users, &model.User{ID: id})
+	}
+	users = append(users, &model.User{ID: 6, Role: model.RoleAdmin})
+
+	sessionRepo := &fakeSessionRepository{}
+	session := &model.Session{UserID: 1, IPAddress: "203.0.113.7", Location: "Jakarta", UserAgent: "test"}
+	require.NoError(t, sessionRepo.Create(context.Background(), session))
+
+	ledger := newFakeLedger(balances...)
+	transferRepo := &fakeTransferRepository{ledger: ledger}
+	transferLimitRepo := &fakeTransferLimitRepository{ledger: ledger, limits: make(map[int]*model.UserTransferLimit)}
+	screener := &fakeTransferScreener{decisions: make(map[int]model.ScreeningDecision)}
+	usecase := NewUserBalanceUsecase(
+		newFakeUserRepository(users...),
+		&fakeUserBalanceRepository{ledger: ledger},
+		&fakeUserBalanceHistoryRepository{ledger: ledger},
+		&fakeGormTransactioner{ledger: ledger},
+		sessionRepo,
+		nil,
+		transferLimitRepo,
+		transferRepo,
+		screener,
+		&fakeOutboxRepository{ledger: ledger},
+		&fakeBalanceBroker{},
+	).(*userBalanceUsecase)
+
+	return &userBalanceTestSuite{
+		usecase:           usecase,
+		ledger:            ledger,
+		transferRepo:      transferRepo,
+		transferLimitRepo: transferLimitRepo,
+		screener:          screener,
+		sessionID:         session.ID,
+	}
+}
+
+func (s *userBalanceTestSuite) transferBatch(mode model.TransferBatchMode, items ...model.TransferBatchItem) ([]*model.TransferBatchItemResult, error) {
+	return s.usecase.TransferUserBalanceBatch(context.Background(), model.TransferUserBalanceBatchInput{
+		FromUserID: 1,
+		Items:      items,
+		Mode:       mode,
+		SessionID:  s.sessionID,
+	})
+}
+
+func (s *userBalanceTestSuite) transfer(toUserID int, amount int64) (*model.Transfer, error) {
+	return s.usecase.TransferUserBalance(context.Background(), model.TransferUserBalanceInput{
+		FromUserID: 1,
+		ToUserID:   toUserID,
+		Balance:    amount,
+		SessionID:  s.sessionID,
+	})
+}
+
+func batchItemStatuses(results []*model.TransferBatchItemResult) []model.TransferBatchItemStatus {
+	var statuses []model.TransferBatchItemStatus
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
+
+func TestUserBalanceUsecase_AddUserBalance(t *testing.T) {
+	s := newUserBalanceTestSuite(t, &model.UserBalance{UserID: 1, Balance: 100, BalanceAchieve: 100})
+
+	err := s.usecase.AddUserBalance(context.Background(), model.AddUserBalanceInput{UserID: 1, Balance: 50, SessionID: s.sessionID})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(150), s.ledger.balanceOf(1))
+	assert.Equal(t, [][]int{{1}}, s.ledger.lockCalls)
+	histories := s.ledger.committedHistories()
+	require.Len(t, histories, 1)
+	assert.Equal(t, "203.0.113.7", histories[0].IPAddress)
+	assert.Equal(t, "Jakarta", histories[0].Location)
+
+	// the balance row of a new user is created under the lock
+	err = s.usecase.AddUserBalance(context.Background(), model.AddUserBalanceInput{UserID: 2, Balance: 10, SessionID: s.sessionID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), s.ledger.balanceOf(2))
+
+	err = s.usecase.AddUserBalance(context.Background(), model.AddUserBalanceInput{UserID: 1, Balance: 10, SessionID: 99})
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestUserBalanceUsecase_TransferUserBalanceBatch_Atomic(t *testing.T) {
+	s := newUserBalanceTestSuite(t, &model.UserBalance{UserID: 1, Balance: 100})
+
+	// the third item can't be paid, nothing is applied
+	results, err := s.transferBatch(model.TransferBatchModeAtomic,
+		model.TransferBatchItem{ToUserID: 2, Balance: 40},
+		model.TransferBatchItem{ToUserID: 3, Balance: 40},
+		model.TransferBatchItem{ToUserID: 4, Balance: 40},
+	)
+	assert.Equal(t, ErrTransferBatchFailed, err)
+	assert.Equal(t, []model.TransferBatchItemStatus{
+		model.TransferBatchItemStatusSkipped,
+		model.TransferBatchItemStatusSkipped,
+		model.TransferBatchItemStatusFailed,
+	}, batchItemStatuses(results))
+	assert.Equal(t, ErrBalanceNotEnough.Error(), results[2].Reason)
+	assert.Equal(t, int64(100), s.ledger.balanceOf(1))
+	assert.Empty(t, s.ledger.committedHistories())
+	assert.Empty(t, s.ledger.committedTransfers())
+	assert.Equal(t, 0, s.ledger.openTransactions())
+
+	// a failed write roll back the items applied before it
+	s.transferRepo.failToUserID = 3
+	_, err = s.transferBatch(model.TransferBatchModeAtomic,
+		model.TransferBatchItem{ToUserID: 2, Balance: 40},
+		model.TransferBatchItem{ToUserID: 3, Balance: 40},
+	)
+	assert.Error(t, err)
+	assert.Equal(t, int64(100), s.ledger.balanceOf(1))
+	assert.Equal(t, int64(0), s.ledger.balanceOf(2))
+	assert.Empty(t, s.ledger.committedTransfers())
+	assert.Equal(t, 0, s.ledger.openTransactions())
+
+	s.transferRepo.failToUserID = 0
+	results, err = s.transferBatch(model.TransferBatchModeAtomic,
+		model.TransferBatchItem{ToUserID: 2, Balance: 40},
+		model.TransferBatchItem{ToUserID: 3, Balance: 60},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TransferBatchItemStatus{
+		model.TransferBatchItemStatusSuccess,
+		model.TransferBatchItemStatusSuccess,
+	}, batchItemStatuses(results))
+	assert.Equal(t, int64(0), s.ledger.balanceOf(1))
+	assert.Equal(t, int64(40), s.ledger.balanceOf(2))
+	assert.Equal(t, int64(60), s.ledger.balanceOf(3))
+	assert.Len(t, s.ledger.committedTransfers(), 2)
+}
+
+func TestUserBalanceUsecase_TransferUserBalanceBatch_BestEffort(t *testing.T) {
+	s := newUserBalanceTestSuite(t, &model.UserBalance{UserID: 1, Balance: 100})
+	s.transferRepo.failToUserID = 3
+	s.screener.decisions[5] = model.ScreeningDecisionReview
+
+	results, err := s.transferBatch(model.TransferBatchModeBestEffort,
+		model.TransferBatchItem{ToUserID: 2, Balance: 40},
+		model.TransferBatchItem{ToUserID: 3, Balance: 40},
+		model.TransferBatchItem{ToUserID: 4, Balance: 70},
+		model.TransferBatchItem{ToUserID: 1, Balance: 10},
+		model.TransferBatchItem{ToUserID: 5, Balance: 10},
+		model.TransferBatchItem{ToUserID: 4, Balance: 50},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TransferBatchItemStatus{
+		model.TransferBatchItemStatusSuccess,
+		model.TransferBatchItemStatusFailed,
+		model.TransferBatchItemStatusFailed,
+		model.TransferBatchItemStatusFailed,
+		model.TransferBatchItemStatusPendingReview,
+		model.TransferBatchItemStatusSuccess,
+	}, batchItemStatuses(results))
+	assert.Equal(t, "system error", results[1].Reason)
+	assert.Equal(t, ErrBalanceNotEnough.Error(), results[2].Reason)
+
+	// the failed write of the second item is rolled back to its save point, the pending item doesn't move the balance
+	assert.Equal(t, int64(10), s.ledger.balanceOf(1))
+	assert.Equal(t, int64(40), s.ledger.balanceOf(2))
+	assert.Equal(t, int64(0), s.ledger.balanceOf(3))
+	assert.Equal(t, int64(50), s.ledger.balanceOf(4))
+	assert.Len(t, s.ledger.committedHistories(), 4)
+
+	var statuses []model.TransferStatus
+	for _, transfer := range s.ledger.committedTransfers() {
+		statuses = append(statuses, transfer.Status)
+	}
+	assert.Equal(t, []model.TransferStatus{
+		model.TransferStatusCompleted,
+		model.TransferStatusPendingReview,
+		model.TransferStatusCompleted,
+	}, statuses)
+	assert.Equal(t, 0, s.ledger.openTransactions())
+}
+
+func TestUserBalanceUsecase_TransferUserBalanceBatch_SessionNotFound(t *testing.T) {
+	s := newUserBalanceTestSuite(t, &model.UserBalance{UserID: 1, Balance: 100})
+	s.sessionID = 99
+
+	_, err := s.transferBatch(model.TransferBatchModeAtomic, model.TransferBatchItem{ToUserID: 2, Balance: 10})
+	assert.Equal(t, ErrNotFound, err)
+	assert.Empty(t, s.ledger.lockCalls)
+}
+
+func TestUserBalanceUsecase_TransferUserBalanceBatch_LockOrder(t *testing.T) {
+	s := newUserBalanceTestSuite(t, &model.UserBalance{UserID: 1, Balance: 100}, &model.UserBalance{UserID: 4, Balance: 100})
+
+	_, err := s.transferBatch(model.TransferBatchModeAtomic,
+		model.TransferBatchItem{ToUserID: 5, Balance: 10},
+		model.TransferBatchItem{ToUserID: 2, Balance: 10},
+		model.TransferBatchItem{ToUserID: 4, Balance: 10},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{1, 2, 4, 5}}, s.ledger.lockCalls)
+
+	// two payers sending to each other at the same time lock their rows in the same order, so they don't deadlock
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		errs := make(chan error, 2)
+		for i := 0; i < 50; i++ {
+			go func() {
+				_, err := s.transferBatch(model.TransferBatchModeAtomic, model.TransferBatchItem{ToUserID: 4, Balance: 1})
+				errs <- err
+			}()
+			go func() {
+				_, err := s.usecase.TransferUserBalanceBatch(context.Background(), model.TransferUserBalanceBatchInput{
+					FromUserID: 4,
+					Items:      []model.TransferBatchItem{{ToUserID: 1, Balance: 1}},
+					Mode:       model.TransferBatchModeAtomic,
+					SessionID:  s.sessionID,
+				})
+				errs <- err
+			}()
+			assert.NoError(t, <-errs)
+			assert.NoError(t, <-errs)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the batches deadlocked")
+	}
+	assert.Equal(t, int64(70), s.ledger.balanceOf(1))
+	assert.Equal(t, int64(110), s.ledger.balanceOf(4))
+}
+
+func TestUserBalanceUsecase_TransferUserBalance_ConcurrentDailyLimit(t *testing.T) {
+	s := newUserBalanceTestSuite(t, &model.UserBalance{UserID: 1, Balance: 1000})
+	dailyAmount := int64(100)
+	s.transferLimitRepo.limits[1] = &model.UserTransferLimit{UserID: 1, DailyAmount: &dailyAmount}
+
+	// the transfers start together, the usage read under the payer lock keep them under the limit
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.transfer(2, 10)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var completed, rejected int
+	for err := range errs {
+		switch err {
+		case nil:
+			completed++
+		case ErrTransferDailyLimitExceeded:
+			rejected++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 10, completed)
+	assert.Equal(t, 10, rejected)
+	assert.Equal(t, int64(900), s.ledger.balanceOf(1))
+	assert.Equal(t, int64(100), s.ledger.balanceOf(2))
+
+	// the batch count the same usage
+	results, err := s.transferBatch(model.TransferBatchModeBestEffort, model.TransferBatchItem{ToUserID: 3, Balance: 10})
+	require.NoError(t, err)
+	assert.Equal(t, ErrTransferDailyLimitExceeded.Error(), results[0].Reason)
+	assert.Equal(t, int64(900), s.ledger.balanceOf(1))
+}
+
+func TestUserBalanceUsecase_TransferUserBalanceBatch_ConcurrentLimit(t *testing.T) {
+	s := newUserBalanceTestSuite(t, &model.UserBalance{UserID: 1, Balance: 1000})
+	hourlyCount := int64(5)
+	s.transferLimitRepo.limits[1] = &model.UserTransferLimit{UserID: 1, HourlyCount: &hourlyCount}
+
+	// each batch has 2 items, only 2 batches fit in the hourly count and the third one is applied partially
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.transferBatch(model.TransferBatchModeBestEffort,
+				model.TransferBatchItem{ToUserID: 2, Balance: 10},
+				model.TransferBatchItem{ToUserID: 3, Balance: 10},
+			)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(950), s.ledger.balanceOf(1))
+	assert.Len(t, s.ledger.committedTransfers(), 5)
+}
+
+func TestUserBalanceUsecase_ReviewPendingTransfer_Limit(t *testing.T) {
+	s := newUserBalanceTestSuite(t, &model.UserBalance{UserID: 1, Balance: 1000})
+	dailyAmount := int64(100)
+	s.transferLimitRepo.limits[1] = &model.UserTransferLimit{UserID: 1, DailyAmount: &dailyAmount}
+	s.screener.decisions[2] = model.ScreeningDecisionReview
+
+	// the flagged transfers are queued without moving the balance, so none of them is counted in the usage
+	var pending []*model.Transfer
+	for i := 0; i < 2; i++ {
+		transfer, err := s.transfer(2, 60)
+		require.NoError(t, err)
+		assert.Equal(t, model.TransferStatusPendingReview, transfer.Status)
+		pending = append(pending, transfer)
+	}
+
+	transfer, err := s.usecase.ReviewPendingTransfer(context.Background(), 6, pending[0].ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, model.TransferStatusCompleted, transfer.Status)
+
+	// approving the second one would breach the daily limit, it stays pending
+	_, err = s.usecase.ReviewPendingTransfer(context.Background(), 6, pending[1].ID, true)
+	assert.Equal(t, ErrTransferDailyLimitExceeded, err)
+	assert.Equal(t, int64(940), s.ledger.balanceOf(1))
+	assert.Equal(t, int64(60), s.ledger.balanceOf(2))
+
+	stored, err := s.transferRepo.FindByID(context.Background(), pending[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.TransferStatusPendingReview, stored.Status)
+	assert.Equal(t, 0, s.ledger.openTransactions())
+}
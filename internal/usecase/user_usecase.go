@@ -3,22 +3,30 @@ package usecase
 import (
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
 	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
 	"github.com/irvankadhafi/user-balance-transfer-service/utils"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
+	"time"
 )
 
 type userUsecase struct {
-	userRepo model.UserRepository
+	userRepo     model.UserRepository
+	loginLockout *loginLockout
 }
 
-func NewUserUsecase(userRepo model.UserRepository) model.UserUsecase {
-	return &userUsecase{userRepo: userRepo}
+func NewUserUsecase(userRepo model.UserRepository, loginLockoutRepo model.LoginLockoutRepository) model.UserUsecase {
+	return &userUsecase{
+		userRepo:     userRepo,
+		loginLockout: &loginLockout{repo: loginLockoutRepo, now: time.Now},
+	}
 }
 
 func (u *userUsecase) Create(ctx context.Context, input model.CreateUserInput) (*model.User, error) {
-	logger := logrus.WithFields(logrus.Fields{
-		"ctx":   utils.DumpIncomingContext(ctx),
+	ctx, span := tracing.StartSpan(ctx, "userUsecase.Create")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"input": utils.Dump(input),
 	})
 
@@ -56,6 +64,9 @@ func (u *userUsecase) Create(ctx context.Context, input model.CreateUserInput) (
 }
 
 func (u *userUsecase) FindByID(ctx context.Context, userID int) (*model.User, error) {
+	ctx, span := tracing.StartSpan(ctx, "userUsecase.FindByID")
+	defer span.End()
+
 	user, err := u.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		logrus.WithField("userID", userID).Error(err)
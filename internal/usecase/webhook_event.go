@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"gorm.io/gorm"
+)
+
+// createOutboxMessagesWithTransaction write the events to the outbox in the transaction of the change,
+// so an event is never lost nor published for a rolled back change. The outbox relay publish them.
+func (u *userBalanceUsecase) createOutboxMessagesWithTransaction(ctx context.Context, tx *gorm.DB, events ...*model.WebhookEvent) error {
+	messages := make([]*model.OutboxMessage, len(events))
+	for i, event := range events {
+		messages[i] = &model.OutboxMessage{
+			AggregateID: event.UserID,
+			EventID:     event.ID,
+			EventType:   event.Type,
+			Payload:     string(utils.ToByte(event)),
+		}
+	}
+
+	return u.outboxRepo.CreateWithTransaction(ctx, tx, messages)
+}
+
+func newBalanceWebhookEvent(history *model.UserBalanceHistory, userID int, eventType model.WebhookEventType) *model.WebhookEvent {
+	amount := history.Amount()
+	if amount < 0 {
+		amount = -amount
+	}
+
+	return newWebhookEvent(eventType, userID, model.BalanceEventData{
+		UserID:        userID,
+		Amount:        amount,
+		BalanceBefore: history.BalanceBefore,
+		BalanceAfter:  history.BalanceAfter,
+		Activity:      history.Activity,
+	})
+}
+
+func newTransferWebhookEvent(eventType model.WebhookEventType, userID int, transfer *model.Transfer) *model.WebhookEvent {
+	return newWebhookEvent(eventType, userID, model.TransferEventData{
+		TransferID: transfer.ID,
+		FromUserID: transfer.FromUserID,
+		ToUserID:   transfer.ToUserID,
+		Amount:     transfer.Amount,
+		Status:     string(transfer.Status),
+	})
+}
+
+// newCompletedTransferWebhookEvents build the events of both sides of a completed transfer from the balances after the transfer
+func newCompletedTransferWebhookEvents(
+	transfer *model.Transfer,
+	fromUser *model.User,
+	fromBalance *model.UserBalance,
+	toUser *model.User,
+	toBalance *model.UserBalance,
+) []*model.WebhookEvent {
+	return []*model.WebhookEvent{
+		newWebhookEvent(model.WebhookEventBalanceDebited, fromUser.ID, model.BalanceEventData{
+			UserID:        fromUser.ID,
+			Amount:        transfer.Amount,
+			BalanceBefore: fromBalance.Balance + transfer.Amount,
+			BalanceAfter:  fromBalance.Balance,
+			Activity:      fmt.Sprintf("Transfer to %s", toUser.Username),
+		}),
+		newWebhookEvent(model.WebhookEventBalanceCredited, toUser.ID, model.BalanceEventData{
+			UserID:        toUser.ID,
+			Amount:        transfer.Amount,
+			BalanceBefore: toBalance.Balance - transfer.Amount,
+			BalanceAfter:  toBalance.Balance,
+			Activity:      fmt.Sprintf("Transfer from %s", fromUser.Username),
+		}),
+		newTransferWebhookEvent(model.WebhookEventTransferCompleted, fromUser.ID, transfer),
+		newTransferWebhookEvent(model.WebhookEventTransferCompleted, toUser.ID, transfer),
+	}
+}
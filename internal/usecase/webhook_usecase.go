@@ -0,0 +1,376 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/tracing"
+	"github.com/irvankadhafi/user-balance-transfer-service/utils"
+	"github.com/jpillora/backoff"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookDeliveryLogLimit maximum deliveries returned by the delivery log
+const webhookDeliveryLogLimit = 100
+
+type webhookUsecase struct {
+	webhookRepo model.WebhookRepository
+	userRepo    model.UserRepository
+	httpClient  *http.Client
+}
+
+// NewWebhookUsecase :nodoc:
+func NewWebhookUsecase(
+	webhookRepo model.WebhookRepository,
+	userRepo model.UserRepository,
+) model.WebhookUsecase {
+	return &webhookUsecase{
+		webhookRepo: webhookRepo,
+		userRepo:    userRepo,
+		httpClient:  helper.NewWebhookHTTPClient(config.WebhookTimeout()),
+	}
+}
+
+// CreateSubscription create a subscription of the user, or an app subscription receiving the events of every user
+func (w *webhookUsecase) CreateSubscription(ctx context.Context, input model.CreateWebhookSubscriptionInput) (*model.WebhookSubscription, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhookUsecase.CreateSubscription")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"input": utils.Dump(input),
+	})
+
+	if err := input.Validate(); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	for _, eventType := range input.EventTypes {
+		if !eventType.IsValid() {
+			return nil, ErrInvalidWebhookEventType
+		}
+	}
+	// the address is checked again when the webhook is sent
+	if err := helper.ValidateWebhookURL(ctx, input.URL); err != nil {
+		logger.Error(err)
+		return nil, ErrInvalidWebhookURL
+	}
+
+	subscription := &model.WebhookSubscription{
+		EventTypes: input.EventTypes,
+		URL:        input.URL,
+		Active:     true,
+		CreatedBy:  input.UserID,
+	}
+	if input.IsApp {
+		if err := w.mustBeAdmin(ctx, input.UserID); err != nil {
+			return nil, err
+		}
+	} else {
+		subscription.UserID = &input.UserID
+	}
+
+	secret, err := helper.GenerateRandomHex(32)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	subscription.Secret = "whsec_" + secret
+
+	if err = w.webhookRepo.CreateSubscription(ctx, subscription); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// FindSubscriptions find the subscriptions of the user, an admin also see the app subscriptions
+func (w *webhookUsecase) FindSubscriptions(ctx context.Context, userID int) ([]*model.WebhookSubscription, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhookUsecase.FindSubscriptions")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID": userID,
+	})
+
+	subscriptions, err := w.webhookRepo.FindSubscriptionsByUserID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	user, err := w.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if user == nil || !user.IsAdmin() {
+		return subscriptions, nil
+	}
+
+	appSubscriptions, err := w.webhookRepo.FindAppSubscriptions(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	return append(subscriptions, appSubscriptions...), nil
+}
+
+// DeleteSubscription deactivate the subscription, its pending deliveries are no longer sent
+func (w *webhookUsecase) DeleteSubscription(ctx context.Context, userID, subscriptionID int) error {
+	ctx, span := tracing.StartSpan(ctx, "webhookUsecase.DeleteSubscription")
+	defer span.End()
+
+	if _, err := w.findOwnedSubscription(ctx, userID, subscriptionID); err != nil {
+		return err
+	}
+
+	return w.webhookRepo.DeactivateSubscription(ctx, subscriptionID)
+}
+
+// FindDeliveries return the latest deliveries of the subscription
+func (w *webhookUsecase) FindDeliveries(ctx context.Context, userID, subscriptionID int) ([]*model.WebhookDelivery, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhookUsecase.FindDeliveries")
+	defer span.End()
+
+	if _, err := w.findOwnedSubscription(ctx, userID, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	return w.webhookRepo.FindDeliveriesBySubscriptionID(ctx, subscriptionID, webhookDeliveryLogLimit)
+}
+
+// ReplayDelivery send the delivery again with a fresh attempts count, regardless its status
+func (w *webhookUsecase) ReplayDelivery(ctx context.Context, userID, deliveryID int) (*model.WebhookDelivery, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhookUsecase.ReplayDelivery")
+	defer span.End()
+
+	logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID":     userID,
+		"deliveryID": deliveryID,
+	})
+
+	delivery, err := w.webhookRepo.FindDeliveryByID(ctx, deliveryID)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, ErrNotFound
+	}
+
+	subscription, err := w.findOwnedSubscription(ctx, userID, delivery.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.Active {
+		return nil, ErrFailedPrecondition
+	}
+
+	delivery.Status = model.WebhookDeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.NextAttemptAt = time.Now()
+	if err = w.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Publish create a pending delivery for every active subscription of the event
+func (w *webhookUsecase) Publish(ctx context.Context, events ...*model.WebhookEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "webhookUsecase.Publish")
+	defer span.End()
+
+	var deliveries []*model.WebhookDelivery
+	for _, event := range events {
+		logger := logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"event": utils.Dump(event),
+		})
+
+		subscriptions, err := w.webhookRepo.FindActiveSubscriptionsForUser(ctx, event.UserID)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+
+		payload := string(utils.ToByte(event))
+		for _, subscription := range subscriptions {
+			if !subscription.IsSubscribed(event.Type) {
+				continue
+			}
+
+			deliveries = append(deliveries, &model.WebhookDelivery{
+				SubscriptionID: subscription.ID,
+				EventID:        event.ID,
+				EventType:      event.Type,
+				Payload:        payload,
+				Status:         model.WebhookDeliveryStatusPending,
+				NextAttemptAt:  event.CreatedAt,
+			})
+		}
+	}
+
+	return w.webhookRepo.CreateDeliveries(ctx, deliveries)
+}
+
+// DeliverDueDeliveries claim the due deliveries and send them one by one
+func (w *webhookUsecase) DeliverDueDeliveries(ctx context.Context) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhookUsecase.DeliverDueDeliveries")
+	defer span.End()
+
+	// the lease must outlive a whole batch of timed out requests
+	batchSize := config.WebhookBatchSize()
+	lease := time.Duration(batchSize+1) * config.WebhookTimeout()
+
+	deliveries, err := w.webhookRepo.ClaimDueDeliveries(ctx, time.Now(), lease, batchSize)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return 0, err
+	}
+
+	subscriptions := make(map[int]*model.WebhookSubscription)
+	for _, delivery := range deliveries {
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = w.webhookRepo.FindSubscriptionByID(ctx, delivery.SubscriptionID)
+			if err != nil {
+				logrus.WithField("subscriptionID", delivery.SubscriptionID).Error(err)
+				return 0, err
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		if subscription == nil || !subscription.Active {
+			delivery.Status = model.WebhookDeliveryStatusFailed
+			delivery.LastError = "subscription is inactive"
+		} else {
+			w.deliver(ctx, subscription, delivery)
+		}
+
+		if err = w.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+			logrus.WithField("deliveryID", delivery.ID).Error(err)
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// deliver send a single attempt and schedule the next one with an exponential backoff when it failed
+func (w *webhookUsecase) deliver(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery) {
+	delivery.Attempts++
+
+	status, err := w.send(ctx, subscription, delivery)
+	delivery.ResponseStatus = status
+	if err == nil {
+		now := time.Now()
+		delivery.Status = model.WebhookDeliveryStatusSuccess
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= config.WebhookMaxAttempts() {
+		delivery.Status = model.WebhookDeliveryStatusFailed
+		return
+	}
+
+	b := &backoff.Backoff{
+		Min:    config.WebhookRetryMin(),
+		Max:    config.WebhookRetryMax(),
+		Factor: 2,
+		Jitter: true,
+	}
+	delivery.NextAttemptAt = time.Now().Add(b.ForAttempt(float64(delivery.Attempts - 1)))
+}
+
+func (w *webhookUsecase) send(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", string(delivery.EventType))
+	req.Header.Set("X-Webhook-Event-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set(helper.WebhookSignatureHeader, helper.SignWebhookPayload(subscription.Secret, time.Now().Unix(), payload))
+
+	res, err := w.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer helper.WrapCloser(res.Body.Close)
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// findOwnedSubscription find the subscription of the user, an app subscription is owned by the admins
+func (w *webhookUsecase) findOwnedSubscription(ctx context.Context, userID, subscriptionID int) (*model.WebhookSubscription, error) {
+	subscription, err := w.webhookRepo.FindSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		logrus.WithField("subscriptionID", subscriptionID).Error(err)
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, ErrNotFound
+	}
+
+	if subscription.UserID == nil {
+		if err = w.mustBeAdmin(ctx, userID); err != nil {
+			return nil, err
+		}
+		return subscription, nil
+	}
+	if *subscription.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	return subscription, nil
+}
+
+func (w *webhookUsecase) mustBeAdmin(ctx context.Context, userID int) error {
+	user, err := w.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		logrus.WithField("userID", userID).Error(err)
+		return err
+	}
+	if user == nil || !user.IsAdmin() {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// newWebhookEvent :nodoc:
+func newWebhookEvent(eventType model.WebhookEventType, userID int, data any) *model.WebhookEvent {
+	id, err := helper.GenerateRandomHex(16)
+	if err != nil {
+		// crypto/rand never fail on the supported platforms, fallback to a time based id anyway
+		id = strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+
+	return &model.WebhookEvent{
+		ID:        "evt_" + id,
+		Type:      eventType,
+		UserID:    userID,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+}
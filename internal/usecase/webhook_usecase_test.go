@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"context"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/config"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/helper"
+	"github.com/irvankadhafi/user-balance-transfer-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver a receiver answering the given status, it record the requests it got
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []*receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(status int) *webhookReceiver {
+	r := &webhookReceiver{status: status}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, &receivedWebhook{header: req.Header.Clone(), body: body})
+		w.WriteHeader(r.status)
+	}))
+	return r
+}
+
+func (r *webhookReceiver) received() []*receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*receivedWebhook(nil), r.requests...)
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+type webhookTestSuite struct {
+	usecase      *webhookUsecase
+	webhookRepo  *fakeWebhookRepository
+	receiver     *webhookReceiver
+	subscription *model.WebhookSubscription
+	user         *model.User
+}
+
+// newWebhookTestSuite a webhook usecase sending to a local receiver, the user is subscribed to the transfers
+func newWebhookTestSuite(t *testing.T, status int) *webhookTestSuite {
+	t.Helper()
+
+	s := &webhookTestSuite{
+		receiver: newWebhookReceiver(status),
+		user:     &model.User{ID: 1, Email: "johndoe@mail.com", Username: "johndoe", Role: model.RoleUser},
+	}
+	t.Cleanup(s.receiver.Close)
+
+	s.subscription = &model.WebhookSubscription{
+		ID:         1,
+		UserID:     &s.user.ID,
+		URL:        s.receiver.URL,
+		Secret:     "whsec_test",
+		EventTypes: model.WebhookEventTypeList{model.WebhookEventTransferCompleted},
+		Active:     true,
+		CreatedBy:  s.user.ID,
+	}
+	s.webhookRepo = newFakeWebhookRepository(s.subscription)
+	s.usecase = NewWebhookUsecase(s.webhookRepo, newFakeUserRepository(s.user)).(*webhookUsecase)
+	// the receiver listen on the loopback, which the webhook client refuse to dial
+	s.usecase.httpClient = s.receiver.Client()
+
+	return s
+}
+
+// publish publish a transfer event and return its single delivery
+func (s *webhookTestSuite) publish(t *testing.T) *model.WebhookDelivery {
+	t.Helper()
+
+	event := newWebhookEvent(model.WebhookEventTransferCompleted, s.user.ID, map[string]any{"amount": 100})
+	require.NoError(t, s.usecase.Publish(context.Background(), event))
+	require.Len(t, s.webhookRepo.deliveries, 1)
+	return s.webhookRepo.deliveries[1]
+}
+
+func (s *webhookTestSuite) deliverDue(t *testing.T) {
+	t.Helper()
+
+	_, err := s.usecase.DeliverDueDeliveries(context.Background())
+	require.NoError(t, err)
+}
+
+func TestDeliverDueDeliveries_SignThePayload(t *testing.T) {
+	s := newWebhookTestSuite(t, http.StatusNoContent)
+	delivery := s.publish(t)
+
+	s.deliverDue(t)
+
+	requests := s.receiver.received()
+	require.Len(t, requests, 1)
+	req := requests[0]
+	assert.Equal(t, delivery.Payload, string(req.body))
+	assert.Equal(t, string(model.WebhookEventTransferCompleted), req.header.Get("X-Webhook-Event"))
+	assert.Equal(t, delivery.EventID, req.header.Get("X-Webhook-Event-ID"))
+	assert.Equal(t, strconv.Itoa(delivery.ID), req.header.Get("X-Webhook-Delivery"))
+
+	// the receiver verify the signature with the timestamp it carry and the subscription secret
+	signature := req.header.Get(helper.WebhookSignatureHeader)
+	require.True(t, strings.HasPrefix(signature, "t="), signature)
+	timestamp, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), time.Unix(timestamp, 0), time.Minute)
+	assert.Equal(t, helper.SignWebhookPayload(s.subscription.Secret, timestamp, req.body), signature)
+	assert.NotEqual(t, helper.SignWebhookPayload("whsec_other", timestamp, req.body), signature)
+
+	delivered := s.webhookRepo.deliveries[delivery.ID]
+	assert.Equal(t, model.WebhookDeliveryStatusSuccess, delivered.Status)
+	assert.Equal(t, 1, delivered.Attempts)
+	assert.Equal(t, http.StatusNoContent, delivered.ResponseStatus)
+	assert.NotNil(t, delivered.DeliveredAt)
+}
+
+func TestDeliverDueDeliveries_RetryAServerErrorWithBackoff(t *testing.T) {
+	s := newWebhookTestSuite(t, http.StatusInternalServerError)
+	delivery := s.publish(t)
+
+	// the first retry wait the minimum delay, the next ones double it with a jitter
+	expectedDelays := []struct{ min, max time.Duration }{
+		{min: config.WebhookRetryMin(), max: config.WebhookRetryMin()},
+		{min: config.WebhookRetryMin(), max: 2 * config.WebhookRetryMin()},
+		{min: config.WebhookRetryMin(), max: 4 * config.WebhookRetryMin()},
+	}
+	for i, expected := range expectedDelays {
+		before := time.Now()
+		s.deliverDue(t)
+		after := time.Now()
+
+		retried := s.webhookRepo.deliveries[delivery.ID]
+		assert.Equal(t, model.WebhookDeliveryStatusPending, retried.Status)
+		assert.Equal(t, i+1, retried.Attempts)
+		assert.Equal(t, http.StatusInternalServerError, retried.ResponseStatus)
+		assert.Equal(t, "unexpected response status 500", retried.LastError)
+		assert.False(t, retried.NextAttemptAt.Before(before.Add(expected.min)), "attempt %d", i+1)
+		assert.False(t, retried.NextAttemptAt.After(after.Add(expected.max)), "attempt %d", i+1)
+
+		// the delivery is not due before its next attempt
+		s.deliverDue(t)
+		assert.Len(t, s.receiver.received(), i+1)
+		retried.NextAttemptAt = time.Now()
+	}
+
+	// the last attempt fail the delivery for good
+	s.webhookRepo.deliveries[delivery.ID].Attempts = config.WebhookMaxAttempts() - 1
+	s.deliverDue(t)
+	failed := s.webhookRepo.deliveries[delivery.ID]
+	assert.Equal(t, model.WebhookDeliveryStatusFailed, failed.Status)
+	assert.Equal(t, config.WebhookMaxAttempts(), failed.Attempts)
+
+	s.deliverDue(t)
+	assert.Len(t, s.receiver.received(), len(expectedDelays)+1)
+}
+
+func TestDeliverDueDeliveries_ClientErrorIsRetriedToo(t *testing.T) {
+	s := newWebhookTestSuite(t, http.StatusBadRequest)
+	delivery := s.publish(t)
+
+	s.deliverDue(t)
+	retried := s.webhookRepo.deliveries[delivery.ID]
+	assert.Equal(t, model.WebhookDeliveryStatusPending, retried.Status)
+	assert.Equal(t, http.StatusBadRequest, retried.ResponseStatus)
+
+	// the receiver is fixed, the next attempt succeed
+	s.receiver.setStatus(http.StatusOK)
+	retried.NextAttemptAt = time.Now()
+	s.deliverDue(t)
+	assert.Equal(t, model.WebhookDeliveryStatusSuccess, s.webhookRepo.deliveries[delivery.ID].Status)
+	assert.Equal(t, 2, s.webhookRepo.deliveries[delivery.ID].Attempts)
+}
+
+func TestReplayDelivery(t *testing.T) {
+	s := newWebhookTestSuite(t, http.StatusInternalServerError)
+	delivery := s.publish(t)
+	s.webhookRepo.deliveries[delivery.ID].Attempts = config.WebhookMaxAttempts() - 1
+	s.deliverDue(t)
+	require.Equal(t, model.WebhookDeliveryStatusFailed, s.webhookRepo.deliveries[delivery.ID].Status)
+
+	s.receiver.setStatus(http.StatusOK)
+	replayed, err := s.usecase.ReplayDelivery(context.Background(), s.user.ID, delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.WebhookDeliveryStatusPending, replayed.Status)
+	assert.Equal(t, 0, replayed.Attempts)
+	assert.Empty(t, replayed.LastError)
+
+	s.deliverDue(t)
+	requests := s.receiver.received()
+	require.Len(t, requests, 2)
+	// the replay send the same event again
+	assert.Equal(t, requests[0].body, requests[1].body)
+	assert.Equal(t, requests[0].header.Get("X-Webhook-Event-ID"), requests[1].header.Get("X-Webhook-Event-ID"))
+
+	delivered := s.webhookRepo.deliveries[delivery.ID]
+	assert.Equal(t, model.WebhookDeliveryStatusSuccess, delivered.Status)
+	assert.Equal(t, 1, delivered.Attempts)
+}
+
+func TestReplayDelivery_Errors(t *testing.T) {
+	s := newWebhookTestSuite(t, http.StatusOK)
+	delivery := s.publish(t)
+
+	_, err := s.usecase.ReplayDelivery(context.Background(), s.user.ID, 999)
+	assert.Equal(t, ErrNotFound, err)
+
+	// the delivery of another user is not found rather than forbidden
+	_, err = s.usecase.ReplayDelivery(context.Background(), 2, delivery.ID)
+	assert.Equal(t, ErrNotFound, err)
+
+	s.webhookRepo.subscriptions[s.subscription.ID].Active = false
+	_, err = s.usecase.ReplayDelivery(context.Background(), s.user.ID, delivery.ID)
+	assert.Equal(t, ErrFailedPrecondition, err)
+	assert.Equal(t, model.WebhookDeliveryStatusPending, s.webhookRepo.deliveries[delivery.ID].Status)
+}
+
+func TestCreateSubscription_RefuseANonPublicURL(t *testing.T) {
+	s := newWebhookTestSuite(t, http.StatusOK)
+
+	for _, url := range []string{
+		"http://93.184.216.34/hooks",
+		"https://127.0.0.1/hooks",
+		"https://10.0.0.5/hooks",
+		"https://169.254.169.254/latest/meta-data",
+		s.receiver.URL,
+	} {
+		_, err := s.usecase.CreateSubscription(context.Background(), model.CreateWebhookSubscriptionInput{
+			UserID:     s.user.ID,
+			URL:        url,
+			EventTypes: []model.WebhookEventType{model.WebhookEventTransferCompleted},
+		})
+		assert.Equal(t, ErrInvalidWebhookURL, err, url)
+	}
+	assert.Len(t, s.webhookRepo.subscriptions, 1)
+
+	subscription, err := s.usecase.CreateSubscription(context.Background(), model.CreateWebhookSubscriptionInput{
+		UserID:     s.user.ID,
+		URL:        "https://93.184.216.34/hooks",
+		EventTypes: []model.WebhookEventType{model.WebhookEventTransferCompleted},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(subscription.Secret, "whsec_"))
+}
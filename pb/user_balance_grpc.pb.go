@@ -0,0 +1,365 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.9
+// source: user_balance.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// UserBalanceServiceClient is the client API for UserBalanceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserBalanceServiceClient interface {
+	// auth
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	VerifyLoginChallenge(ctx context.Context, in *VerifyLoginChallengeRequest, opts ...grpc.CallOption) (*Session, error)
+	RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...grpc.CallOption) (*Session, error)
+	// balance
+	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*UserBalance, error)
+	AddBalance(ctx context.Context, in *AddBalanceRequest, opts ...grpc.CallOption) (*AddBalanceResponse, error)
+	// transfer
+	TransferBalance(ctx context.Context, in *TransferBalanceRequest, opts ...grpc.CallOption) (*Transfer, error)
+	TransferBalanceBatch(ctx context.Context, in *TransferBalanceBatchRequest, opts ...grpc.CallOption) (*TransferBalanceBatchResponse, error)
+	// history
+	GetBalanceStatement(ctx context.Context, in *GetBalanceStatementRequest, opts ...grpc.CallOption) (*BalanceStatement, error)
+}
+
+type userBalanceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserBalanceServiceClient(cc grpc.ClientConnInterface) UserBalanceServiceClient {
+	return &userBalanceServiceClient{cc}
+}
+
+func (c *userBalanceServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, "/userbalance.UserBalanceService/Login", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userBalanceServiceClient) VerifyLoginChallenge(ctx context.Context, in *VerifyLoginChallengeRequest, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	err := c.cc.Invoke(ctx, "/userbalance.UserBalanceService/VerifyLoginChallenge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userBalanceServiceClient) RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	err := c.cc.Invoke(ctx, "/userbalance.UserBalanceService/RefreshToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userBalanceServiceClient) GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*UserBalance, error) {
+	out := new(UserBalance)
+	err := c.cc.Invoke(ctx, "/userbalance.UserBalanceService/GetBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userBalanceServiceClient) AddBalance(ctx context.Context, in *AddBalanceRequest, opts ...grpc.CallOption) (*AddBalanceResponse, error) {
+	out := new(AddBalanceResponse)
+	err := c.cc.Invoke(ctx, "/userbalance.UserBalanceService/AddBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userBalanceServiceClient) TransferBalance(ctx context.Context, in *TransferBalanceRequest, opts ...grpc.CallOption) (*Transfer, error) {
+	out := new(Transfer)
+	err := c.cc.Invoke(ctx, "/userbalance.UserBalanceService/TransferBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userBalanceServiceClient) TransferBalanceBatch(ctx context.Context, in *TransferBalanceBatchRequest, opts ...grpc.CallOption) (*TransferBalanceBatchResponse, error) {
+	out := new(TransferBalanceBatchResponse)
+	err := c.cc.Invoke(ctx, "/userbalance.UserBalanceService/TransferBalanceBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userBalanceServiceClient) GetBalanceStatement(ctx context.Context, in *GetBalanceStatementRequest, opts ...grpc.CallOption) (*BalanceStatement, error) {
+	out := new(BalanceStatement)
+	err := c.cc.Invoke(ctx, "/userbalance.UserBalanceService/GetBalanceStatement", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserBalanceServiceServer is the server API for UserBalanceService service.
+// All implementations must embed UnimplementedUserBalanceServiceServer
+// for forward compatibility
+type UserBalanceServiceServer interface {
+	// auth
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	VerifyLoginChallenge(context.Context, *VerifyLoginChallengeRequest) (*Session, error)
+	RefreshToken(context.Context, *RefreshTokenRequest) (*Session, error)
+	// balance
+	GetBalance(context.Context, *GetBalanceRequest) (*UserBalance, error)
+	AddBalance(context.Context, *AddBalanceRequest) (*AddBalanceResponse, error)
+	// transfer
+	TransferBalance(context.Context, *TransferBalanceRequest) (*Transfer, error)
+	TransferBalanceBatch(context.Context, *TransferBalanceBatchRequest) (*TransferBalanceBatchResponse, error)
+	// history
+	GetBalanceStatement(context.Context, *GetBalanceStatementRequest) (*BalanceStatement, error)
+	mustEmbedUnimplementedUserBalanceServiceServer()
+}
+
+// UnimplementedUserBalanceServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedUserBalanceServiceServer struct {
+}
+
+func (UnimplementedUserBalanceServiceServer) Login(context.Context, *LoginRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedUserBalanceServiceServer) VerifyLoginChallenge(context.Context, *VerifyLoginChallengeRequest) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyLoginChallenge not implemented")
+}
+func (UnimplementedUserBalanceServiceServer) RefreshToken(context.Context, *RefreshTokenRequest) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshToken not implemented")
+}
+func (UnimplementedUserBalanceServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*UserBalance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedUserBalanceServiceServer) AddBalance(context.Context, *AddBalanceRequest) (*AddBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddBalance not implemented")
+}
+func (UnimplementedUserBalanceServiceServer) TransferBalance(context.Context, *TransferBalanceRequest) (*Transfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferBalance not implemented")
+}
+func (UnimplementedUserBalanceServiceServer) TransferBalanceBatch(context.Context, *TransferBalanceBatchRequest) (*TransferBalanceBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferBalanceBatch not implemented")
+}
+func (UnimplementedUserBalanceServiceServer) GetBalanceStatement(context.Context, *GetBalanceStatementRequest) (*BalanceStatement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalanceStatement not implemented")
+}
+func (UnimplementedUserBalanceServiceServer) mustEmbedUnimplementedUserBalanceServiceServer() {}
+
+// UnsafeUserBalanceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserBalanceServiceServer will
+// result in compilation errors.
+type UnsafeUserBalanceServiceServer interface {
+	mustEmbedUnimplementedUserBalanceServiceServer()
+}
+
+func RegisterUserBalanceServiceServer(s grpc.ServiceRegistrar, srv UserBalanceServiceServer) {
+	s.RegisterService(&UserBalanceService_ServiceDesc, srv)
+}
+
+func _UserBalanceService_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserBalanceServiceServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userbalance.UserBalanceService/Login",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserBalanceServiceServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserBalanceService_VerifyLoginChallenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyLoginChallengeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserBalanceServiceServer).VerifyLoginChallenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userbalance.UserBalanceService/VerifyLoginChallenge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserBalanceServiceServer).VerifyLoginChallenge(ctx, req.(*VerifyLoginChallengeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserBalanceService_RefreshToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserBalanceServiceServer).RefreshToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userbalance.UserBalanceService/RefreshToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserBalanceServiceServer).RefreshToken(ctx, req.(*RefreshTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserBalanceService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserBalanceServiceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userbalance.UserBalanceService/GetBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserBalanceServiceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserBalanceService_AddBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserBalanceServiceServer).AddBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userbalance.UserBalanceService/AddBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserBalanceServiceServer).AddBalance(ctx, req.(*AddBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserBalanceService_TransferBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserBalanceServiceServer).TransferBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userbalance.UserBalanceService/TransferBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserBalanceServiceServer).TransferBalance(ctx, req.(*TransferBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserBalanceService_TransferBalanceBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferBalanceBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserBalanceServiceServer).TransferBalanceBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userbalance.UserBalanceService/TransferBalanceBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserBalanceServiceServer).TransferBalanceBatch(ctx, req.(*TransferBalanceBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserBalanceService_GetBalanceStatement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceStatementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserBalanceServiceServer).GetBalanceStatement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userbalance.UserBalanceService/GetBalanceStatement",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserBalanceServiceServer).GetBalanceStatement(ctx, req.(*GetBalanceStatementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserBalanceService_ServiceDesc is the grpc.ServiceDesc for UserBalanceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserBalanceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "userbalance.UserBalanceService",
+	HandlerType: (*UserBalanceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Login",
+			Handler:    _UserBalanceService_Login_Handler,
+		},
+		{
+			MethodName: "VerifyLoginChallenge",
+			Handler:    _UserBalanceService_VerifyLoginChallenge_Handler,
+		},
+		{
+			MethodName: "RefreshToken",
+			Handler:    _UserBalanceService_RefreshToken_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _UserBalanceService_GetBalance_Handler,
+		},
+		{
+			MethodName: "AddBalance",
+			Handler:    _UserBalanceService_AddBalance_Handler,
+		},
+		{
+			MethodName: "TransferBalance",
+			Handler:    _UserBalanceService_TransferBalance_Handler,
+		},
+		{
+			MethodName: "TransferBalanceBatch",
+			Handler:    _UserBalanceService_TransferBalanceBatch_Handler,
+		},
+		{
+			MethodName: "GetBalanceStatement",
+			Handler:    _UserBalanceService_GetBalanceStatement_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "user_balance.proto",
+}